@@ -22,8 +22,7 @@ var decoder *schema.Decoder
 type API struct {
 	base *sudoapi.BaseAPI
 
-	signupLock      sync.Mutex
-	testArchiveLock sync.Mutex
+	signupLock sync.Mutex
 }
 
 // New declares a new API instance
@@ -49,6 +48,9 @@ func (s *API) Handler() http.Handler {
 			r.Post("/resetWaitingSubs", webMessageWrapper("Reset waiting subs", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
 				return s.base.ResetWaitingSubmissions(ctx)
 			}))
+			r.Post("/reloadLanguages", webMessageWrapper("Reloaded languages", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
+				return s.base.ReloadLanguages(ctx)
+			}))
 			r.Post("/cleanBucketCache", webMessageWrapper("Reset bucket cache", func(ctx context.Context, args struct {
 				Name datastore.BucketType `json:"name"`
 			}) *kilonova.StatusError {
@@ -71,6 +73,9 @@ func (s *API) Handler() http.Handler {
 		r.Post("/endSubscription", s.endSubscription)
 
 		r.Get("/getAllUsers", s.getAllUsers)
+		r.Get("/submissionQueue", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.QueuedSubmission, *kilonova.StatusError) {
+			return s.base.SubmissionQueue(ctx)
+		}))
 	})
 
 	r.Route("/webhook", func(r chi.Router) {
@@ -105,6 +110,8 @@ func (s *API) Handler() http.Handler {
 			r.Get("/maxScoreBreakdown", s.maxScoreBreakdown)
 			r.Get("/statistics", s.problemStatistics)
 			r.With(s.validateProblemFullyVisible).Get("/tags", webWrapper(s.problemTags))
+			r.With(s.MustBeAuthed).Post("/debugRun", webWrapper(s.debugRun))
+			r.With(s.MustBeAuthed).Post("/customInvocation", webWrapper(s.customInvocation))
 
 			r.Group(func(r chi.Router) {
 				r.Use(s.validateProblemEditor)
@@ -135,6 +142,10 @@ func (s *API) Handler() http.Handler {
 					r.Post("/bulkDeleteTests", s.bulkDeleteTests)
 					r.Post("/bulkUpdateTestScores", s.bulkUpdateTestScores)
 					r.Post("/processTestArchive", s.processTestArchive)
+					r.Post("/generateTests", webMessageWrapper("Started test generation", s.generateTests))
+
+					r.Post("/solutionExpectation", webMessageWrapper("Updated expected result", s.setSolutionExpectation))
+					r.Post("/verify", webWrapper(s.verifyProblem))
 
 					r.Post("/addSubTask", s.createSubTask)
 					r.Post("/updateSubTask", s.updateSubTask)
@@ -142,6 +153,10 @@ func (s *API) Handler() http.Handler {
 					r.Post("/bulkDeleteSubTasks", s.bulkDeleteSubTasks)
 				})
 
+				r.Post("/checkSimilarity", webMessageWrapper("Similarity check finished", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
+					return s.base.CheckProblemSimilarity(context.WithoutCancel(ctx), util.ProblemContext(ctx))
+				}))
+
 				r.Post("/reevaluateSubs", webMessageWrapper("Reevaluating submissions", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
 					return s.base.ResetProblemSubmissions(context.WithoutCancel(ctx), util.ProblemContext(ctx))
 				}))
@@ -159,6 +174,25 @@ func (s *API) Handler() http.Handler {
 				r.With(s.validateProblemEditor).Get("/checklist", webWrapper(func(ctx context.Context, _ struct{}) (*kilonova.ProblemChecklist, *kilonova.StatusError) {
 					return s.base.ProblemChecklist(ctx, util.ProblemContext(ctx).ID)
 				}))
+				r.With(s.validateProblemEditor).Get("/verification", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.SolutionVerification, *kilonova.StatusError) {
+					return s.base.ProblemVerification(ctx, util.ProblemContext(ctx).ID)
+				}))
+
+				r.With(s.validateProblemEditor).Get("/similarityReport", webWrapper(func(ctx context.Context, args struct {
+					ID int `json:"id"`
+				}) (*sudoapi.SimilarityReport, *kilonova.StatusError) {
+					return s.base.SimilarityReport(ctx, args.ID, nil, &util.ProblemContext(ctx).ID)
+				}))
+				r.With(s.validateProblemEditor).Get("/similarityPair", webWrapper(func(ctx context.Context, args struct {
+					ReportID int `json:"report_id"`
+					PairID   int `json:"pair_id"`
+				}) (*sudoapi.SimilarityPairDetails, *kilonova.StatusError) {
+					return s.base.SimilarityPair(ctx, args.ReportID, args.PairID, nil, &util.ProblemContext(ctx).ID)
+				}))
+
+				r.With(s.validateProblemEditor).Get("/testGeneration", webWrapper(func(ctx context.Context, _ struct{}) (*sudoapi.TestGeneration, *kilonova.StatusError) {
+					return s.base.TestGenerationStatus(util.ProblemContext(ctx).ID), nil
+				}))
 
 				r.Get("/accessControl", webWrapper(s.getProblemAccessControl))
 
@@ -323,6 +357,12 @@ func (s *API) Handler() http.Handler {
 			r.Get("/problems", s.getContestProblems)
 
 			r.Get("/leaderboard", s.contestLeaderboard)
+			r.With(s.validateContestEditor).Get("/resolver", webWrapper(func(ctx context.Context, args struct {
+				Step      int   `json:"step"`
+				Generated *bool `json:"generated_acc"`
+			}) (*kilonova.ResolverState, *kilonova.StatusError) {
+				return s.base.ContestResolver(ctx, util.ContestContext(ctx), args.Step, args.Generated)
+			}))
 
 			r.Get("/questions", webWrapper(s.contestUserQuestions))
 			r.With(s.validateContestEditor).Get("/allQuestions", webWrapper(s.contestAllQuestions))
@@ -336,24 +376,40 @@ func (s *API) Handler() http.Handler {
 
 			r.With(s.MustBeAuthed).Post("/register", s.registerForContest)
 			r.With(s.MustBeAuthed).Post("/startRegistration", s.startContestRegistration)
-			r.With(s.validateContestEditor).Post("/runMOSS", webMessageWrapper("MOSS executed successfully", s.runMOSS))
+			r.With(s.MustBeAuthed).Post("/startVirtual", webMessageWrapper("Started virtual participation", s.startVirtualContest))
+			r.With(s.validateContestEditor).Post("/checkSimilarity", webMessageWrapper("Similarity check finished", s.checkContestSimilarity))
+			r.With(s.validateContestEditor).Get("/similarityReport", webWrapper(s.contestSimilarityReport))
+			r.With(s.validateContestEditor).Get("/similarityPair", webWrapper(s.contestSimilarityPair))
 
 			r.With(s.validateContestEditor).Get("/invitations", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.ContestInvitation, *kilonova.StatusError) {
 				return s.base.ContestInvitations(ctx, util.ContestContext(ctx).ID)
 			}))
 			r.With(s.validateContestEditor).Post("/createInvitation", webWrapper(func(ctx context.Context, args struct {
-				MaxUses int `json:"max_uses"`
+				MaxUses    int  `json:"max_uses"`
+				CategoryID *int `json:"category_id"`
 			}) (string, *kilonova.StatusError) {
 				var cnt *int
 				if args.MaxUses > 0 {
 					cnt = &args.MaxUses
 				}
-				return s.base.CreateContestInvitation(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx), cnt)
+				return s.base.CreateContestInvitation(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx), cnt, args.CategoryID)
+			}))
+
+			r.Get("/categories", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.ContestCategory, *kilonova.StatusError) {
+				return s.base.ContestCategories(ctx, util.ContestContext(ctx).ID)
+			}))
+			r.With(s.validateContestEditor).Post("/createCategory", webWrapper(func(ctx context.Context, args struct {
+				Name string `json:"name"`
+			}) (int, *kilonova.StatusError) {
+				return s.base.CreateContestCategory(ctx, util.ContestContext(ctx).ID, args.Name)
 			}))
+			r.With(s.validateContestEditor).Post("/updateCategory", webMessageWrapper("Updated category", s.updateContestCategory))
+			r.With(s.validateContestEditor).Post("/deleteCategory", webMessageWrapper("Deleted category", s.deleteContestCategory))
 
 			r.With(s.MustBeAuthed).Get("/checkRegistration", webWrapper(s.checkRegistration))
 			r.With(s.validateContestEditor).Get("/registrations", s.contestRegistrations)
 			r.With(s.validateContestEditor).Post("/kickUser", s.stripContestRegistration)
+			r.With(s.validateContestEditor).Post("/updateRegistration", webMessageWrapper("Updated registration", s.updateContestRegistration))
 			r.With(s.MustBeAdmin).Post("/forceRegister", s.forceRegisterForContest)
 			r.With(s.validateContestEditor).Post("/delete", webMessageWrapper("Deleted contest", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
 				return s.base.DeleteContest(ctx, util.ContestContext(ctx))
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
@@ -79,7 +80,13 @@ func (s *Assets) AssetsRouter() http.Handler {
 
 	r.With(s.api.MustBeProposer).Get("/subtest/{subtestID}", s.ServeSubtest)
 
-	r.With(s.api.validateContestID).Get("/contest/{contestID}/leaderboard.csv", s.ServeContestLeaderboard)
+	r.Route("/contest/{contestID}", func(r chi.Router) {
+		r.Use(s.api.validateContestID)
+		r.Get("/leaderboard.csv", s.ServeContestLeaderboard)
+		r.Get("/results.csv", s.ServeContestResults)
+		r.Get("/scoreboard.json", s.ServeContestScoreboard)
+		r.Get("/awards.json", s.ServeContestAwards)
+	})
 
 	return r
 }
@@ -189,22 +196,130 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 		http.Error(w, err.Error(), err.Code)
 		return
 	}
+	s.serveLeaderboardCSV(w, r, "leaderboard.csv", ld, nil)
+}
+
+type contestResultsParams struct {
+	Frozen     bool  `json:"frozen"`
+	Generated  *bool `json:"generated_acc"`
+	CategoryID *int  `json:"category_id"`
+
+	Gold   int `json:"gold"`
+	Silver int `json:"silver"`
+	Bronze int `json:"bronze"`
+}
+
+// officialLeaderboard returns the leaderboard used for publishing results, along with the requested medal cutoffs
+func (s *Assets) officialLeaderboard(r *http.Request) (*kilonova.ContestLeaderboard, kilonova.MedalCutoffs, *kilonova.StatusError) {
+	r.ParseForm()
+	var args contestResultsParams
+	if err := decoder.Decode(&args, r.Form); err != nil {
+		return nil, kilonova.MedalCutoffs{}, kilonova.Statusf(400, "Can't decode parameters")
+	}
+	cutoffs := kilonova.MedalCutoffs{Gold: args.Gold, Silver: args.Silver, Bronze: args.Bronze}
+	if !cutoffs.Valid() {
+		return nil, cutoffs, kilonova.Statusf(400, "Invalid medal cutoffs")
+	}
+
+	ld, err := s.api.leaderboard(r.Context(), util.Contest(r), util.UserBrief(r), &contestLeaderboardParams{
+		Frozen:     args.Frozen,
+		Generated:  args.Generated,
+		CategoryID: args.CategoryID,
+		official:   true,
+	})
+	if err != nil {
+		return nil, cutoffs, err
+	}
+	return ld, cutoffs, nil
+}
+
+// ServeContestResults writes the leaderboard with shared places and medals, suitable for publishing official results
+func (s *Assets) ServeContestResults(w http.ResponseWriter, r *http.Request) {
+	ld, cutoffs, err := s.officialLeaderboard(r)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	s.serveLeaderboardCSV(w, r, "results.csv", ld, ld.Medals(cutoffs))
+}
+
+// ServeContestScoreboard writes the CLICS Contest API scoreboard of an ICPC-style contest
+func (s *Assets) ServeContestScoreboard(w http.ResponseWriter, r *http.Request) {
+	contest := util.Contest(r)
+	if contest.LeaderboardStyle != kilonova.LeaderboardTypeICPC {
+		http.Error(w, "Scoreboard export is only available for ICPC-style contests", 400)
+		return
+	}
+	ld, _, err := s.officialLeaderboard(r)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	s.serveJSON(w, r, "scoreboard.json", clicsScoreboardFromLeaderboard(contest, ld, time.Now()))
+}
+
+// ServeContestAwards writes the CLICS Contest API awards of an ICPC-style contest
+func (s *Assets) ServeContestAwards(w http.ResponseWriter, r *http.Request) {
+	if util.Contest(r).LeaderboardStyle != kilonova.LeaderboardTypeICPC {
+		http.Error(w, "Awards export is only available for ICPC-style contests", 400)
+		return
+	}
+	ld, cutoffs, err := s.officialLeaderboard(r)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	s.serveJSON(w, r, "awards.json", clicsAwardsFromLeaderboard(ld, cutoffs))
+}
+
+func (s *Assets) serveJSON(w http.ResponseWriter, r *http.Request, name string, val any) {
+	data, err := json.MarshalIndent(val, "", "\t")
+	if err != nil {
+		zap.S().Warn(err)
+		http.Error(w, "Couldn't write JSON", 500)
+		return
+	}
+	http.ServeContent(w, r, name, time.Now(), bytes.NewReader(data))
+}
+
+// serveLeaderboardCSV writes the leaderboard entries along with their ranks. If medals are given, they are written in an additional column
+// and the ranks are written as places in the first column, otherwise the ranks are written in the last column
+func (s *Assets) serveLeaderboardCSV(w http.ResponseWriter, r *http.Request, name string, ld *kilonova.ContestLeaderboard, medals []kilonova.Medal) {
 	var buf bytes.Buffer
 	wr := csv.NewWriter(&buf)
 
-	var hasDisplayName bool
+	var hasDisplayName, hasSchool, hasRegion bool
 	for _, entry := range ld.Entries {
 		if entry.User != nil && entry.User.DisplayName != "" {
 			hasDisplayName = true
-			break
 		}
+		hasSchool = hasSchool || entry.School != ""
+		hasRegion = hasRegion || entry.Region != ""
+	}
+	hasCategories := len(ld.Categories) > 0
+	categoryNames := make(map[int]string, len(ld.Categories))
+	for _, category := range ld.Categories {
+		categoryNames[category.ID] = category.Name
 	}
 
 	// Header
+	// Official results start with the place, while the rank comes last in the leaderboard, to keep its older columns in place
 	header := []string{"username"}
+	if medals != nil {
+		header = []string{"place", "username"}
+	}
 	if hasDisplayName {
 		header = append(header, "display_name")
 	}
+	if hasCategories {
+		header = append(header, "category", "category_rank")
+	}
+	if hasSchool {
+		header = append(header, "school")
+	}
+	if hasRegion {
+		header = append(header, "region")
+	}
 	for _, pb := range ld.ProblemOrder {
 		name, ok := ld.ProblemNames[pb]
 		if !ok {
@@ -214,23 +329,44 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 		}
 		header = append(header, name)
 	}
-	if util.Contest(r).LeaderboardStyle == kilonova.LeaderboardTypeICPC {
+	if ld.Type == kilonova.LeaderboardTypeICPC {
 		header = append(header, "num_solved")
 		header = append(header, "penalty")
 	} else {
 		header = append(header, "total")
 	}
+	if medals != nil {
+		header = append(header, "medal")
+	} else {
+		header = append(header, "rank")
+	}
 	if err := wr.Write(header); err != nil {
 		zap.S().Warn(err)
 		http.Error(w, "Couldn't write CSV", 500)
 		return
 	}
-	for _, entry := range ld.Entries {
+	for i, entry := range ld.Entries {
 		line := []string{entry.User.Name}
+		if medals != nil {
+			line = []string{strconv.Itoa(entry.Rank), entry.User.Name}
+		}
 		if hasDisplayName {
 			line = append(line, entry.User.DisplayName)
 		}
-		if util.Contest(r).LeaderboardStyle == kilonova.LeaderboardTypeICPC {
+		if hasCategories {
+			if entry.CategoryID != nil {
+				line = append(line, categoryNames[*entry.CategoryID], strconv.Itoa(entry.CategoryRank))
+			} else {
+				line = append(line, "", "")
+			}
+		}
+		if hasSchool {
+			line = append(line, entry.School)
+		}
+		if hasRegion {
+			line = append(line, entry.Region)
+		}
+		if ld.Type == kilonova.LeaderboardTypeICPC {
 			for _, pb := range ld.ProblemOrder {
 				score, ok := entry.ProblemScores[pb]
 				if !ok || score.Equal(decimal.NewFromInt(-1)) {
@@ -268,6 +404,11 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 			}
 			line = append(line, entry.TotalScore.String())
 		}
+		if medals != nil {
+			line = append(line, string(medals[i]))
+		} else {
+			line = append(line, strconv.Itoa(entry.Rank))
+		}
 
 		if err := wr.Write(line); err != nil {
 			zap.S().Warn(err)
@@ -283,7 +424,7 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	http.ServeContent(w, r, "leaderboard.csv", time.Now(), bytes.NewReader(buf.Bytes()))
+	http.ServeContent(w, r, name, time.Now(), bytes.NewReader(buf.Bytes()))
 }
 
 func (s *Assets) ServeSubtest(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+// Scoreboard and awards objects of the CLICS Contest API (2022-07 version).
+// Contestants are exported as teams identified by their user ID, and problems by their problem ID.
+// See https://ccs-specs.icpc.io/2022-07/contest_api
+
+type clicsScoreboard struct {
+	Time        string                `json:"time"`
+	ContestTime string                `json:"contest_time"`
+	State       clicsState            `json:"state"`
+	Rows        []*clicsScoreboardRow `json:"rows"`
+}
+
+type clicsState struct {
+	Started      *string `json:"started"`
+	Frozen       *string `json:"frozen"`
+	Ended        *string `json:"ended"`
+	Thawed       *string `json:"thawed"`
+	Finalized    *string `json:"finalized"`
+	EndOfUpdates *string `json:"end_of_updates"`
+}
+
+type clicsScoreboardRow struct {
+	Rank     int                  `json:"rank"`
+	TeamID   string               `json:"team_id"`
+	Score    clicsScore           `json:"score"`
+	Problems []*clicsProblemScore `json:"problems"`
+}
+
+type clicsScore struct {
+	NumSolved int `json:"num_solved"`
+	TotalTime int `json:"total_time"`
+}
+
+type clicsProblemScore struct {
+	ProblemID  string `json:"problem_id"`
+	NumJudged  int    `json:"num_judged"`
+	NumPending int    `json:"num_pending"`
+	Solved     bool   `json:"solved"`
+	// Time is the number of minutes since the start of the contest, set only for solved problems
+	Time         *int `json:"time,omitempty"`
+	FirstToSolve bool `json:"first_to_solve,omitempty"`
+}
+
+type clicsAward struct {
+	ID       string   `json:"id"`
+	Citation string   `json:"citation"`
+	TeamIDs  []string `json:"team_ids"`
+}
+
+var clicsMedalCitations = map[kilonova.Medal]string{
+	kilonova.MedalGold:   "Gold medal winner",
+	kilonova.MedalSilver: "Silver medal winner",
+	kilonova.MedalBronze: "Bronze medal winner",
+}
+
+func clicsTime(t time.Time) *string {
+	val := t.Format("2006-01-02T15:04:05.000Z07:00")
+	return &val
+}
+
+func clicsRelTime(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%d:%02d:%02d.%03d", sign, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000)
+}
+
+func clicsTeamID(entry *kilonova.LeaderboardEntry) string {
+	return strconv.Itoa(entry.User.ID)
+}
+
+// clicsSolved reports if the entry solved the problem, and the number of minutes it took
+func clicsSolved(entry *kilonova.LeaderboardEntry, problemID int) (bool, int) {
+	score, ok := entry.ProblemScores[problemID]
+	if !ok || score.LessThan(decimal.NewFromInt(100)) {
+		return false, 0
+	}
+	return true, max(int(math.Floor(entry.ProblemTimes[problemID])), 0)
+}
+
+// clicsFirstSolvers returns, for every solved problem, the indices of the entries that were first to solve it
+func clicsFirstSolvers(ld *kilonova.ContestLeaderboard) map[int][]int {
+	firstSolvers := make(map[int][]int)
+	for _, pb := range ld.ProblemOrder {
+		bestTime := math.Inf(1)
+		for i, entry := range ld.Entries {
+			if solved, _ := clicsSolved(entry, pb); !solved {
+				continue
+			}
+			switch t := entry.ProblemTimes[pb]; {
+			case t < bestTime:
+				bestTime = t
+				firstSolvers[pb] = []int{i}
+			case t == bestTime:
+				firstSolvers[pb] = append(firstSolvers[pb], i)
+			}
+		}
+	}
+	return firstSolvers
+}
+
+func clicsScoreboardFromLeaderboard(contest *kilonova.Contest, ld *kilonova.ContestLeaderboard, now time.Time) *clicsScoreboard {
+	contestTime := now
+	if contest.EndTime.Before(contestTime) {
+		contestTime = contest.EndTime
+	}
+	if contestTime.Before(contest.StartTime) {
+		contestTime = contest.StartTime
+	}
+
+	scoreboard := &clicsScoreboard{
+		Time:        *clicsTime(now),
+		ContestTime: clicsRelTime(contestTime.Sub(contest.StartTime)),
+		Rows:        make([]*clicsScoreboardRow, 0, len(ld.Entries)),
+	}
+	if contest.Started() {
+		scoreboard.State.Started = clicsTime(contest.StartTime)
+	}
+	if ld.FreezeTime != nil {
+		scoreboard.State.Frozen = clicsTime(*ld.FreezeTime)
+	}
+	if contest.Ended() {
+		scoreboard.State.Ended = clicsTime(contest.EndTime)
+		// Results are final only once they are no longer frozen
+		if ld.FreezeTime == nil {
+			if contest.LeaderboardFreeze != nil {
+				scoreboard.State.Thawed = clicsTime(contest.EndTime)
+			}
+			scoreboard.State.Finalized = clicsTime(contest.EndTime)
+			scoreboard.State.EndOfUpdates = clicsTime(contest.EndTime)
+		}
+	}
+
+	firstSolvers := make(map[int]map[int]bool)
+	for pb, entries := range clicsFirstSolvers(ld) {
+		firstSolvers[pb] = make(map[int]bool)
+		for _, i := range entries {
+			firstSolvers[pb][i] = true
+		}
+	}
+
+	for i, entry := range ld.Entries {
+		row := &clicsScoreboardRow{
+			Rank:     entry.Rank,
+			TeamID:   clicsTeamID(entry),
+			Score:    clicsScore{NumSolved: entry.NumSolved, TotalTime: entry.Penalty},
+			Problems: make([]*clicsProblemScore, 0, len(ld.ProblemOrder)),
+		}
+		for _, pb := range ld.ProblemOrder {
+			// Negative scores mark problems without submissions
+			if score, ok := entry.ProblemScores[pb]; !ok || score.IsNegative() {
+				continue
+			}
+			problem := &clicsProblemScore{
+				ProblemID: strconv.Itoa(pb),
+				NumJudged: entry.ProblemAttempts[pb],
+			}
+			if solved, mins := clicsSolved(entry, pb); solved {
+				// Attempts only count the rejected submissions before the accepted one
+				problem.NumJudged++
+				problem.Solved = true
+				problem.Time = &mins
+				problem.FirstToSolve = firstSolvers[pb][i]
+			}
+			row.Problems = append(row.Problems, problem)
+		}
+		scoreboard.Rows = append(scoreboard.Rows, row)
+	}
+	return scoreboard
+}
+
+func clicsAwardsFromLeaderboard(ld *kilonova.ContestLeaderboard, cutoffs kilonova.MedalCutoffs) []*clicsAward {
+	var winners []string
+	medalists := make(map[kilonova.Medal][]string)
+	for i, medal := range ld.Medals(cutoffs) {
+		if ld.Entries[i].Rank == 1 && ld.Entries[i].NumSolved > 0 {
+			winners = append(winners, clicsTeamID(ld.Entries[i]))
+		}
+		if medal != kilonova.MedalNone {
+			medalists[medal] = append(medalists[medal], clicsTeamID(ld.Entries[i]))
+		}
+	}
+
+	awards := []*clicsAward{}
+	if len(winners) > 0 {
+		awards = append(awards, &clicsAward{ID: "winner", Citation: "Contest winner", TeamIDs: winners})
+	}
+	for _, medal := range []kilonova.Medal{kilonova.MedalGold, kilonova.MedalSilver, kilonova.MedalBronze} {
+		if teams, ok := medalists[medal]; ok {
+			awards = append(awards, &clicsAward{
+				ID:       string(medal) + "-medal",
+				Citation: clicsMedalCitations[medal],
+				TeamIDs:  teams,
+			})
+		}
+	}
+
+	firstSolvers := clicsFirstSolvers(ld)
+	for _, pb := range ld.ProblemOrder {
+		entries, ok := firstSolvers[pb]
+		if !ok {
+			continue
+		}
+		teams := make([]string, 0, len(entries))
+		for _, i := range entries {
+			teams = append(teams, clicsTeamID(ld.Entries[i]))
+		}
+		awards = append(awards, &clicsAward{
+			ID:       "first-to-solve-" + strconv.Itoa(pb),
+			Citation: "First to solve problem " + ld.ProblemNames[pb],
+			TeamIDs:  teams,
+		})
+	}
+	return awards
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+// clicsTestLeaderboard returns a small ranked ICPC leaderboard, where users 1, 2 and 3 all solve problem 10 at the same time
+func clicsTestLeaderboard() *kilonova.ContestLeaderboard {
+	// Problem results are given as (score, attempts, minutes)
+	entry := func(userID int, numSolved int, penalty int, results map[int][3]int) *kilonova.LeaderboardEntry {
+		e := &kilonova.LeaderboardEntry{
+			User:            &kilonova.UserBrief{ID: userID},
+			ProblemScores:   make(map[int]decimal.Decimal),
+			ProblemAttempts: make(map[int]int),
+			ProblemTimes:    make(map[int]float64),
+			NumSolved:       numSolved,
+			Penalty:         penalty,
+		}
+		for pb, res := range results {
+			e.ProblemScores[pb] = decimal.NewFromInt(int64(res[0]))
+			e.ProblemAttempts[pb] = res[1]
+			if res[0] == 100 {
+				e.ProblemTimes[pb] = float64(res[2]) + 0.5
+			}
+		}
+		return e
+	}
+	ld := &kilonova.ContestLeaderboard{
+		Type:         kilonova.LeaderboardTypeICPC,
+		ProblemOrder: []int{10, 20},
+		ProblemNames: map[int]string{10: "A", 20: "B"},
+		Entries: []*kilonova.LeaderboardEntry{
+			entry(1, 2, 130, map[int][3]int{10: {100, 0, 30}, 20: {100, 2, 60}}),
+			entry(2, 1, 30, map[int][3]int{10: {100, 0, 30}, 20: {0, 3, 0}}),
+			entry(3, 1, 30, map[int][3]int{10: {100, 0, 30}}),
+			entry(4, 0, 0, map[int][3]int{10: {-1, 0, 0}, 20: {0, 1, 0}}),
+		},
+	}
+	ld.Rank()
+	return ld
+}
+
+func TestClicsScoreboard(t *testing.T) {
+	now := time.Now()
+	contest := &kilonova.Contest{StartTime: now.Add(-5 * time.Hour), EndTime: now.Add(-time.Hour)}
+	ld := clicsTestLeaderboard()
+	// Entry 3 solved problem 10 later than the others, in the same minute
+	ld.Entries[2].ProblemTimes[10] = 30.9
+
+	scoreboard := clicsScoreboardFromLeaderboard(contest, ld, now)
+	if scoreboard.ContestTime != "4:00:00.000" {
+		t.Fatalf("Expected the contest time to stop at the end of the contest, got %q", scoreboard.ContestTime)
+	}
+	state := scoreboard.State
+	if state.Started == nil || *state.Started != *clicsTime(contest.StartTime) || state.Frozen != nil || state.Thawed != nil ||
+		state.Ended == nil || *state.Ended != *clicsTime(contest.EndTime) ||
+		state.Finalized == nil || *state.Finalized != *clicsTime(contest.EndTime) || state.EndOfUpdates == nil {
+		t.Fatalf("Unexpected state of an ended contest: %+v", state)
+	}
+
+	if len(scoreboard.Rows) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(scoreboard.Rows))
+	}
+	ranks := make([]int, 0, len(scoreboard.Rows))
+	for _, row := range scoreboard.Rows {
+		ranks = append(ranks, row.Rank)
+	}
+	if !slices.Equal(ranks, []int{1, 2, 2, 4}) {
+		t.Fatalf("Expected tied teams to share their rank, got %v", ranks)
+	}
+
+	row := scoreboard.Rows[0]
+	if row.TeamID != "1" || row.Score.NumSolved != 2 || row.Score.TotalTime != 130 || len(row.Problems) != 2 {
+		t.Fatalf("Unexpected first row: %+v", row)
+	}
+	// num_judged includes the accepted submission
+	if pb := row.Problems[1]; pb.ProblemID != "20" || !pb.Solved || pb.NumJudged != 3 || pb.Time == nil || *pb.Time != 60 || !pb.FirstToSolve {
+		t.Fatalf("Unexpected result of a problem solved after 2 rejected submissions: %+v", pb)
+	}
+	// Both teams that solved the problem at the same time are first to solve it
+	for i, expected := range []bool{true, true, false} {
+		pb := scoreboard.Rows[i].Problems[0]
+		if pb.ProblemID != "10" || !pb.Solved || pb.NumJudged != 1 || pb.Time == nil || *pb.Time != 30 || pb.FirstToSolve != expected {
+			t.Fatalf("Unexpected result of problem 10 for row %d: %+v", i, pb)
+		}
+	}
+	if pb := scoreboard.Rows[1].Problems[1]; pb.Solved || pb.NumJudged != 3 || pb.Time != nil {
+		t.Fatalf("Unexpected result of an unsolved problem: %+v", pb)
+	}
+	// Problems without submissions are skipped
+	if problems := scoreboard.Rows[3].Problems; len(problems) != 1 || problems[0].ProblemID != "20" || problems[0].NumJudged != 1 {
+		t.Fatalf("Unexpected problems of the last row: %+v", problems)
+	}
+}
+
+func TestClicsScoreboardState(t *testing.T) {
+	now := time.Now()
+	freeze := now.Add(-2 * time.Hour)
+	contest := &kilonova.Contest{StartTime: now.Add(-5 * time.Hour), EndTime: now.Add(-time.Hour), LeaderboardFreeze: &freeze}
+
+	// Frozen results of an ended contest are not final
+	ld := clicsTestLeaderboard()
+	ld.FreezeTime = &freeze
+	state := clicsScoreboardFromLeaderboard(contest, ld, now).State
+	if state.Frozen == nil || *state.Frozen != *clicsTime(freeze) || state.Ended == nil || state.Thawed != nil || state.Finalized != nil || state.EndOfUpdates != nil {
+		t.Fatalf("Unexpected state of a frozen leaderboard: %+v", state)
+	}
+
+	// Unfrozen results of a contest with a freeze are thawed at the end
+	state = clicsScoreboardFromLeaderboard(contest, clicsTestLeaderboard(), now).State
+	if state.Frozen != nil || state.Thawed == nil || *state.Thawed != *clicsTime(contest.EndTime) || state.Finalized == nil {
+		t.Fatalf("Unexpected state of a thawed leaderboard: %+v", state)
+	}
+
+	// Running contests are neither ended nor finalized
+	contest = &kilonova.Contest{StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour)}
+	scoreboard := clicsScoreboardFromLeaderboard(contest, clicsTestLeaderboard(), now)
+	if scoreboard.State.Started == nil || scoreboard.State.Ended != nil || scoreboard.State.Finalized != nil || scoreboard.ContestTime != "1:00:00.000" {
+		t.Fatalf("Unexpected state of a running contest: %+v (contest time %s)", scoreboard.State, scoreboard.ContestTime)
+	}
+
+	// Contests that didn't start yet have no state
+	contest = &kilonova.Contest{StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}
+	scoreboard = clicsScoreboardFromLeaderboard(contest, clicsTestLeaderboard(), now)
+	if scoreboard.State != (clicsState{}) || scoreboard.ContestTime != "0:00:00.000" {
+		t.Fatalf("Unexpected state of a contest that didn't start: %+v (contest time %s)", scoreboard.State, scoreboard.ContestTime)
+	}
+}
+
+func TestClicsAwards(t *testing.T) {
+	ld := clicsTestLeaderboard()
+	awards := clicsAwardsFromLeaderboard(ld, kilonova.MedalCutoffs{Gold: 1, Silver: 3, Bronze: 4})
+
+	expected := map[string][]string{
+		"winner":            {"1"},
+		"gold-medal":        {"1"},
+		"silver-medal":      {"2", "3"},
+		"first-to-solve-10": {"1", "2", "3"},
+		"first-to-solve-20": {"1"},
+	}
+	if len(awards) != len(expected) {
+		t.Fatalf("Expected %d awards, got %d", len(expected), len(awards))
+	}
+	for _, award := range awards {
+		teams, ok := expected[award.ID]
+		if !ok || !slices.Equal(award.TeamIDs, teams) {
+			t.Fatalf("Unexpected award %q for teams %v", award.ID, award.TeamIDs)
+		}
+	}
+	if awards[len(awards)-2].Citation != "First to solve problem A" {
+		t.Fatalf("Unexpected citation %q", awards[len(awards)-2].Citation)
+	}
+
+	// Teams without solved problems don't win
+	for _, entry := range ld.Entries {
+		entry.NumSolved, entry.Penalty = 0, 0
+		entry.ProblemScores = map[int]decimal.Decimal{}
+	}
+	if awards := clicsAwardsFromLeaderboard(ld, kilonova.MedalCutoffs{Gold: 1}); len(awards) != 0 {
+		t.Fatalf("Expected no awards without solved problems, got %d", len(awards))
+	}
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
 	"go.uber.org/zap"
 )
 
@@ -107,7 +109,11 @@ func (s *API) getContestProblems(w http.ResponseWriter, r *http.Request) {
 type contestLeaderboardParams struct {
 	Frozen bool `json:"frozen"`
 
-	Generated *bool `json:"generated_acc"`
+	Generated  *bool `json:"generated_acc"`
+	CategoryID *int  `json:"category_id"`
+
+	// official leaves out the ghost entries of virtual participants, for publishing results
+	official bool
 }
 
 func (s *API) leaderboard(ctx context.Context, contest *kilonova.Contest, lookingUser *kilonova.UserBrief, args *contestLeaderboardParams) (*kilonova.ContestLeaderboard, *kilonova.StatusError) {
@@ -115,11 +121,21 @@ func (s *API) leaderboard(ctx context.Context, contest *kilonova.Contest, lookin
 		return nil, kilonova.Statusf(400, "Leaderboard for this contest is not available")
 	}
 
-	return s.base.ContestLeaderboard(
-		ctx, contest,
-		s.base.UserContestFreezeTime(lookingUser, contest, args.Frozen),
-		kilonova.UserFilter{Generated: args.Generated},
-	)
+	freezeTime := s.base.UserContestFreezeTime(lookingUser, contest, args.Frozen)
+	filter := kilonova.UserFilter{Generated: args.Generated, ContestCategoryID: args.CategoryID}
+
+	// Virtual participants see their run next to the original participants
+	if lookingUser != nil && !args.official {
+		reg, err := s.base.ContestRegistration(ctx, contest.ID, lookingUser.ID)
+		if err != nil && !errors.Is(err, kilonova.ErrNotFound) {
+			return nil, err
+		}
+		if reg != nil && reg.Virtual {
+			return s.base.VirtualContestLeaderboard(ctx, contest, reg, freezeTime, filter)
+		}
+	}
+
+	return s.base.ContestLeaderboard(ctx, contest, freezeTime, filter)
 }
 
 func (s *API) contestLeaderboard(w http.ResponseWriter, r *http.Request) {
@@ -388,6 +404,10 @@ func (s *API) startContestRegistration(w http.ResponseWriter, r *http.Request) {
 	returnData(w, "Started contest registration.")
 }
 
+func (s *API) startVirtualContest(ctx context.Context, _ struct{}) *kilonova.StatusError {
+	return s.base.StartVirtualContest(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx).ID)
+}
+
 func (s *API) forceRegisterForContest(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
@@ -444,6 +464,50 @@ func (s *API) stripContestRegistration(w http.ResponseWriter, r *http.Request) {
 	returnData(w, "Kicked user")
 }
 
+func (s *API) updateContestRegistration(ctx context.Context, args struct {
+	Username string `json:"name"`
+
+	CategoryID     *int    `json:"category_id"`
+	RemoveCategory bool    `json:"remove_category"`
+	School         *string `json:"school"`
+	Region         *string `json:"region"`
+}) *kilonova.StatusError {
+	user, err := s.base.UserBriefByName(ctx, args.Username)
+	if err != nil {
+		return err
+	}
+	if _, err := s.base.ContestRegistration(ctx, util.ContestContext(ctx).ID, user.ID); err != nil {
+		return err
+	}
+	return s.base.UpdateContestRegistration(ctx, util.ContestContext(ctx).ID, user.ID, kilonova.ContestRegistrationUpdate{
+		CategoryID:     args.CategoryID,
+		RemoveCategory: args.RemoveCategory,
+		School:         args.School,
+		Region:         args.Region,
+	})
+}
+
+func (s *API) updateContestCategory(ctx context.Context, args struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}) *kilonova.StatusError {
+	category, err := s.base.ContestCategory(ctx, util.ContestContext(ctx).ID, args.ID)
+	if err != nil {
+		return err
+	}
+	return s.base.UpdateContestCategory(ctx, category.ID, args.Name)
+}
+
+func (s *API) deleteContestCategory(ctx context.Context, args struct {
+	ID int `json:"id"`
+}) *kilonova.StatusError {
+	category, err := s.base.ContestCategory(ctx, util.ContestContext(ctx).ID, args.ID)
+	if err != nil {
+		return err
+	}
+	return s.base.DeleteContestCategory(ctx, category.ID)
+}
+
 type regRez struct {
 	User *kilonova.UserBrief           `json:"user"`
 	Reg  *kilonova.ContestRegistration `json:"registration"`
@@ -454,6 +518,7 @@ func (s *API) contestRegistrations(w http.ResponseWriter, r *http.Request) {
 	var args struct {
 		FuzzyName    *string `json:"name_fuzzy"`
 		InvitationID *string `json:"invitation_id"`
+		CategoryID   *int    `json:"category_id"`
 
 		Limit  int `json:"limit"`
 		Offset int `json:"offset"`
@@ -467,7 +532,7 @@ func (s *API) contestRegistrations(w http.ResponseWriter, r *http.Request) {
 		args.Limit = 50
 	}
 
-	regs, err := s.base.ContestRegistrations(r.Context(), util.Contest(r).ID, args.FuzzyName, args.InvitationID, args.Limit, args.Offset)
+	regs, err := s.base.ContestRegistrations(r.Context(), util.Contest(r).ID, args.FuzzyName, args.InvitationID, args.CategoryID, args.Limit, args.Offset)
 	if err != nil {
 		err.WriteError(w)
 		return
@@ -519,9 +584,22 @@ func (s *API) contestRegistrations(w http.ResponseWriter, r *http.Request) {
 	}{Registrations: rez, Count: cnt})
 }
 
-func (s *API) runMOSS(ctx context.Context, args struct{}) *kilonova.StatusError {
+func (s *API) checkContestSimilarity(ctx context.Context, args struct{}) *kilonova.StatusError {
 	if util.ContestContext(ctx).Type != kilonova.ContestTypeOfficial {
-		return kilonova.Statusf(400, "MOSS can't run on virtual contests, for now")
+		return kilonova.Statusf(400, "Similarity checks can't run on virtual contests, for now")
 	}
-	return s.base.RunMOSS(context.WithoutCancel(ctx), util.ContestContext(ctx))
+	return s.base.CheckContestSimilarity(context.WithoutCancel(ctx), util.ContestContext(ctx))
+}
+
+func (s *API) contestSimilarityReport(ctx context.Context, args struct {
+	ID int `json:"id"`
+}) (*sudoapi.SimilarityReport, *kilonova.StatusError) {
+	return s.base.SimilarityReport(ctx, args.ID, &util.ContestContext(ctx).ID, nil)
+}
+
+func (s *API) contestSimilarityPair(ctx context.Context, args struct {
+	ReportID int `json:"report_id"`
+	PairID   int `json:"pair_id"`
+}) (*sudoapi.SimilarityPairDetails, *kilonova.StatusError) {
+	return s.base.SimilarityPair(ctx, args.ReportID, args.PairID, &util.ContestContext(ctx).ID, nil)
 }
@@ -538,3 +538,18 @@ func (s *API) getProblemAccessControl(ctx context.Context, _ struct{}) (*problem
 		Viewers: viewers,
 	}, nil
 }
+
+func (s *API) setSolutionExpectation(ctx context.Context, args struct {
+	SubmissionID int    `json:"submission_id"`
+	Expectation  string `json:"expectation"`
+}) *kilonova.StatusError {
+	return s.base.SetSolutionExpectation(ctx, util.ProblemContext(ctx), args.SubmissionID, args.Expectation)
+}
+
+func (s *API) verifyProblem(ctx context.Context, _ struct{}) (string, *kilonova.StatusError) {
+	count, err := s.base.VerifyProblem(context.WithoutCancel(ctx), util.ProblemContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Reevaluating %d model solutions", count), nil
+}
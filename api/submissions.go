@@ -93,7 +93,7 @@ func (s *API) createSubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lang, ok := eval.Langs[args.Lang]
+	lang, ok := eval.Langs()[args.Lang]
 	if !ok {
 		errorData(w, "Invalid language", 400)
 		return
@@ -125,3 +125,26 @@ func (s *API) createSubmission(w http.ResponseWriter, r *http.Request) {
 
 	returnData(w, id)
 }
+
+func (s *API) debugRun(ctx context.Context, args struct {
+	Lang string `json:"language"`
+	Code string `json:"code"`
+}) (*kilonova.DebugRun, *kilonova.StatusError) {
+	lang, ok := eval.Langs()[args.Lang]
+	if !ok {
+		return nil, kilonova.Statusf(400, "Invalid language")
+	}
+	return s.base.DebugRun(ctx, util.UserBriefContext(ctx), util.ProblemContext(ctx), []byte(args.Code), lang)
+}
+
+func (s *API) customInvocation(ctx context.Context, args struct {
+	Lang  string `json:"language"`
+	Code  string `json:"code"`
+	Input string `json:"input"`
+}) (*kilonova.CustomInvocation, *kilonova.StatusError) {
+	lang, ok := eval.Langs()[args.Lang]
+	if !ok {
+		return nil, kilonova.Statusf(400, "Invalid language")
+	}
+	return s.base.CustomInvocation(ctx, util.UserBriefContext(ctx), util.ProblemContext(ctx), []byte(args.Code), lang, []byte(args.Input))
+}
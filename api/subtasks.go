@@ -11,9 +11,10 @@ import (
 
 func (s *API) createSubTask(w http.ResponseWriter, r *http.Request) {
 	var args struct {
-		VisibleID int   `json:"visible_id"`
-		Score     int   `json:"score"`
-		Tests     []int `json:"tests"`
+		VisibleID int                     `json:"visible_id"`
+		Score     int                     `json:"score"`
+		Tests     []int                   `json:"tests"`
+		Scoring   kilonova.SubtaskScoring `json:"scoring"`
 	}
 	if err := parseJSONBody(r, &args); err != nil {
 		err.WriteError(w)
@@ -44,6 +45,7 @@ func (s *API) createSubTask(w http.ResponseWriter, r *http.Request) {
 		VisibleID: args.VisibleID,
 		Score:     decimal.NewFromInt(int64(args.Score)),
 		Tests:     realIDs,
+		Scoring:   args.Scoring,
 	}
 
 	if err := s.base.CreateSubTask(r.Context(), &stk); err != nil {
@@ -56,10 +58,11 @@ func (s *API) createSubTask(w http.ResponseWriter, r *http.Request) {
 func (s *API) updateSubTask(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		SubTaskID *int     `json:"subtask_id"`
-		NewID     *int     `json:"new_id"`
-		Score     *float64 `json:"score"`
-		Tests     []int    `json:"tests"`
+		SubTaskID *int                     `json:"subtask_id"`
+		NewID     *int                     `json:"new_id"`
+		Score     *float64                 `json:"score"`
+		Tests     []int                    `json:"tests"`
+		Scoring   *kilonova.SubtaskScoring `json:"scoring"`
 	}
 	if err := parseJSONBody(r, &args); err != nil {
 		err.WriteError(w)
@@ -86,6 +89,7 @@ func (s *API) updateSubTask(w http.ResponseWriter, r *http.Request) {
 	if err := s.base.UpdateSubTask(r.Context(), stk.ID, kilonova.SubTaskUpdate{
 		VisibleID: args.NewID,
 		Score:     score,
+		Scoring:   args.Scoring,
 	}); err != nil {
 		err.WriteError(w)
 		return
@@ -39,8 +39,9 @@ func (s *API) saveTestData(w http.ResponseWriter, r *http.Request) {
 func (s *API) updateTestInfo(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		ID    int
-		Score string
+		ID      int
+		Score   string
+		Example *bool
 	}
 	if err := decoder.Decode(&args, r.Form); err != nil {
 		errorData(w, err, http.StatusBadRequest)
@@ -58,7 +59,7 @@ func (s *API) updateTestInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.base.UpdateTest(r.Context(), util.Test(r).ID, kilonova.TestUpdate{VisibleID: &args.ID, Score: &scoreValue}); err != nil {
+	if err := s.base.UpdateTest(r.Context(), util.Test(r).ID, kilonova.TestUpdate{VisibleID: &args.ID, Score: &scoreValue, Example: args.Example}); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -120,12 +121,8 @@ func (s *API) createTest(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *API) processArchive(r *http.Request, changeTestName bool) *kilonova.StatusError {
-	// Since this operation can take a lot of space, I am putting this lock as a precaution.
-	// This might create a problem with timeouts, and this should be handled asynchronously.
-	// (ie not in a request), but eh, I cant be bothered right now to do it the right way.
-	// TODO: Do this the right way (low priority)
-	s.testArchiveLock.Lock()
-	defer s.testArchiveLock.Unlock()
+	// Archives can be big, so the tests of a problem are changed by a single archive (or test generation) at a time
+	defer s.base.LockProblemTests(util.Problem(r).ID)()
 	r.ParseMultipartForm(20 * 1024 * 1024)
 	defer cleanupMultipart(r)
 
@@ -164,6 +161,10 @@ func (s *API) processTestArchive(w http.ResponseWriter, r *http.Request) {
 	returnData(w, "Processed tests")
 }
 
+func (s *API) generateTests(ctx context.Context, _ struct{}) *kilonova.StatusError {
+	return s.base.StartTestGeneration(ctx, util.ProblemContext(ctx))
+}
+
 func (s *API) bulkDeleteTests(w http.ResponseWriter, r *http.Request) {
 	var removedTests int
 	var testIDs []int
@@ -29,12 +29,16 @@ type ArchiveCtx struct {
 	props       *properties
 
 	submissions []*submissionStub
+	// solutionVerdicts are the expected results of the submissions, by file name
+	solutionVerdicts map[string]string
 
 	params *TestProcessParams
 
 	scoreParameters []ScoreParamEntry
 
 	testScores ScoreFileEntries
+	// exampleTests are the visible IDs of the tests marked as examples (samples)
+	exampleTests map[int]bool
 }
 
 type properties struct {
@@ -66,6 +70,10 @@ func NewArchiveCtx(params *TestProcessParams) *ArchiveCtx {
 		attachments: make(map[string]archiveAttachment),
 		testScores:  make(ScoreFileEntries),
 
+		exampleTests: make(map[int]bool),
+
+		solutionVerdicts: make(map[string]string),
+
 		params: params,
 	}
 }
@@ -328,6 +336,7 @@ func ProcessZipTestArchive(ctx context.Context, pb *kilonova.Problem, ar *zip.Re
 			test.ProblemID = pb.ID
 			test.VisibleID = v.VisibleID
 			test.Score = v.Score
+			test.Example = aCtx.exampleTests[v.VisibleID]
 			if err := base.CreateTest(ctx, &test); err != nil {
 				zap.S().Warn(err)
 				return err
@@ -422,6 +431,7 @@ func ProcessZipTestArchive(ctx context.Context, pb *kilonova.Problem, ar *zip.Re
 					VisibleID: stkId,
 					Score:     stk.Score,
 					Tests:     tests,
+					Scoring:   stk.Scoring,
 				}); err != nil {
 					zap.S().Warn(err)
 					return kilonova.WrapError(err, "Couldn't create subtask")
@@ -552,13 +562,20 @@ func ProcessZipTestArchive(ctx context.Context, pb *kilonova.Problem, ar *zip.Re
 	// Do submissions at the end after all changes have been merged
 	if len(aCtx.submissions) > 0 {
 		for _, sub := range aCtx.submissions {
-			lang, ok := eval.Langs[sub.lang]
+			lang, ok := eval.Langs()[sub.lang]
 			if !ok {
 				zap.S().Warn("Skipping submission")
 				continue
 			}
-			if _, err := base.CreateSubmission(ctx, params.Requestor, pb, sub.code, lang, nil, true); err != nil {
+			id, err := base.CreateSubmission(ctx, params.Requestor, pb, sub.code, lang, nil, true)
+			if err != nil {
 				zap.S().Warn(err)
+				continue
+			}
+			if verdict, ok := aCtx.solutionVerdicts[sub.name]; ok {
+				if err := base.SetSolutionExpectation(ctx, pb, id, verdict); err != nil {
+					zap.S().Warn(err)
+				}
 			}
 		}
 	}
@@ -75,10 +75,11 @@ func ProcessAttachmentFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusErro
 	return nil
 }
 
+// ProcessPolygonCheckFile imports the Polygon checker as a testlib checker, since it reports through testlib exit codes
 func ProcessPolygonCheckFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusError {
-	ctx.attachments["checker.cpp17"] = archiveAttachment{
+	ctx.attachments["checker_testlib.cpp17"] = archiveAttachment{
 		File:    file,
-		Name:    "checker.cpp17",
+		Name:    "checker_testlib.cpp17",
 		Visible: false,
 		Private: true,
 		Exec:    true,
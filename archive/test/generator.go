@@ -174,6 +174,8 @@ func (ag *archiveGenerator) addGraderProperties(ctx context.Context) *kilonova.S
 
 			groups := []string{}
 			weights := []string{}
+			scorings := []string{}
+			hasScoring := false
 
 			for _, st := range subtasks {
 				group := ""
@@ -190,9 +192,16 @@ func (ag *archiveGenerator) addGraderProperties(ctx context.Context) *kilonova.S
 				}
 				groups = append(groups, group)
 				weights = append(weights, st.Score.String())
+				scorings = append(scorings, string(st.Scoring))
+				if st.Scoring != kilonova.SubtaskScoringMin {
+					hasScoring = true
+				}
 			}
 			fmt.Fprintf(&buf, "groups=%s\n", strings.Join(groups, ","))
 			fmt.Fprintf(&buf, "weights=%s\n", strings.Join(weights, ","))
+			if hasScoring {
+				fmt.Fprintf(&buf, "subtask_scoring=%s\n", strings.Join(scorings, ","))
+			}
 		}
 	}
 	if ag.opts.ProblemDetails {
@@ -272,13 +281,27 @@ func (ag *archiveGenerator) addSubmissions(ctx context.Context) *kilonova.Status
 	if err != nil {
 		return err
 	}
+	exps, err := ag.base.ProblemSolutionExpectations(ctx, ag.pb.ID)
+	if err != nil {
+		return err
+	}
+	expectations := make(map[int]*kilonova.SolutionExpectation)
+	for _, exp := range exps {
+		expectations[exp.SubmissionID] = exp
+	}
+
+	var verdicts strings.Builder
 	for _, sub := range subs {
-		lang, ok := eval.Langs[sub.Language]
+		lang, ok := eval.Langs()[sub.Language]
 		if !ok || lang.Disabled {
 			zap.S().Infof("Skipping submission due to unknown/disabled language (%q): %d", sub.Language, sub.ID)
 			continue
 		}
-		f, err := ag.ar.Create(fmt.Sprintf("submissions/%d-%sp%s", sub.ID, sub.Score.String(), lang.Extensions[len(lang.Extensions)-1]))
+		name := fmt.Sprintf("%d-%sp%s", sub.ID, sub.Score.String(), lang.Extensions[len(lang.Extensions)-1])
+		if exp, ok := expectations[sub.ID]; ok {
+			fmt.Fprintf(&verdicts, "%s %s\n", name, exp)
+		}
+		f, err := ag.ar.Create("submissions/" + name)
 		if err != nil {
 			return kilonova.WrapError(err, "Couldn't create archive submission file")
 		}
@@ -291,6 +314,16 @@ func (ag *archiveGenerator) addSubmissions(ctx context.Context) *kilonova.Status
 			return kilonova.WrapError(err, "Couldn't write submission file")
 		}
 	}
+
+	if verdicts.Len() > 0 {
+		f, err := ag.ar.Create("submissions/" + SolutionVerdictsFile)
+		if err != nil {
+			return kilonova.WrapError(err, "Couldn't create expected verdicts file")
+		}
+		if _, err := f.Write([]byte(verdicts.String())); err != nil {
+			return kilonova.WrapError(err, "Couldn't write expected verdicts file")
+		}
+	}
 	return nil
 }
 
@@ -2,6 +2,7 @@ package test
 
 import (
 	"io"
+	"path"
 	"strconv"
 
 	"github.com/KiloProjects/kilonova"
@@ -51,6 +52,9 @@ func ProcessProblemXMLFile(actx *ArchiveCtx, file io.Reader) *kilonova.StatusErr
 				actx.testScores[id+1] = val
 			}
 		}
+		if test.SelectAttr("sample") == "true" {
+			actx.exampleTests[id+1] = true
+		}
 		if group := test.SelectAttr("group"); group != "" {
 			stk, ok := subtasks[group]
 			if !ok {
@@ -75,6 +79,18 @@ func ProcessProblemXMLFile(actx *ArchiveCtx, file io.Reader) *kilonova.StatusErr
 					stk.Score = val
 				}
 			}
+			switch group.SelectAttr("points-policy") {
+			case "complete-group":
+				stk.Scoring = kilonova.SubtaskScoringAllOrNothing
+			case "each-test":
+				// The group is worth the sum of its test points, which are usually equal
+				stk.Scoring = kilonova.SubtaskScoringSum
+				if stk.Score.IsZero() {
+					for _, test := range stk.Tests {
+						stk.Score = stk.Score.Add(actx.testScores[test])
+					}
+				}
+			}
 
 			var dependencies []string
 			for _, dep := range xmlquery.Find(group, "//dependencies/dependency") {
@@ -93,6 +109,21 @@ func ProcessProblemXMLFile(actx *ArchiveCtx, file io.Reader) *kilonova.StatusErr
 		actx.props.Subtasks, actx.props.SubtaskedTests = solveSubtaskDependencies(subtasks)
 	}
 
+	// Get expected results of solutions, matched by file name with the submissions in the archive
+	for _, sol := range xmlquery.Find(node, "//assets/solutions/solution") {
+		source := xmlquery.FindOne(sol, "source")
+		if source == nil {
+			continue
+		}
+		name := path.Base(source.SelectAttr("path"))
+		if _, ok := actx.solutionVerdicts[name]; ok {
+			continue
+		}
+		if _, err := kilonova.ParseSolutionExpectation(sol.SelectAttr("tag")); err == nil {
+			actx.solutionVerdicts[name] = sol.SelectAttr("tag")
+		}
+	}
+
 	// Parse time/memory limit
 	if node := xmlquery.FindOne(testsetNode, "//time-limit"); node != nil {
 		timeLimit, err := strconv.Atoi(node.Data)
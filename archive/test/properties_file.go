@@ -17,8 +17,9 @@ import (
 )
 
 type Subtask struct {
-	Score decimal.Decimal
-	Tests []int
+	Score   decimal.Decimal
+	Tests   []int
+	Scoring kilonova.SubtaskScoring
 }
 
 type mockTag struct {
@@ -27,16 +28,18 @@ type mockTag struct {
 }
 
 type PropertiesRaw struct {
-	Groups       string   `props:"groups"`
-	Weights      string   `props:"weights"`
-	Dependencies string   `props:"dependencies"`
-	Time         *float64 `props:"time"`
-	Memory       *float64 `props:"memory"`
-	Tags         *string  `props:"tags"`
-	Source       *string  `props:"source"`
-	ConsoleInput *string  `props:"console_input"`
-	TestName     *string  `props:"test_name"`
-	ProblemName  *string  `props:"problem_name"`
+	Groups       string `props:"groups"`
+	Weights      string `props:"weights"`
+	Dependencies string `props:"dependencies"`
+	// SubtaskScoring is either a single scoring rule for all groups or one rule for every group
+	SubtaskScoring string   `props:"subtask_scoring"`
+	Time           *float64 `props:"time"`
+	Memory         *float64 `props:"memory"`
+	Tags           *string  `props:"tags"`
+	Source         *string  `props:"source"`
+	ConsoleInput   *string  `props:"console_input"`
+	TestName       *string  `props:"test_name"`
+	ProblemName    *string  `props:"problem_name"`
 
 	Editors *string `props:"editors"`
 
@@ -225,6 +228,22 @@ func ProcessPropertiesFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusErro
 			stks[strconv.Itoa(i+1)] = stk
 		}
 
+		if rawProps.SubtaskScoring != "" {
+			scoringStrings := strings.Split(rawProps.SubtaskScoring, ",")
+			if len(scoringStrings) != 1 && len(scoringStrings) != len(groupStrings) {
+				return kilonova.Statusf(400, "Number of subtask scoring rules must be 1 or match number of groups")
+			}
+			for i := range len(groupStrings) {
+				scoring := kilonova.SubtaskScoring(strings.TrimSpace(scoringStrings[min(i, len(scoringStrings)-1)]))
+				if !scoring.Valid() {
+					return kilonova.Statusf(400, "Invalid subtask scoring rule %q in properties", scoring)
+				}
+				stk := stks[strconv.Itoa(i+1)]
+				stk.Scoring = scoring
+				stks[strconv.Itoa(i+1)] = stk
+			}
+		}
+
 		if rawProps.Dependencies != "" {
 			depStrings := strings.Split(rawProps.Dependencies, ",")
 			if len(depStrings) != len(weightStrings) {
@@ -261,8 +280,9 @@ func ProcessPropertiesFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusErro
 }
 
 type parsedSubtask struct {
-	Score decimal.Decimal
-	Tests []int
+	Score   decimal.Decimal
+	Tests   []int
+	Scoring kilonova.SubtaskScoring
 
 	// The current subtask is automatically considered a dependency
 	Dependencies []string
@@ -275,7 +295,7 @@ func solveSubtaskDependencies(subtasks map[string]parsedSubtask) (stks map[int]S
 	finalSubtasks := make(map[string]Subtask)
 
 	for id, group := range subtasks {
-		stk := Subtask{Score: group.Score}
+		stk := Subtask{Score: group.Score, Scoring: group.Scoring}
 		stk.Tests = slices.Clone(group.Tests)
 		for _, dependency := range group.Dependencies {
 			dep, ok := subtasks[dependency]
@@ -11,7 +11,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// SolutionVerdictsFile is the file in the submissions directory with the expected results of the model solutions.
+// Every line has the name of a submission file, followed by its expected result (see kilonova.ParseSolutionExpectation)
+const SolutionVerdictsFile = "verdicts.txt"
+
 type submissionStub struct {
+	name string
 	code []byte
 	lang string
 }
@@ -21,11 +26,16 @@ func ProcessSubmissionFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusErro
 	if err != nil {
 		return kilonova.WrapError(err, "Couldn't open submission file")
 	}
+	defer f.Close()
 	data, err := io.ReadAll(f)
 	if err != nil {
 		return kilonova.WrapError(err, "Couldn't read submission file")
 	}
 
+	if path.Base(file.Name) == SolutionVerdictsFile {
+		return parseSolutionVerdicts(ctx, string(data))
+	}
+
 	lang := eval.GetLangByFilename(path.Base(file.Name))
 	if lang == "" {
 		if !strings.HasSuffix(file.Name, ".desc") { // Don't show for polygon description files
@@ -35,8 +45,23 @@ func ProcessSubmissionFile(ctx *ArchiveCtx, file *zip.File) *kilonova.StatusErro
 	}
 
 	ctx.submissions = append(ctx.submissions, &submissionStub{
+		name: path.Base(file.Name),
 		code: data,
 		lang: lang,
 	})
 	return nil
 }
+
+func parseSolutionVerdicts(ctx *ArchiveCtx, data string) *kilonova.StatusError {
+	for i, line := range strings.Split(data, "\n") {
+		name, expectation, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if _, err := kilonova.ParseSolutionExpectation(expectation); err != nil {
+			return kilonova.Statusf(400, "Invalid expected result on line %d of %s: %s", i+1, SolutionVerdictsFile, err)
+		}
+		ctx.solutionVerdicts[name] = strings.TrimSpace(expectation)
+	}
+	return nil
+}
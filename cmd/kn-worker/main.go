@@ -0,0 +1,85 @@
+// kn-worker is a remote grader worker. It connects to a Kilonova instance that has feature.grader.worker_token set,
+// and runs compile and execute jobs using the local sandboxes.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/remote"
+	"github.com/KiloProjects/kilonova/eval/scheduler"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	confPath = flag.String("config", "./config.toml", "Config path. Only the eval section and data_dir are used. Languages are loaded from languages.toml in the same directory")
+	server   = flag.String("server", "http://localhost:8070/grader", "Address of the main node grader endpoints")
+	token    = flag.String("token", os.Getenv("KN_WORKER_TOKEN"), "Worker token, as set in feature.grader.worker_token on the main node. Defaults to $KN_WORKER_TOKEN")
+	name     = flag.String("name", "", "Worker name, shown in logs on the main node. Defaults to the hostname")
+	insecure = flag.Bool("insecure", false, "Allow running without a secure sandbox, using the stupid sandbox. Never use this in production")
+)
+
+func main() {
+	flag.Parse()
+	initLogger(true)
+
+	config.SetConfigPath(*confPath)
+	if err := config.Load(); err != nil {
+		zap.S().Fatal(err)
+	}
+	initLogger(config.Common.Debug)
+
+	if *token == "" {
+		zap.S().Fatal("No worker token specified")
+	}
+	if *name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			zap.S().Fatal(err)
+		}
+		*name = hostname
+	}
+
+	// Binaries and outputs are kept only while the job runs, but compile caching still works locally
+	if err := datastore.InitBuckets(config.Common.DataDir); err != nil {
+		zap.S().Fatal(err)
+	}
+	eval.SetLanguagesPath(filepath.Join(filepath.Dir(*confPath), "languages.toml"))
+	if err := eval.Initialize(); err != nil {
+		zap.S().Fatal("Could not initialize the box manager:", err)
+	}
+
+	boxFunc, boxVersion, err := scheduler.SandboxBackend(*insecure)
+	if err != nil {
+		zap.S().Fatal(err)
+	}
+	zap.S().Infof("Using sandbox %s", boxVersion)
+	logger := zap.S()
+	runner, err := scheduler.New(config.Eval.StartingBox, config.Eval.NumConcurrent, config.Eval.GlobalMaxMem, logger, boxFunc)
+	if err != nil {
+		zap.S().Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	zap.S().Infof("Starting worker %q for %s", *name, *server)
+	if err := remote.NewWorker(*server, *token, *name, runner, logger).Run(ctx); err != nil && ctx.Err() == nil {
+		zap.S().Fatal(err)
+	}
+	if err := runner.Close(context.Background()); err != nil {
+		zap.S().Warn(err)
+	}
+}
+
+func initLogger(debug bool) {
+	core := kilonova.GetZapCore(debug, true, os.Stdout)
+	zap.ReplaceGlobals(zap.New(core, zap.AddCaller()))
+}
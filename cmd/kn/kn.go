@@ -13,7 +13,9 @@ import (
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/api"
+	"github.com/KiloProjects/kilonova/datastore"
 	"github.com/KiloProjects/kilonova/eval/grader"
+	"github.com/KiloProjects/kilonova/eval/remote"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/sudoapi"
 	"github.com/KiloProjects/kilonova/web"
@@ -45,6 +47,16 @@ func Kilonova() error {
 	base.Start(ctx)
 	defer base.Close()
 
+	var dispatcher *remote.Dispatcher
+	if token := remote.WorkerToken.Value(); token != "" {
+		dispatcher = remote.NewDispatcher(token,
+			datastore.GetBucket(datastore.BucketTypeCompiles),
+			datastore.GetBucket(datastore.BucketTypeSubtests),
+			datastore.GetBucket(datastore.BucketTypeTests),
+		)
+		go dispatcher.Start(ctx)
+	}
+
 	// Initialize components
 	if graderFeature.Value() { // TODO: Hot stopping/starting grader
 		grader, err := grader.NewHandler(ctx, base)
@@ -52,6 +64,9 @@ func Kilonova() error {
 			zap.S().Fatal(err)
 		}
 		defer grader.Close()
+		if dispatcher != nil {
+			grader.UseRemoteWorkers(dispatcher)
+		}
 
 		go func() {
 			err := grader.Start()
@@ -66,7 +81,7 @@ func Kilonova() error {
 	}
 
 	// for graceful setup and shutdown
-	server := webV1(true, base)
+	server := webV1(true, base, dispatcher)
 
 	go launchProfiler()
 	go func() {
@@ -113,7 +128,7 @@ var (
 )
 
 // initialize webserver for public api+web
-func webV1(templWeb bool, base *sudoapi.BaseAPI) *http.Server {
+func webV1(templWeb bool, base *sudoapi.BaseAPI, dispatcher *remote.Dispatcher) *http.Server {
 	// Initialize router
 	r := chi.NewRouter()
 
@@ -137,6 +152,9 @@ func webV1(templWeb bool, base *sudoapi.BaseAPI) *http.Server {
 
 	r.Mount("/api", api.New(base).Handler())
 	r.Mount("/assets", api.NewAssets(base).AssetsRouter())
+	if dispatcher != nil {
+		r.Mount("/grader", dispatcher.Handler())
+	}
 
 	if templWeb {
 		r.Mount("/", web.NewWeb(base).Handler())
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
 
 	"github.com/KiloProjects/kilonova/eval"
 	"github.com/KiloProjects/kilonova/internal/config"
@@ -42,6 +43,7 @@ func main() {
 		zap.S().Fatal(err)
 	}
 
+	eval.SetLanguagesPath(filepath.Join(filepath.Dir(*confPath), "languages.toml"))
 	if err := eval.Initialize(); err != nil {
 		zap.S().Fatal("Could not initialize the box manager:", err)
 	}
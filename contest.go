@@ -1,6 +1,7 @@
 package kilonova
 
 import (
+	"slices"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -53,10 +54,6 @@ type Contest struct {
 	// Contestants may be able to see the contest
 	Visible bool `json:"hidden"`
 
-	// Virtual indicates a virtual contest.
-	// TODO: Implement this
-	// Virtual bool `json:"virtual"`
-
 	// PublicLeaderboard controls whether the contest's leaderboard
 	// is viewable by everybody or just admins
 	PublicLeaderboard bool `json:"public_leaderboard"`
@@ -106,6 +103,14 @@ func (c *Contest) Running() bool {
 	return c.Started() && !c.Ended()
 }
 
+// VirtualDuration is the length of a virtual participation: the per-user time for USACO-style contests, the whole contest otherwise
+func (c *Contest) VirtualDuration() time.Duration {
+	if c.PerUserTime > 0 {
+		return time.Duration(c.PerUserTime) * time.Second
+	}
+	return c.EndTime.Sub(c.StartTime)
+}
+
 type ContestFilter struct {
 	ID          *int       `json:"id"`
 	IDs         []int      `json:"ids"`
@@ -194,12 +199,15 @@ type ContestAnnouncement struct {
 	Text      string    `json:"text"`
 }
 
+// MOSSSubmission is a code similarity report over the submissions of a problem in a given language.
+// Reports used to be generated by MOSS, but are now computed natively. Only the old MOSS reports have an URL
 type MOSSSubmission struct {
 	ID        int       `json:"id" db:"id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 
-	ContestID int `json:"contest_id" db:"contest_id"`
-	ProblemID int `json:"problem_id" db:"problem_id"`
+	// ContestID is nil for reports over all the submissions of a problem
+	ContestID *int `json:"contest_id" db:"contest_id"`
+	ProblemID int  `json:"problem_id" db:"problem_id"`
 
 	Language string `json:"language" db:"language"`
 
@@ -207,6 +215,33 @@ type MOSSSubmission struct {
 	SubCount int    `json:"subcount" db:"subcount"`
 }
 
+// SimilarityMatch is a region of code shared by the two submissions of a pair, in lines (1-indexed, inclusive)
+type SimilarityMatch struct {
+	LeftStart  int `json:"left_start"`
+	LeftEnd    int `json:"left_end"`
+	RightStart int `json:"right_start"`
+	RightEnd   int `json:"right_end"`
+	Tokens     int `json:"tokens"`
+}
+
+// SimilarityPair is a pair of similar submissions found in a similarity report
+type SimilarityPair struct {
+	ID       int `json:"id" db:"id"`
+	ReportID int `json:"report_id" db:"report_id"`
+
+	LeftSubmissionID  int `json:"left_sub_id" db:"left_sub_id"`
+	RightSubmissionID int `json:"right_sub_id" db:"right_sub_id"`
+	LeftUserID        int `json:"left_user_id" db:"left_user_id"`
+	RightUserID       int `json:"right_user_id" db:"right_user_id"`
+
+	// LeftSimilarity and RightSimilarity are the fractions (between 0 and 1) of each submission's code found in the other one
+	LeftSimilarity  float64 `json:"left_similarity" db:"left_similarity"`
+	RightSimilarity float64 `json:"right_similarity" db:"right_similarity"`
+	MatchedTokens   int     `json:"matched_tokens" db:"matched_tokens"`
+
+	Matches []*SimilarityMatch `json:"matches" db:"matches"`
+}
+
 type ContestRegistration struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	ContestID int       `json:"contest_id" db:"contest_id"`
@@ -216,6 +251,40 @@ type ContestRegistration struct {
 	IndividualEndTime   *time.Time `json:"individual_end" db:"individual_end_at"`
 
 	InvitationID *string `json:"invitation_id" db:"invitation_id"`
+
+	CategoryID *int   `json:"category_id" db:"category_id"`
+	School     string `json:"school" db:"school"`
+	Region     string `json:"region" db:"region"`
+
+	// Virtual marks a personal run of the contest after it ended, timed by the individual start and end times
+	Virtual bool `json:"virtual" db:"virtual"`
+}
+
+// VirtualRunning reports if the registration is a virtual participation that is currently underway
+func (reg *ContestRegistration) VirtualRunning() bool {
+	if reg == nil || !reg.Virtual || reg.IndividualStartTime == nil || reg.IndividualEndTime == nil {
+		return false
+	}
+	return time.Now().After(*reg.IndividualStartTime) && time.Now().Before(*reg.IndividualEndTime)
+}
+
+// ContestRegistrationUpdate holds the registration details contest editors can change
+type ContestRegistrationUpdate struct {
+	CategoryID *int `json:"category_id"`
+	// RemoveCategory moves the participant out of any category, since a nil CategoryID means no update
+	RemoveCategory bool `json:"remove_category"`
+
+	School *string `json:"school"`
+	Region *string `json:"region"`
+}
+
+// ContestCategory is a contest-defined group of participants, such as "official", "official at home" or "unofficial".
+// Participants get the category of the invitation they registered with, and the leaderboard can be filtered by it
+type ContestCategory struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	Name      string    `json:"name"`
 }
 
 type ContestInvitation struct {
@@ -228,6 +297,9 @@ type ContestInvitation struct {
 	RedeemCount int  `json:"redeem_count" db:"redeem_cnt"`
 	MaxCount    *int `json:"max_invitation_count" db:"max_invitation_cnt"`
 
+	// CategoryID is the category given to the users registering with the invitation
+	CategoryID *int `json:"category_id" db:"category_id"`
+
 	Expired bool `json:"expired"`
 }
 
@@ -252,6 +324,18 @@ type LeaderboardEntry struct {
 
 	LastTime   *time.Time `json:"last_time"`
 	FreezeTime *time.Time `json:"freeze_time"`
+
+	// Registration details
+	CategoryID *int   `json:"category_id"`
+	School     string `json:"school"`
+	Region     string `json:"region"`
+
+	// Rank is the position in the leaderboard, CategoryRank is the position among the participants of the same category
+	Rank         int `json:"rank"`
+	CategoryRank int `json:"category_rank"`
+
+	// Virtual marks the ghost entry of a virtual participant. Its times are moved onto the original contest's timeline
+	Virtual bool `json:"virtual"`
 }
 
 type ContestLeaderboard struct {
@@ -261,6 +345,125 @@ type ContestLeaderboard struct {
 
 	AdvancedFilter bool `json:"advanced_filter"`
 
+	Categories []*ContestCategory `json:"categories"`
+
 	FreezeTime *time.Time      `json:"freeze_time"`
 	Type       LeaderboardType `json:"type"`
 }
+
+// Rank sets the ranks of the (already sorted) entries, which are also their official places: tied participants share a rank.
+// Classic participants tie on total score, ICPC participants on the number of solved problems and penalty.
+// Participants without a category have no category rank
+func (ld *ContestLeaderboard) Rank() {
+	categoryCounts := make(map[int]int)
+	categoryLast := make(map[int]*LeaderboardEntry)
+	for i, entry := range ld.Entries {
+		entry.Rank = i + 1
+		if i > 0 && ld.tied(ld.Entries[i-1], entry) {
+			entry.Rank = ld.Entries[i-1].Rank
+		}
+		entry.CategoryRank = 0
+		if entry.CategoryID != nil {
+			categoryCounts[*entry.CategoryID]++
+			entry.CategoryRank = categoryCounts[*entry.CategoryID]
+			if last, ok := categoryLast[*entry.CategoryID]; ok && ld.tied(last, entry) {
+				entry.CategoryRank = last.CategoryRank
+			}
+			categoryLast[*entry.CategoryID] = entry
+		}
+	}
+}
+
+func (ld *ContestLeaderboard) tied(a, b *LeaderboardEntry) bool {
+	if ld.Type == LeaderboardTypeICPC {
+		return a.NumSolved == b.NumSolved && a.Penalty == b.Penalty
+	}
+	return a.TotalScore.Equal(b.TotalScore)
+}
+
+type Medal string
+
+const (
+	MedalNone   Medal = ""
+	MedalGold   Medal = "gold"
+	MedalSilver Medal = "silver"
+	MedalBronze Medal = "bronze"
+)
+
+// MedalCutoffs holds the last place that is awarded each medal. A cutoff of 0 awards no medals of that kind
+type MedalCutoffs struct {
+	Gold   int `json:"gold"`
+	Silver int `json:"silver"`
+	Bronze int `json:"bronze"`
+}
+
+// Valid checks that the cutoffs are not negative and that better medals have lower cutoffs
+func (mc MedalCutoffs) Valid() bool {
+	if mc.Gold < 0 || mc.Silver < 0 || mc.Bronze < 0 {
+		return false
+	}
+	if mc.Silver > 0 && mc.Silver < mc.Gold {
+		return false
+	}
+	return mc.Bronze == 0 || mc.Bronze >= max(mc.Gold, mc.Silver)
+}
+
+// Medal returns the medal awarded for the given place
+func (mc MedalCutoffs) Medal(place int) Medal {
+	switch {
+	case place <= 0:
+		return MedalNone
+	case place <= mc.Gold:
+		return MedalGold
+	case place <= mc.Silver:
+		return MedalSilver
+	case place <= mc.Bronze:
+		return MedalBronze
+	default:
+		return MedalNone
+	}
+}
+
+// Medals awards medals to the (already ranked) entries according to their ranks. Participants without any points get no medal
+func (ld *ContestLeaderboard) Medals(cutoffs MedalCutoffs) []Medal {
+	medals := make([]Medal, len(ld.Entries))
+	for i, entry := range ld.Entries {
+		if (ld.Type == LeaderboardTypeICPC && entry.NumSolved == 0) || (ld.Type != LeaderboardTypeICPC && !entry.TotalScore.IsPositive()) {
+			continue
+		}
+		medals[i] = cutoffs.Medal(entry.Rank)
+	}
+	return medals
+}
+
+// ranksBefore reports if entry a is placed strictly before entry b in a leaderboard of the given type
+func (ld *ContestLeaderboard) ranksBefore(a, b *LeaderboardEntry) bool {
+	if ld.Type == LeaderboardTypeICPC {
+		if a.NumSolved != b.NumSolved {
+			return a.NumSolved > b.NumSolved
+		}
+		if a.Penalty != b.Penalty {
+			return a.Penalty < b.Penalty
+		}
+	} else if !a.TotalScore.Equal(b.TotalScore) {
+		return a.TotalScore.GreaterThan(b.TotalScore)
+	}
+	if a.LastTime == nil || b.LastTime == nil {
+		return a.LastTime != nil && b.LastTime == nil
+	}
+	return a.LastTime.Before(*b.LastTime)
+}
+
+// AddGhost inserts the entry of a virtual participant in the (already sorted) leaderboard,
+// after all the original participants it does not beat. Rank should be called afterwards
+func (ld *ContestLeaderboard) AddGhost(ghost *LeaderboardEntry) {
+	ghost.Virtual = true
+	pos := len(ld.Entries)
+	for i, entry := range ld.Entries {
+		if ld.ranksBefore(ghost, entry) {
+			pos = i
+			break
+		}
+	}
+	ld.Entries = slices.Insert(ld.Entries, pos, ghost)
+}
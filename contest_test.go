@@ -0,0 +1,112 @@
+package kilonova
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLeaderboardRank(t *testing.T) {
+	official, unofficial := 1, 2
+	ld := &ContestLeaderboard{Type: LeaderboardTypeClassic, Entries: []*LeaderboardEntry{
+		{TotalScore: decimal.NewFromInt(500), CategoryID: &unofficial},
+		{TotalScore: decimal.NewFromInt(400), CategoryID: &official},
+		{TotalScore: decimal.NewFromInt(400)},
+		{TotalScore: decimal.NewFromInt(400), CategoryID: &official},
+		{TotalScore: decimal.NewFromInt(300), CategoryID: &unofficial},
+	}}
+	ld.Rank()
+	// Tied participants share their rank, both overall and in their category
+	var expected = [][2]int{{1, 1}, {2, 1}, {2, 0}, {2, 1}, {5, 2}}
+	for i, entry := range ld.Entries {
+		if entry.Rank != expected[i][0] || entry.CategoryRank != expected[i][1] {
+			t.Fatalf("Wanted ranks %v for entry %d, got %d and %d", expected[i], i, entry.Rank, entry.CategoryRank)
+		}
+	}
+}
+
+func TestLeaderboardAddGhost(t *testing.T) {
+	start := time.Now()
+	at := func(mins int) *time.Time {
+		t := start.Add(time.Duration(mins) * time.Minute)
+		return &t
+	}
+	classic := func(total int64, last *time.Time) *LeaderboardEntry {
+		return &LeaderboardEntry{TotalScore: decimal.NewFromInt(total), LastTime: last}
+	}
+
+	ld := &ContestLeaderboard{Type: LeaderboardTypeClassic, Entries: []*LeaderboardEntry{
+		classic(200, at(50)), classic(100, at(20)), classic(100, at(40)), classic(0, nil),
+	}}
+	ghost := classic(100, at(30))
+	ld.AddGhost(ghost)
+	if ld.Entries[2] != ghost || !ghost.Virtual {
+		t.Fatalf("Ghost should be placed between the tied entries by time")
+	}
+
+	// Ties are broken in favour of the original participants
+	ghost = classic(0, nil)
+	ld.AddGhost(ghost)
+	if ld.Entries[len(ld.Entries)-1] != ghost {
+		t.Fatalf("Ghost without points should be placed last")
+	}
+
+	icpc := &ContestLeaderboard{Type: LeaderboardTypeICPC, Entries: []*LeaderboardEntry{
+		{NumSolved: 3, Penalty: 200, LastTime: at(100)},
+		{NumSolved: 2, Penalty: 90, LastTime: at(60)},
+	}}
+	ghost = &LeaderboardEntry{NumSolved: 3, Penalty: 150, LastTime: at(120)}
+	icpc.AddGhost(ghost)
+	if icpc.Entries[0] != ghost {
+		t.Fatalf("Ghost with a smaller penalty should be placed first")
+	}
+}
+
+func TestLeaderboardMedals(t *testing.T) {
+	classic := func(total int64) *LeaderboardEntry {
+		return &LeaderboardEntry{TotalScore: decimal.NewFromInt(total)}
+	}
+	ld := &ContestLeaderboard{Type: LeaderboardTypeClassic, Entries: []*LeaderboardEntry{
+		classic(300), classic(250), classic(250), classic(250), classic(100), classic(0),
+	}}
+	ld.Rank()
+	var expected = []int{1, 2, 2, 2, 5, 6}
+	for i, entry := range ld.Entries {
+		if entry.Rank != expected[i] {
+			t.Fatalf("Wanted rank %d for entry %d, got %d", expected[i], i, entry.Rank)
+		}
+	}
+
+	// The last participant would get bronze by place, but has no points
+	medals := ld.Medals(MedalCutoffs{Gold: 1, Silver: 3, Bronze: 6})
+	var expectedMedals = []Medal{MedalGold, MedalSilver, MedalSilver, MedalSilver, MedalBronze, MedalNone}
+	if !slices.Equal(medals, expectedMedals) {
+		t.Fatalf("Wanted medals %v, got %v", expectedMedals, medals)
+	}
+
+	icpc := &ContestLeaderboard{Type: LeaderboardTypeICPC, Entries: []*LeaderboardEntry{
+		{NumSolved: 3, Penalty: 100}, {NumSolved: 3, Penalty: 100}, {NumSolved: 3, Penalty: 120},
+	}}
+	icpc.Rank()
+	if medals := icpc.Medals(MedalCutoffs{Gold: 1, Silver: 2}); !slices.Equal(medals, []Medal{MedalGold, MedalGold, MedalNone}) {
+		t.Fatalf("Wanted tied ICPC participants to share the gold medal, got %v", medals)
+	}
+}
+
+func TestMedalCutoffsValid(t *testing.T) {
+	var cases = map[MedalCutoffs]bool{
+		{}:                              true,
+		{Gold: 1, Silver: 3, Bronze: 6}: true,
+		{Gold: 2, Bronze: 6}:            true,
+		{Gold: 3, Silver: 2}:            false,
+		{Gold: 2, Silver: 4, Bronze: 3}: false,
+		{Gold: -1}:                      false,
+	}
+	for cutoffs, want := range cases {
+		if got := cutoffs.Valid(); got != want {
+			t.Fatalf("Wanted %t for %+v, got %t", want, cutoffs, got)
+		}
+	}
+}
@@ -85,7 +85,7 @@ func Kilonova() error {
 		}
 
 		ext := ""
-		lang, ok := eval.Langs[sub.Language]
+		lang, ok := eval.Langs()[sub.Language]
 		if !ok {
 			zap.S().Warn("Unknown language: ", sub.Language)
 			ext = ".cpp17"
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"slices"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -203,6 +204,59 @@ func (b *Bucket) RemoveFile(name string) error {
 	return nil
 }
 
+// Touch updates the modification time of the file, which is used by EvictOldest to determine the least recently used files
+func (b *Bucket) Touch(name string) error {
+	now := time.Now()
+	for _, suffix := range []string{".zst", ".gz", ""} {
+		err := os.Chtimes(b.filePath(name)+suffix, now, now)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return kilonova.ErrNotExist
+}
+
+// EvictOldest removes the files with the oldest modification time until the total size of the bucket is at most maxSize bytes,
+// and returns the remaining total size. Like ResetCache, it only works on buckets marked as cache
+func (b *Bucket) EvictOldest(maxSize int64) (int64, error) {
+	if !b.Cache {
+		return -1, errors.New("Bucket is not marked as cache, refusing to evict")
+	}
+	var files []fs.FileInfo
+	var total int64
+	if err := b.IterFiles(func(entry fs.DirEntry) error {
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, info)
+		total += info.Size()
+		return nil
+	}); err != nil {
+		return -1, err
+	}
+	if total <= maxSize {
+		return total, nil
+	}
+
+	slices.SortFunc(files, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+	var errs []error
+	for _, file := range files {
+		if total <= maxSize {
+			break
+		}
+		if err := os.Remove(b.filePath(file.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		total -= file.Size()
+	}
+	b.lastStatTime = time.Time{}
+	return total, errors.Join(errs...)
+}
+
 func (b *Bucket) ResetCache() error {
 	if !b.Cache {
 		return errors.New("Bucket is not marked as cache, refusing to delete")
@@ -10,19 +10,22 @@ import (
 type BucketType string
 
 const (
-	BucketTypeNone        BucketType = ""
-	BucketTypeTests       BucketType = "tests"
-	BucketTypeSubtests    BucketType = "subtests"
-	BucketTypeAttachments BucketType = "attachments"
-	BucketTypeAvatars     BucketType = "avatars"
-	BucketTypeCheckers    BucketType = "checkers"
-	BucketTypeCompiles    BucketType = "compiles"
+	BucketTypeNone         BucketType = ""
+	BucketTypeTests        BucketType = "tests"
+	BucketTypeSubtests     BucketType = "subtests"
+	BucketTypeAttachments  BucketType = "attachments"
+	BucketTypeAvatars      BucketType = "avatars"
+	BucketTypeCheckers     BucketType = "checkers"
+	BucketTypeCompiles     BucketType = "compiles"
+	BucketTypeOutputs      BucketType = "outputs"
+	BucketTypeCompileCache BucketType = "compile_cache"
 )
 
 func (t BucketType) Valid() bool {
 	return t == BucketTypeTests || t == BucketTypeSubtests ||
 		t == BucketTypeAttachments || t == BucketTypeAvatars ||
-		t == BucketTypeCheckers || t == BucketTypeCompiles
+		t == BucketTypeCheckers || t == BucketTypeCompiles ||
+		t == BucketTypeOutputs || t == BucketTypeCompileCache
 }
 
 type bucketDef struct {
@@ -72,6 +75,20 @@ var (
 			Name:    BucketTypeCompiles,
 			IsCache: false, // Well it kind of is but not really since it's cleaned up in the grader
 
+			CompressionLevel: NoCompression,
+		},
+		{
+			// Archives with outputs for output-only problems. They are already compressed
+			Name:    BucketTypeOutputs,
+			IsCache: false,
+
+			CompressionLevel: NoCompression,
+		},
+		{
+			// Compiled binaries, indexed by the hash of their sources and compile command
+			Name:    BucketTypeCompileCache,
+			IsCache: true,
+
 			CompressionLevel: NoCompression,
 		},
 	}
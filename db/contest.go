@@ -186,6 +186,12 @@ func (s *DB) DeleteContest(ctx context.Context, id int) error {
 
 // Contest leaderboard
 
+// Leaderboard views also include contest editors, who aren't necessarily registered
+const (
+	registrationColumns = "reg.category_id, COALESCE(reg.school, '') AS school, COALESCE(reg.region, '') AS region"
+	registrationJoin    = "LEFT JOIN contest_registrations reg ON reg.user_id = top.user_id AND reg.contest_id = top.contest_id"
+)
+
 type databaseClassicEntry struct {
 	UserID    int             `db:"user_id"`
 	ContestID int             `db:"contest_id"`
@@ -193,6 +199,10 @@ type databaseClassicEntry struct {
 	LastTime  *time.Time      `db:"last_time"`
 
 	FreezeTime *time.Time `db:"freeze_time"`
+
+	CategoryID *int   `db:"category_id"`
+	School     string `db:"school"`
+	Region     string `db:"region"`
 }
 
 func (s *DB) classicToLeaderboardEntry(ctx context.Context, entry *databaseClassicEntry) (*kilonova.LeaderboardEntry, error) {
@@ -231,6 +241,10 @@ func (s *DB) classicToLeaderboardEntry(ctx context.Context, entry *databaseClass
 
 		LastTime:   entry.LastTime,
 		FreezeTime: entry.FreezeTime,
+
+		CategoryID: entry.CategoryID,
+		School:     entry.School,
+		Region:     entry.Region,
 	}, nil
 }
 
@@ -258,7 +272,7 @@ func (s *DB) ContestClassicLeaderboard(ctx context.Context, contest *kilonova.Co
 	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual)
 	userFilterQuery(filter, fb)
 
-	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_top_view($1, $2, $3) WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY total_score DESC, last_time ASC NULLS LAST, user_id", fb.Args()...)
+	err = Select(s.conn, ctx, &topList, "SELECT top.*, $2 AS freeze_time, "+registrationColumns+" FROM contest_top_view($1, $2, $3) top "+registrationJoin+" WHERE EXISTS (SELECT 1 FROM users WHERE top.user_id = users.id AND "+fb.Where()+") ORDER BY total_score DESC, last_time ASC NULLS LAST, top.user_id", fb.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -279,6 +293,10 @@ type databaseICPCEntry struct {
 	NumAttempts int `db:"num_attempts"`
 
 	FreezeTime *time.Time `db:"freeze_time"`
+
+	CategoryID *int   `db:"category_id"`
+	School     string `db:"school"`
+	Region     string `db:"region"`
 }
 
 func (s *DB) icpcToLeaderboardEntry(ctx context.Context, entry *databaseICPCEntry) (*kilonova.LeaderboardEntry, error) {
@@ -327,6 +345,10 @@ func (s *DB) icpcToLeaderboardEntry(ctx context.Context, entry *databaseICPCEntr
 
 		LastTime:   entry.LastTime,
 		FreezeTime: entry.FreezeTime,
+
+		CategoryID: entry.CategoryID,
+		School:     entry.School,
+		Region:     entry.Region,
 	}, nil
 }
 
@@ -354,7 +376,7 @@ func (s *DB) ContestICPCLeaderboard(ctx context.Context, contest *kilonova.Conte
 	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual)
 	userFilterQuery(filter, fb)
 
-	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_icpc_view($1, $2, $3) WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY num_solved DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, user_id", fb.Args()...)
+	err = Select(s.conn, ctx, &topList, "SELECT top.*, $2 AS freeze_time, "+registrationColumns+" FROM contest_icpc_view($1, $2, $3) top "+registrationJoin+" WHERE EXISTS (SELECT 1 FROM users WHERE top.user_id = users.id AND "+fb.Where()+") ORDER BY num_solved DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, top.user_id", fb.Args()...)
 	if err != nil {
 		zap.S().Warn(err)
 		return nil, err
@@ -365,14 +387,81 @@ func (s *DB) ContestICPCLeaderboard(ctx context.Context, contest *kilonova.Conte
 	return leaderboard, nil
 }
 
-// MOSS setup
+// ContestVirtualEntry computes the leaderboard entry of a virtual participant.
+// The participant's times are moved onto the original contest's timeline, so the entry can be compared with the others
+func (s *DB) ContestVirtualEntry(ctx context.Context, contest *kilonova.Contest, reg *kilonova.ContestRegistration) (*kilonova.LeaderboardEntry, error) {
+	if reg.IndividualStartTime == nil {
+		return nil, kilonova.ErrMissingRequired
+	}
+	// The original contest, as if it started at the same time as the participant
+	virtualContest := *contest
+	virtualContest.StartTime = *reg.IndividualStartTime
+
+	var entry *kilonova.LeaderboardEntry
+	switch contest.LeaderboardStyle {
+	case kilonova.LeaderboardTypeICPC:
+		dbEntry := &databaseICPCEntry{UserID: reg.UserID, ContestID: contest.ID}
+		var err error
+		entry, err = s.icpcToLeaderboardEntry(context.WithValue(ctx, util.ContestKey, &virtualContest), dbEntry)
+		if err != nil {
+			return nil, err
+		}
+		entry.ComputeICPCTotals(virtualContest.StartTime, contest.ICPCSubmissionPenalty)
+	default:
+		var dbEntry databaseClassicEntry
+		if err := Get(s.conn, ctx, &dbEntry, "SELECT $1::bigint AS user_id, $2::bigint AS contest_id, COALESCE(SUM(score), 0) AS total_score, MAX(mintime) FILTER (WHERE score > 0) AS last_time FROM contest_max_scores($2, NULL) WHERE user_id = $1 AND score >= 0", reg.UserID, contest.ID); err != nil {
+			return nil, err
+		}
+		var err error
+		entry, err = s.classicToLeaderboardEntry(ctx, &dbEntry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if entry.LastTime != nil {
+		t := contest.StartTime.Add(entry.LastTime.Sub(virtualContest.StartTime))
+		entry.LastTime = &t
+	}
+	entry.CategoryID = reg.CategoryID
+	entry.School = reg.School
+	entry.Region = reg.Region
+	return entry, nil
+}
 
-func (s *DB) InsertMossSubmission(ctx context.Context, contestID int, problemID int, lang eval.Language, url string, subcount int) (int, error) {
+// Similarity reports
+
+// InsertSimilarityReport saves a similarity report and its pairs, returning the report ID.
+// contestID is nil for reports over all the submissions of a problem
+func (s *DB) InsertSimilarityReport(ctx context.Context, contestID *int, problemID int, lang eval.Language, subcount int, pairs []*kilonova.SimilarityPair) (int, error) {
 	var id int
-	err := s.conn.QueryRow(ctx, "INSERT INTO moss_submissions (contest_id, problem_id, language, url, subcount) VALUES ($1, $2, $3, $4, $5) RETURNING id", contestID, problemID, lang.InternalName, url, subcount).Scan(&id)
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, "INSERT INTO moss_submissions (contest_id, problem_id, language, subcount) VALUES ($1, $2, $3, $4) RETURNING id", contestID, problemID, lang.InternalName, subcount).Scan(&id); err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			if _, err := tx.Exec(ctx, `INSERT INTO similarity_pairs (report_id, left_sub_id, right_sub_id, left_user_id, right_user_id, left_similarity, right_similarity, matched_tokens, matches)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, id, pair.LeftSubmissionID, pair.RightSubmissionID, pair.LeftUserID, pair.RightUserID, pair.LeftSimilarity, pair.RightSimilarity, pair.MatchedTokens, pair.Matches); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	return id, err
 }
 
+func (s *DB) MossSubmission(ctx context.Context, id int) (*kilonova.MOSSSubmission, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM moss_submissions WHERE id = $1 LIMIT 1", id)
+	sub, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.MOSSSubmission])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
 func (s *DB) MossSubmissions(ctx context.Context, contestID int) ([]*kilonova.MOSSSubmission, error) {
 	rows, _ := s.conn.Query(ctx, "SELECT * FROM moss_submissions WHERE contest_id = $1 ORDER BY created_at DESC", contestID)
 	subs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.MOSSSubmission])
@@ -385,6 +474,43 @@ func (s *DB) MossSubmissions(ctx context.Context, contestID int) ([]*kilonova.MO
 	return subs, nil
 }
 
+// ProblemMossSubmissions returns the similarity reports over all the submissions of a problem, not those of its contests
+func (s *DB) ProblemMossSubmissions(ctx context.Context, problemID int) ([]*kilonova.MOSSSubmission, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM moss_submissions WHERE problem_id = $1 AND contest_id IS NULL ORDER BY created_at DESC", problemID)
+	subs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.MOSSSubmission])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (s *DB) SimilarityPairs(ctx context.Context, reportID int) ([]*kilonova.SimilarityPair, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM similarity_pairs WHERE report_id = $1 ORDER BY GREATEST(left_similarity, right_similarity) DESC, matched_tokens DESC, id", reportID)
+	pairs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.SimilarityPair])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pairs, nil
+}
+
+func (s *DB) SimilarityPair(ctx context.Context, reportID int, pairID int) (*kilonova.SimilarityPair, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM similarity_pairs WHERE report_id = $1 AND id = $2 LIMIT 1", reportID, pairID)
+	pair, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.SimilarityPair])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
 // Contest problems
 
 func (s *DB) UpdateContestProblems(ctx context.Context, contestID int, problems []int) error {
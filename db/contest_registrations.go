@@ -9,7 +9,7 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-func (s *DB) ContestRegistrations(ctx context.Context, contestID int, fuzzyName *string, inviteID *string, limit, offset int) ([]*kilonova.ContestRegistration, error) {
+func (s *DB) ContestRegistrations(ctx context.Context, contestID int, fuzzyName *string, inviteID *string, categoryID *int, limit, offset int) ([]*kilonova.ContestRegistration, error) {
 	fb := newFilterBuilder()
 	fb.AddConstraint("contest_id = %s", contestID)
 	if fuzzyName != nil {
@@ -18,6 +18,9 @@ func (s *DB) ContestRegistrations(ctx context.Context, contestID int, fuzzyName
 	if inviteID != nil {
 		fb.AddConstraint("invitation_id = %s", inviteID)
 	}
+	if categoryID != nil {
+		fb.AddConstraint("category_id = %s", categoryID)
+	}
 	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_registrations WHERE "+fb.Where()+" ORDER BY created_at ASC "+FormatLimitOffset(limit, offset), fb.Args()...)
 	regs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestRegistration])
 	if err != nil {
@@ -53,8 +56,31 @@ func (s *DB) ContestRegistration(ctx context.Context, contestID, userID int) (*k
 	return &reg, nil
 }
 
-func (s *DB) InsertContestRegistration(ctx context.Context, contestID, userID int, invitationID *string) error {
-	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, invitation_id) VALUES ($1, $2, $3)", userID, contestID, invitationID)
+func (s *DB) InsertContestRegistration(ctx context.Context, contestID, userID int, invitationID *string, categoryID *int) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, invitation_id, category_id) VALUES ($1, $2, $3, $4)", userID, contestID, invitationID, categoryID)
+	return err
+}
+
+func (s *DB) UpdateContestRegistration(ctx context.Context, contestID, userID int, upd kilonova.ContestRegistrationUpdate) error {
+	ub := newUpdateBuilder()
+	if v := upd.CategoryID; v != nil {
+		ub.AddUpdate("category_id = %s", v)
+	} else if upd.RemoveCategory {
+		ub.AddUpdate("category_id = NULL")
+	}
+	if v := upd.School; v != nil {
+		ub.AddUpdate("school = %s", v)
+	}
+	if v := upd.Region; v != nil {
+		ub.AddUpdate("region = %s", v)
+	}
+	if ub.CheckUpdates() != nil {
+		return kilonova.ErrNoUpdates
+	}
+	fb := ub.MakeFilter()
+	fb.AddConstraint("contest_id = %s", contestID)
+	fb.AddConstraint("user_id = %s", userID)
+	_, err := s.conn.Exec(ctx, "UPDATE contest_registrations SET "+fb.WithUpdate(), fb.Args()...)
 	return err
 }
 
@@ -63,6 +89,12 @@ func (s *DB) StartContestRegistration(ctx context.Context, contestID, userID int
 	return err
 }
 
+// InsertVirtualContestRegistration registers the user for a virtual participation, already started with the given window
+func (s *DB) InsertVirtualContestRegistration(ctx context.Context, contestID, userID int, startTime time.Time, endTime time.Time) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, virtual, individual_start_at, individual_end_at) VALUES ($1, $2, true, $3, $4)", userID, contestID, startTime, endTime)
+	return err
+}
+
 func (s *DB) DeleteContestRegistration(ctx context.Context, contestID, userID int) error {
 	_, err := s.conn.Exec(ctx, "DELETE FROM contest_registrations WHERE user_id = $1 AND contest_id = $2", userID, contestID)
 	return err
@@ -97,8 +129,48 @@ func (s *DB) UpdateContestInvitation(ctx context.Context, id string, expired boo
 	return err
 }
 
-func (s *DB) CreateContestInvitation(ctx context.Context, contestID int, creatorID *int, maxUses *int) (string, error) {
+func (s *DB) CreateContestInvitation(ctx context.Context, contestID int, creatorID *int, maxUses *int, categoryID *int) (string, error) {
 	id := kilonova.RandomString(12)
-	_, err := s.conn.Exec(ctx, "INSERT INTO contest_invitations (id, contest_id, creator_id, max_invitation_cnt) VALUES ($1, $2, $3, $4)", id, contestID, creatorID, maxUses)
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_invitations (id, contest_id, creator_id, max_invitation_cnt, category_id) VALUES ($1, $2, $3, $4, $5)", id, contestID, creatorID, maxUses, categoryID)
+	return id, err
+}
+
+func (s *DB) ContestCategories(ctx context.Context, contestID int) ([]*kilonova.ContestCategory, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_categories WHERE contest_id = $1 ORDER BY id", contestID)
+	categories, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestCategory])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []*kilonova.ContestCategory{}, nil
+		}
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (s *DB) ContestCategory(ctx context.Context, id int) (*kilonova.ContestCategory, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_categories WHERE id = $1 LIMIT 1", id)
+	category, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.ContestCategory])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *DB) CreateContestCategory(ctx context.Context, contestID int, name string) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, "INSERT INTO contest_categories (contest_id, name) VALUES ($1, $2) RETURNING id", contestID, name).Scan(&id)
 	return id, err
 }
+
+func (s *DB) UpdateContestCategory(ctx context.Context, id int, name string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_categories SET name = $2 WHERE id = $1", id, name)
+	return err
+}
+
+func (s *DB) DeleteContestCategory(ctx context.Context, id int) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM contest_categories WHERE id = $1", id)
+	return err
+}
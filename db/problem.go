@@ -37,6 +37,14 @@ type dbProblem struct {
 	// Eval stuff
 	ConsoleInput   bool  `db:"console_input"`
 	DigitPrecision int32 `db:"digit_precision"`
+	NumProcesses   int   `db:"num_processes"`
+
+	BuiltinChecker    kilonova.BuiltinCheckerType `db:"builtin_checker"`
+	CheckerAbsEpsilon float64                     `db:"checker_abs_epsilon"`
+	CheckerRelEpsilon float64                     `db:"checker_rel_epsilon"`
+	StopOnFailure     bool                        `db:"stop_on_failure"`
+
+	LanguageLimits map[string]*kilonova.LanguageLimits `db:"language_limits"`
 
 	ScoringStrategy kilonova.ScoringType `db:"scoring_strategy"`
 }
@@ -311,6 +319,24 @@ func problemUpdateQuery(upd *kilonova.ProblemUpdate, ub *updateBuilder) {
 	if v := upd.ScorePrecision; v != nil {
 		ub.AddUpdate("digit_precision = %s", v)
 	}
+	if v := upd.NumProcesses; v != nil {
+		ub.AddUpdate("num_processes = %s", v)
+	}
+	if v := upd.BuiltinChecker; v != nil {
+		ub.AddUpdate("builtin_checker = %s", v)
+	}
+	if v := upd.CheckerAbsEpsilon; v != nil {
+		ub.AddUpdate("checker_abs_epsilon = %s", v)
+	}
+	if v := upd.CheckerRelEpsilon; v != nil {
+		ub.AddUpdate("checker_rel_epsilon = %s", v)
+	}
+	if v := upd.StopOnFailure; v != nil {
+		ub.AddUpdate("stop_on_failure = %s", v)
+	}
+	if v := upd.LanguageLimits; v != nil {
+		ub.AddUpdate("language_limits = %s", v)
+	}
 }
 
 // Access rights
@@ -361,6 +387,14 @@ func (s *DB) internalToProblem(pb *dbProblem) *kilonova.Problem {
 
 		ConsoleInput:   pb.ConsoleInput,
 		ScorePrecision: pb.DigitPrecision,
+		NumProcesses:   pb.NumProcesses,
+
+		BuiltinChecker:    pb.BuiltinChecker,
+		CheckerAbsEpsilon: pb.CheckerAbsEpsilon,
+		CheckerRelEpsilon: pb.CheckerRelEpsilon,
+		StopOnFailure:     pb.StopOnFailure,
+
+		LanguageLimits: pb.LanguageLimits,
 
 		PublishedAt:     pb.PublishedAt,
 		ScoringStrategy: pb.ScoringStrategy,
@@ -0,0 +1 @@
+ALTER TABLE problems ADD COLUMN num_processes integer NOT NULL DEFAULT 1;
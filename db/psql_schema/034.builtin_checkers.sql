@@ -0,0 +1,3 @@
+ALTER TABLE problems ADD COLUMN builtin_checker text NOT NULL DEFAULT '';
+ALTER TABLE problems ADD COLUMN checker_abs_epsilon double precision NOT NULL DEFAULT 1e-6;
+ALTER TABLE problems ADD COLUMN checker_rel_epsilon double precision NOT NULL DEFAULT 1e-6;
@@ -0,0 +1 @@
+ALTER TABLE problems ADD COLUMN stop_on_failure boolean NOT NULL DEFAULT false;
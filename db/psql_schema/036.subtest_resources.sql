@@ -0,0 +1,7 @@
+ALTER TABLE submission_tests ADD COLUMN wall_time double precision NOT NULL DEFAULT 0;
+ALTER TABLE submission_tests ADD COLUMN exit_code integer NOT NULL DEFAULT 0;
+ALTER TABLE submission_tests ADD COLUMN exit_signal integer NOT NULL DEFAULT 0;
+ALTER TABLE submission_tests ADD COLUMN max_rss integer NOT NULL DEFAULT 0;
+ALTER TABLE submission_tests ADD COLUMN oom_killed boolean NOT NULL DEFAULT false;
+ALTER TABLE submission_tests ADD COLUMN csw_voluntary integer NOT NULL DEFAULT 0;
+ALTER TABLE submission_tests ADD COLUMN csw_forced integer NOT NULL DEFAULT 0;
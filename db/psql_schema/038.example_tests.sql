@@ -0,0 +1 @@
+ALTER TABLE tests ADD COLUMN example boolean NOT NULL DEFAULT false;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS solution_expectations (
+    submission_id   bigint      PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    verdict         text        NOT NULL DEFAULT '',
+    subtask         integer,
+    min_score       numeric,
+    max_score       numeric
+);
@@ -0,0 +1,13 @@
+CREATE TYPE subtask_scoring AS ENUM (
+    'min',
+    'sum',
+    'product',
+    'all_or_nothing',
+    'max',
+    'dependent'
+);
+
+ALTER TABLE subtasks ADD COLUMN scoring subtask_scoring NOT NULL DEFAULT 'min';
+
+-- Like the score, the rule is saved with the submission, so that changing it doesn't affect old submissions until they are reevaluated
+ALTER TABLE submission_subtasks ADD COLUMN scoring subtask_scoring NOT NULL DEFAULT 'min';
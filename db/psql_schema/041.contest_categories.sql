@@ -0,0 +1,16 @@
+-- Contest-defined participant categories, such as "official", "official at home" or "unofficial"
+CREATE TABLE IF NOT EXISTS contest_categories (
+    id          bigserial   PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    name        text        NOT NULL,
+
+    UNIQUE (contest_id, name)
+);
+
+-- Users registering through an invitation get its category
+ALTER TABLE contest_invitations ADD COLUMN category_id bigint REFERENCES contest_categories(id) ON DELETE SET NULL;
+
+ALTER TABLE contest_registrations ADD COLUMN category_id bigint REFERENCES contest_categories(id) ON DELETE SET NULL;
+ALTER TABLE contest_registrations ADD COLUMN school text NOT NULL DEFAULT '';
+ALTER TABLE contest_registrations ADD COLUMN region text NOT NULL DEFAULT '';
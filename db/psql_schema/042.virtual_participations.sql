@@ -0,0 +1,3 @@
+-- Virtual participations are personal runs of an ended contest, timed by individual_start_at/individual_end_at.
+-- They are kept out of the official leaderboard and shown only as ghost entries to the participant
+ALTER TABLE contest_registrations ADD COLUMN virtual boolean NOT NULL DEFAULT false;
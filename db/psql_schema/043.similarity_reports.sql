@@ -0,0 +1,21 @@
+-- Similarity reports are now computed natively, so they are also available for problems outside contests.
+-- The url column is kept only for the old MOSS results
+ALTER TABLE moss_submissions ALTER COLUMN contest_id DROP NOT NULL;
+ALTER TABLE moss_submissions ALTER COLUMN url SET DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS similarity_pairs (
+    id                  bigserial           PRIMARY KEY,
+    report_id           bigint              NOT NULL REFERENCES moss_submissions(id) ON DELETE CASCADE,
+
+    left_sub_id         bigint              NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    right_sub_id        bigint              NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    left_user_id        bigint              NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    right_user_id       bigint              NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    left_similarity     double precision    NOT NULL,
+    right_similarity    double precision    NOT NULL,
+    matched_tokens      integer             NOT NULL,
+    matches             jsonb               NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS similarity_pairs_report ON similarity_pairs (report_id);
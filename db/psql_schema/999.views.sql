@@ -199,13 +199,13 @@ DROP VIEW IF EXISTS max_score_contest_view CASCADE;
 DROP VIEW IF EXISTS contest_top_view CASCADE;
 DROP FUNCTION IF EXISTS contest_top_view;
 -- Since we now return -1 on no attempt, we must filter it when computing the top view
--- also, exclude contest editors/testers since they didn't get that score legit
+-- also, exclude contest editors/testers since they didn't get that score legit, and virtual participants, who are shown separately
 CREATE OR REPLACE FUNCTION contest_top_view(contest_id bigint, freeze_time timestamptz, include_editors boolean) RETURNS TABLE (user_id bigint, contest_id bigint, total_score decimal, last_time timestamptz) AS $$
     -- both contest_scores and legit_contestants will contain results only for that contest id, so it's safe to simply join them 
     WITH contest_scores AS (
         SELECT user_id, SUM(score) AS total_score, MAX(mintime) FILTER (WHERE score > 0) AS last_time FROM contest_max_scores($1, $2) WHERE score >= 0 GROUP BY user_id
     ), legit_contestants AS (
-        SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
+        SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND regs.virtual = false AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
     )
     SELECT users.user_id, $1 AS contest_id, COALESCE(scores.total_score, 0) AS total_score, last_time
     FROM 
@@ -215,11 +215,11 @@ CREATE OR REPLACE FUNCTION contest_top_view(contest_id bigint, freeze_time times
 $$ LANGUAGE SQL STABLE;
 
 DROP FUNCTION IF EXISTS contest_icpc_view;
--- we exclude contest editors/testers since they didn't get that score legit
+-- we exclude contest editors/testers since they didn't get that score legit, and virtual participants, who are shown separately
 CREATE OR REPLACE FUNCTION contest_icpc_view(contest_id bigint, freeze_time timestamptz, include_editors boolean) 
 RETURNS TABLE (user_id bigint, contest_id bigint, last_time timestamptz, num_solved integer, penalty integer, num_attempts integer) AS $$
     WITH legit_contestants AS (
-        SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
+        SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND regs.virtual = false AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
     ), solved_pbs AS (
         SELECT user_id, problem_id, mintime AS last_time FROM contest_max_scores($1, $2) WHERE score = 100
     ), last_times AS (
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+type dbSolutionExpectation struct {
+	SubmissionID int       `db:"submission_id"`
+	CreatedAt    time.Time `db:"created_at"`
+
+	Verdict  kilonova.ExpectedVerdict `db:"verdict"`
+	Subtask  *int                     `db:"subtask"`
+	MinScore *decimal.Decimal         `db:"min_score"`
+	MaxScore *decimal.Decimal         `db:"max_score"`
+}
+
+// SetSolutionExpectation creates or replaces the expected result of a submission
+func (s *DB) SetSolutionExpectation(ctx context.Context, exp *kilonova.SolutionExpectation) error {
+	_, err := s.conn.Exec(ctx, `INSERT INTO solution_expectations (submission_id, verdict, subtask, min_score, max_score) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (submission_id) DO UPDATE SET verdict = EXCLUDED.verdict, subtask = EXCLUDED.subtask, min_score = EXCLUDED.min_score, max_score = EXCLUDED.max_score`,
+		exp.SubmissionID, exp.Verdict, exp.Subtask, exp.MinScore, exp.MaxScore)
+	return err
+}
+
+func (s *DB) DeleteSolutionExpectation(ctx context.Context, submissionID int) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM solution_expectations WHERE submission_id = $1", submissionID)
+	return err
+}
+
+// ProblemSolutionExpectations returns the expectations of the model solutions of a problem, ordered by submission
+func (s *DB) ProblemSolutionExpectations(ctx context.Context, problemID int) ([]*kilonova.SolutionExpectation, error) {
+	var exps []*dbSolutionExpectation
+	err := Select(s.conn, ctx, &exps, `SELECT exps.* FROM solution_expectations exps 
+		WHERE EXISTS (SELECT 1 FROM submissions subs WHERE subs.id = exps.submission_id AND subs.problem_id = $1) 
+		ORDER BY exps.submission_id`, problemID)
+	if err != nil {
+		return []*kilonova.SolutionExpectation{}, err
+	}
+	return mapper(exps, internalToSolutionExpectation), nil
+}
+
+func internalToSolutionExpectation(exp *dbSolutionExpectation) *kilonova.SolutionExpectation {
+	return &kilonova.SolutionExpectation{
+		SubmissionID: exp.SubmissionID,
+		Verdict:      exp.Verdict,
+		Subtask:      exp.Subtask,
+		MinScore:     exp.MinScore,
+		MaxScore:     exp.MaxScore,
+	}
+}
@@ -80,9 +80,9 @@ func initSubs(ctx context.Context, tx pgx.Tx, filter kilonova.SubmissionFilter)
 	// Init subtasks
 	if _, err := tx.Exec(ctx, fmt.Sprintf(`
 	INSERT INTO submission_subtasks 
-	(user_id, created_at, submission_id, contest_id, subtask_id, problem_id, visible_id, digit_precision, score, leaderboard_score_scale) 
+	(user_id, created_at, submission_id, contest_id, subtask_id, problem_id, visible_id, digit_precision, score, scoring, leaderboard_score_scale) 
 		WITH subs_to_add AS (SELECT * FROM submissions WHERE %s)
-	SELECT subs.user_id, subs.created_at AS created_at, subs.id AS submission_id, subs.contest_id, stks.id AS subtask_id, stks.problem_id AS problem_id, stks.visible_id, subs.digit_precision AS digit_precision, stks.score AS score, subs.leaderboard_score_scale AS leaderboard_score_scale
+	SELECT subs.user_id, subs.created_at AS created_at, subs.id AS submission_id, subs.contest_id, stks.id AS subtask_id, stks.problem_id AS problem_id, stks.visible_id, subs.digit_precision AS digit_precision, stks.score AS score, stks.scoring AS scoring, subs.leaderboard_score_scale AS leaderboard_score_scale
 	FROM subs_to_add subs, subtasks stks 
 	WHERE subs.problem_id = stks.problem_id`, fb.Where()), fb.Args()...); err != nil {
 		return err
@@ -27,6 +27,8 @@ type subSubtask struct {
 
 	ScoreScale *decimal.Decimal `db:"leaderboard_score_scale"`
 
+	Scoring kilonova.SubtaskScoring `db:"scoring"`
+
 	ComputedScore decimal.Decimal `db:"computed_score"`
 }
 
@@ -115,5 +117,6 @@ func (s *DB) internalToSubmissionSubTask(ctx context.Context, st *subSubtask) (*
 
 		FinalPercentage: st.FinalPercentage,
 		ScorePrecision:  st.ScorePrecision,
+		Scoring:         st.Scoring,
 	}, nil
 }
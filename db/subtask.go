@@ -17,7 +17,7 @@ func (s *DB) CreateSubTask(ctx context.Context, subtask *kilonova.SubTask) error
 	}
 	var id int
 	// Do insertion
-	err := s.conn.QueryRow(ctx, "INSERT INTO subtasks (problem_id, visible_id, score) VALUES ($1, $2, $3) RETURNING id", subtask.ProblemID, subtask.VisibleID, subtask.Score).Scan(&id)
+	err := s.conn.QueryRow(ctx, "INSERT INTO subtasks (problem_id, visible_id, score, scoring) VALUES ($1, $2, $3, $4) RETURNING id", subtask.ProblemID, subtask.VisibleID, subtask.Score, subtask.Scoring).Scan(&id)
 	if err != nil {
 		return err
 	}
@@ -98,6 +98,9 @@ func (s *DB) UpdateSubTask(ctx context.Context, id int, upd kilonova.SubTaskUpda
 	if v := upd.Score; v != nil {
 		ub.AddUpdate("score = %s", v)
 	}
+	if v := upd.Scoring; v != nil {
+		ub.AddUpdate("scoring = %s", v)
+	}
 
 	if ub.CheckUpdates() != nil {
 		return kilonova.ErrNoUpdates
@@ -147,7 +150,8 @@ type subtask struct {
 	ProblemID int       `db:"problem_id"`
 	VisibleID int       `db:"visible_id"`
 
-	Score decimal.Decimal
+	Score   decimal.Decimal
+	Scoring kilonova.SubtaskScoring
 }
 
 func (s *DB) internalToSubTask(ctx context.Context, st *subtask) (*kilonova.SubTask, error) {
@@ -181,5 +185,6 @@ ORDER BY tests.visible_id ASC
 		VisibleID: st.VisibleID,
 		Score:     st.Score,
 		Tests:     ids,
+		Scoring:   st.Scoring,
 	}, nil
 }
@@ -19,6 +19,7 @@ func (s *DB) SubTestsBySubID(ctx context.Context, subid int) ([]*kilonova.SubTes
 	} else if len(subtests) == 0 {
 		return []*kilonova.SubTest{}, nil
 	}
+	fillSignalNames(subtests...)
 	return subtests, err
 }
 
@@ -44,6 +45,7 @@ WITH subtask_ids AS (
 	} else if len(subtests) == 0 {
 		return []*kilonova.SubTest{}, nil
 	}
+	fillSignalNames(subtests...)
 	return subtests, err
 }
 
@@ -53,6 +55,7 @@ func (s *DB) SubTest(ctx context.Context, id int) (*kilonova.SubTest, error) {
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
+	fillSignalNames(&subtest)
 	return &subtest, err
 }
 
@@ -87,4 +90,31 @@ func subtestUpdateQuery(upd *kilonova.SubTestUpdate, ub *updateBuilder) {
 	if v := upd.Skipped; v != nil {
 		ub.AddUpdate("skipped = %s", v)
 	}
+	if v := upd.WallTime; v != nil {
+		ub.AddUpdate("wall_time = %s", v)
+	}
+	if v := upd.ExitCode; v != nil {
+		ub.AddUpdate("exit_code = %s", v)
+	}
+	if v := upd.ExitSignal; v != nil {
+		ub.AddUpdate("exit_signal = %s", v)
+	}
+	if v := upd.MaxRSS; v != nil {
+		ub.AddUpdate("max_rss = %s", v)
+	}
+	if v := upd.OOMKilled; v != nil {
+		ub.AddUpdate("oom_killed = %s", v)
+	}
+	if v := upd.VoluntaryCSW; v != nil {
+		ub.AddUpdate("csw_voluntary = %s", v)
+	}
+	if v := upd.ForcedCSW; v != nil {
+		ub.AddUpdate("csw_forced = %s", v)
+	}
+}
+
+func fillSignalNames(subtests ...*kilonova.SubTest) {
+	for _, st := range subtests {
+		st.SignalName = kilonova.SignalName(st.ExitSignal)
+	}
 }
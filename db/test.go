@@ -14,7 +14,7 @@ func (s *DB) CreateTest(ctx context.Context, test *kilonova.Test) error {
 	}
 
 	var id int
-	err := s.conn.QueryRow(ctx, "INSERT INTO tests (score, problem_id, visible_id) VALUES ($1, $2, $3) RETURNING id", test.Score, test.ProblemID, test.VisibleID).Scan(&id)
+	err := s.conn.QueryRow(ctx, "INSERT INTO tests (score, problem_id, visible_id, example) VALUES ($1, $2, $3, $4) RETURNING id", test.Score, test.ProblemID, test.VisibleID, test.Example).Scan(&id)
 	if err == nil {
 		test.ID = id
 	}
@@ -47,6 +47,9 @@ func (s *DB) UpdateTest(ctx context.Context, id int, upd kilonova.TestUpdate) er
 	if v := upd.VisibleID; v != nil {
 		ub.AddUpdate("visible_id = %s", v)
 	}
+	if v := upd.Example; v != nil {
+		ub.AddUpdate("example = %s", v)
+	}
 	if ub.CheckUpdates() != nil {
 		return ub.CheckUpdates()
 	}
@@ -0,0 +1,37 @@
+package box_test
+
+import (
+	"testing"
+
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/box"
+	"github.com/KiloProjects/kilonova/eval/box/sandboxtest"
+	"go.uber.org/zap"
+)
+
+// Box IDs are offset for each sandbox, so that the tests don't interfere with boxes of a running grader
+func newBoxFunc(boxFunc func(int, int64, *zap.SugaredLogger) (eval.Sandbox, error), offset int) sandboxtest.NewBoxFunc {
+	return func(t *testing.T, id int) eval.Sandbox {
+		t.Helper()
+		b, err := boxFunc(offset+id, 0, zap.NewNop().Sugar())
+		if err != nil {
+			t.Skipf("Sandbox not available: %v", err)
+		}
+		return b
+	}
+}
+
+func TestStupidSandbox(t *testing.T) {
+	sandboxtest.Run(t, newBoxFunc(box.NewStupid, 900), sandboxtest.Options{})
+}
+
+func TestIsolateBox(t *testing.T) {
+	sandboxtest.Run(t, newBoxFunc(box.New, 920), sandboxtest.Options{Isolated: true})
+}
+
+func TestNamespaceBox(t *testing.T) {
+	if err := box.SetupNamespaceSandbox(); err != nil {
+		t.Skipf("Sandbox not available: %v", err)
+	}
+	sandboxtest.Run(t, newBoxFunc(box.NewNamespace, 940), sandboxtest.Options{Isolated: true})
+}
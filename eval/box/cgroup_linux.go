@@ -0,0 +1,293 @@
+package box
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cgroupMount = "/sys/fs/cgroup"
+	// boxMaxProcesses is the pids.max of the programs in a box. It's high enough for runtimes with many threads, such as the JVM
+	boxMaxProcesses = 1024
+)
+
+var (
+	cgroupRootMu   sync.Mutex
+	cgroupRootPath string
+)
+
+// SetupNamespaceSandbox prepares the cgroup v2 directory under which the native boxes create their cgroups.
+// It must be called once the native sandbox is selected, before creating any box, since it may move the process to another cgroup:
+// the directory is the cgroup_root option of the eval config or, if unset, the cgroup of the current process, which must be delegated to us.
+// In the latter case, the process moves itself into a leaf cgroup, since cgroups with processes can't enable controllers for children
+func SetupNamespaceSandbox() error {
+	cgroupRootMu.Lock()
+	defer cgroupRootMu.Unlock()
+	if cgroupRootPath != "" {
+		return nil
+	}
+	if err := NamespaceSandboxAvailable(); err != nil {
+		return err
+	}
+	root, err := initCgroupRoot()
+	if err != nil {
+		return fmt.Errorf("couldn't set up the native sandbox: %w", err)
+	}
+	cgroupRootPath = root
+	return nil
+}
+
+// cgroupRoot returns the directory prepared by SetupNamespaceSandbox
+func cgroupRoot() (string, error) {
+	cgroupRootMu.Lock()
+	defer cgroupRootMu.Unlock()
+	if cgroupRootPath == "" {
+		return "", errors.New("the native sandbox was not set up")
+	}
+	return cgroupRootPath, nil
+}
+
+// NamespaceSandboxAvailable checks, without changing anything, that the native sandbox could be set up:
+// cgroup v2 must be mounted, the cgroup directory must be writable and have the needed controllers, and boxes must have a user of their own
+func NamespaceSandboxAvailable() error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(cgroupMount, &st); err != nil {
+		return fmt.Errorf("couldn't stat cgroup mount: %w", err)
+	}
+	if st.Type != unix.CGROUP2_SUPER_MAGIC {
+		return errors.New("cgroup v2 is not mounted on " + cgroupMount)
+	}
+	root, err := configuredCgroupRoot()
+	if err != nil {
+		return err
+	}
+	if err := unix.Access(root, unix.W_OK); err != nil {
+		return fmt.Errorf("cgroup %s is not writable (is it delegated?): %w", root, err)
+	}
+	if err := checkControllers(root); err != nil {
+		return err
+	}
+	if os.Getuid() != 0 {
+		if _, _, err := subordinateIDs(0); err != nil {
+			return fmt.Errorf("boxes need a subordinate UID and GID when not running as root: %w", err)
+		}
+	}
+	return nil
+}
+
+// configuredCgroupRoot returns the cgroup_root option of the eval config or, if unset, the cgroup of the current process
+func configuredCgroupRoot() (string, error) {
+	root := config.Eval.CgroupRoot
+	if root == "" {
+		own, err := ownCgroup()
+		if err != nil {
+			return "", err
+		}
+		return path.Join(cgroupMount, own), nil
+	}
+	if !path.IsAbs(root) {
+		root = path.Join(cgroupMount, root)
+	}
+	return root, nil
+}
+
+func checkControllers(root string) error {
+	controllers, err := os.ReadFile(path.Join(root, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("couldn't read cgroup controllers: %w", err)
+	}
+	for _, controller := range []string{"memory", "pids"} {
+		if !slices.Contains(strings.Fields(string(controllers)), controller) {
+			return fmt.Errorf("cgroup controller %q is not available in %s", controller, root)
+		}
+	}
+	return nil
+}
+
+func initCgroupRoot() (string, error) {
+	root, err := configuredCgroupRoot()
+	if err != nil {
+		return "", err
+	}
+	if config.Eval.CgroupRoot == "" {
+		leaf := path.Join(root, "kn-main")
+		if err := os.Mkdir(leaf, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("couldn't create leaf cgroup (is the cgroup delegated?): %w", err)
+		}
+		if err := os.WriteFile(path.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+			return "", fmt.Errorf("couldn't move process into leaf cgroup: %w", err)
+		}
+		zap.S().Infof("Moved the process into %s, to give the native sandbox the control of %s", leaf, root)
+	}
+
+	if err := os.WriteFile(path.Join(root, "cgroup.subtree_control"), []byte("+memory +pids"), 0); err != nil {
+		return "", fmt.Errorf("couldn't enable cgroup controllers: %w", err)
+	}
+	return root, nil
+}
+
+// ownCgroup returns the cgroup v2 path of the current process, relative to the cgroup mount
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		if p, ok := strings.CutPrefix(s.Text(), "0::"); ok {
+			return p, nil
+		}
+	}
+	return "", errors.New("process is not in a cgroup v2 hierarchy")
+}
+
+// cgroup is a directory in the cgroup v2 hierarchy
+type cgroup string
+
+func newCgroup(parent string, name string, enableControllers bool) (cgroup, error) {
+	cg := cgroup(path.Join(parent, name))
+	// Leftover from a crashed run
+	if err := cg.remove(); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(string(cg), 0755); err != nil {
+		return "", err
+	}
+	if enableControllers {
+		if err := cg.write("cgroup.subtree_control", "+memory +pids"); err != nil {
+			cg.remove()
+			return "", err
+		}
+	}
+	return cg, nil
+}
+
+func (cg cgroup) path(file string) string {
+	return path.Join(string(cg), file)
+}
+
+// write writes to an existing file of the cgroup. Creating files in cgroupfs fails with EACCES, which would hide missing files
+func (cg cgroup) write(file string, val string) error {
+	f, err := os.OpenFile(cg.path(file), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(val)
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return err
+}
+
+func (cg cgroup) readInt(file string) (int64, error) {
+	data, err := os.ReadFile(cg.path(file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// stat returns a value from a flat keyed file, such as cpu.stat or memory.events
+func (cg cgroup) stat(file string, key string) (int64, error) {
+	data, err := os.ReadFile(cg.path(file))
+	if err != nil {
+		return 0, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		k, v, _ := strings.Cut(s.Text(), " ")
+		if k == key {
+			return strconv.ParseInt(v, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("key %q not found in %s", key, file)
+}
+
+// setLimits sets the memory limit (in kilobytes, 0 means unlimited) and the process limit of the cgroup
+func (cg cgroup) setLimits(memoryLimit int) error {
+	if memoryLimit > 0 {
+		if err := cg.write("memory.max", strconv.Itoa(memoryLimit*1024)); err != nil {
+			return err
+		}
+		// Swap would let programs go over the limit, but memory.swap.max doesn't exist if swap accounting is disabled
+		if err := cg.write("memory.swap.max", "0"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return cg.write("pids.max", strconv.Itoa(boxMaxProcesses))
+}
+
+// cpuTime returns the CPU time used by the processes in the cgroup, in seconds
+func (cg cgroup) cpuTime() float64 {
+	usec, err := cg.stat("cpu.stat", "usage_usec")
+	if err != nil {
+		return 0
+	}
+	return float64(usec) / 1e6
+}
+
+// memory returns the current memory usage of the cgroup, in kilobytes
+func (cg cgroup) memory() int {
+	val, err := cg.readInt("memory.current")
+	if err != nil {
+		return 0
+	}
+	return int(val / 1024)
+}
+
+// peakMemory returns the peak memory usage of the cgroup, in kilobytes. memory.peak is only available since Linux 5.19
+func (cg cgroup) peakMemory() (int, bool) {
+	val, err := cg.readInt("memory.peak")
+	if err != nil {
+		return 0, false
+	}
+	return int(val / 1024), true
+}
+
+func (cg cgroup) oomKilled() bool {
+	val, err := cg.stat("memory.events", "oom_kill")
+	return err == nil && val > 0
+}
+
+// remove removes the cgroup and its children. The cgroup can be removed only after all of its processes exited,
+// which might take a short while after they are killed
+func (cg cgroup) remove() error {
+	entries, err := os.ReadDir(string(cg))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := cgroup(cg.path(entry.Name())).remove(); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; ; i++ {
+		err := os.Remove(string(cg))
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if !errors.Is(err, unix.EBUSY) || i >= 100 {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -306,6 +306,17 @@ func New(id int, memQuota int64, logger *zap.SugaredLogger) (eval.Sandbox, error
 	return &IsolateBox{path: strings.TrimSpace(string(ret)), boxID: id, memoryQuota: memQuota, logger: logger}, nil
 }
 
+// IsolateAvailable checks if the isolate binary is installed at the configured path
+func IsolateAvailable() error {
+	if _, err := os.Stat(config.Eval.IsolatePath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("isolate not found at %s (run scripts/init_isolate.sh to install it)", config.Eval.IsolatePath)
+		}
+		return fmt.Errorf("couldn't check isolate binary: %w", err)
+	}
+	return nil
+}
+
 func IsolateVersion() string {
 	ret, err := exec.Command(config.Eval.IsolatePath, "--version").CombinedOutput()
 	if err != nil {
@@ -347,9 +358,16 @@ func parseMetaFile(r io.Reader, out bytes.Buffer) *eval.RunStats {
 		case "time":
 			file.Time, _ = strconv.ParseFloat(val, 64)
 		case "time-wall":
-			// file.WallTime, _ = strconv.ParseFloat(val, 32)
-			continue
-		case "max-rss", "csw-voluntary", "csw-forced", "cg-enabled", "cg-oom-killed":
+			file.WallTime, _ = strconv.ParseFloat(val, 64)
+		case "max-rss":
+			file.MaxRSS, _ = strconv.Atoi(val)
+		case "csw-voluntary":
+			file.VoluntaryCSW, _ = strconv.Atoi(val)
+		case "csw-forced":
+			file.ForcedCSW, _ = strconv.Atoi(val)
+		case "cg-oom-killed":
+			file.OOMKilled = true
+		case "cg-enabled":
 			continue
 		default:
 			zap.S().Infof("Unknown isolate stat: %q (value: %v)", key, val)
@@ -0,0 +1,480 @@
+package box
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// The native box re-executes the current binary, which detects these markers in its arguments before doing anything else
+const (
+	// nsInitArg starts the init process of the box, which builds its filesystem and runs the program
+	nsInitArg = "kn-nsbox-init"
+	// nsExecArg starts the process which drops all privileges and executes the program
+	nsExecArg = "kn-nsbox-exec"
+)
+
+// File descriptors received by the init process, see NamespaceBox.run
+const (
+	nsRequestFd = 3
+	nsResultFd  = 4
+	nsRunCgroup = 5
+)
+
+// File descriptors received by the exec stage, see nsInitRun
+const (
+	nsExecCgroup = 3
+	nsExecErrors = 4
+)
+
+// nsMount is a resolved directory rule of the box
+type nsMount struct {
+	Inside string
+	// Outside is the host path to bind mount. It's empty for /proc, /dev and temporary directories
+	Outside string
+
+	ReadWrite bool
+	Maybe     bool
+	Tmp       bool
+	NoExec    bool
+	Dev       bool
+}
+
+// nsRequest is sent by the host to the init process of the box. If Command is empty, the box is only built
+type nsRequest struct {
+	// Root is the (empty) host directory where the root of the box is built
+	Root   string
+	Mounts []nsMount
+
+	// SwitchUser is set when the IDs of the namespace were mapped after the process started (without root, see mapSubordinateIDs),
+	// so the init process must switch to the mapped root by itself
+	SwitchUser bool
+
+	Command []string
+	Env     []string
+
+	InputPath      string
+	OutputPath     string
+	StderrPath     string
+	StderrToStdout bool
+}
+
+// nsResult is sent by the init process of the box after the program finishes
+type nsResult struct {
+	// Error is set if the box couldn't be built
+	Error string
+
+	ExitCode   int
+	ExitSignal int
+	WallTime   float64
+
+	// MaxRSS is an upper bound, since the kernel keeps the peak of the exec stage after the program is executed
+	MaxRSS       int
+	VoluntaryCSW int
+	ForcedCSW    int
+
+	// Message is set if the program couldn't be started
+	Message string
+}
+
+func init() {
+	if len(os.Args) == 0 {
+		return
+	}
+	switch os.Args[0] {
+	case nsInitArg:
+		nsInit()
+	case nsExecArg:
+		nsExec()
+	}
+}
+
+func nsInit() {
+	for _, fd := range []int{nsRequestFd, nsResultFd, nsRunCgroup} {
+		syscall.CloseOnExec(fd)
+	}
+	resFile := os.NewFile(nsResultFd, "result")
+
+	var req nsRequest
+	res := new(nsResult)
+	if err := json.NewDecoder(os.NewFile(nsRequestFd, "request")).Decode(&req); err != nil {
+		res.Error = fmt.Sprintf("Couldn't read box request: %v", err)
+	} else if err := nsSwitchUser(&req); err != nil {
+		res.Error = fmt.Sprintf("Couldn't switch to the box user: %v", err)
+	} else if err := nsInitRun(&req, res); err != nil {
+		res.Error = err.Error()
+	}
+	if err := json.NewEncoder(resFile).Encode(res); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// nsSwitchUser switches to root inside the namespace, once it's mapped to the subordinate user of the box.
+// The server can't own what the programs create, so everything is created with permissions that let it clean up
+func nsSwitchUser(req *nsRequest) error {
+	if !req.SwitchUser {
+		return nil
+	}
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return err
+	}
+	if err := syscall.Setresgid(0, 0, 0); err != nil {
+		return err
+	}
+	if err := syscall.Setresuid(0, 0, 0); err != nil {
+		return err
+	}
+	syscall.Umask(0)
+	return nil
+}
+
+// nsInitRun builds the box and runs the program, as the init process of the box
+func nsInitRun(req *nsRequest, res *nsResult) error {
+	// Programs in the box run as the same user, they shouldn't be able to mess with the init process
+	if err := unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("couldn't make init non-dumpable: %w", err)
+	}
+	exe, err := nsBuildRoot(req)
+	if err != nil {
+		return err
+	}
+	defer exe.Close()
+	if len(req.Command) == 0 {
+		return nil
+	}
+
+	files, err := nsOpenRedirects(req)
+	if err != nil {
+		return err
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer errR.Close()
+
+	start := time.Now()
+	pid, err := syscall.ForkExec(fmt.Sprintf("/proc/self/fd/%d", exe.Fd()), append([]string{nsExecArg}, req.Command...), &syscall.ProcAttr{
+		Dir:   "/box",
+		Env:   req.Env,
+		Files: []uintptr{files[0].Fd(), files[1].Fd(), files[2].Fd(), nsRunCgroup, errW.Fd()},
+	})
+	errW.Close()
+	for _, f := range files {
+		f.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't start program: %w", err)
+	}
+
+	// The exec stage only writes to the pipe if it fails
+	msg, _ := io.ReadAll(errR)
+	res.Message = string(msg)
+
+	var status syscall.WaitStatus
+	var usage syscall.Rusage
+	for {
+		_, err = syscall.Wait4(pid, &status, 0, &usage)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't wait for program: %w", err)
+	}
+	res.WallTime = time.Since(start).Seconds()
+
+	switch {
+	case status.Signaled():
+		res.ExitSignal = int(status.Signal())
+	case status.Exited():
+		res.ExitCode = status.ExitStatus()
+	}
+	res.MaxRSS = int(usage.Maxrss)
+	res.VoluntaryCSW = int(usage.Nvcsw)
+	res.ForcedCSW = int(usage.Nivcsw)
+	return nil
+}
+
+// nsBuildRoot builds the filesystem of the box and switches to it.
+// It returns the binary of the process, to be executed later, since it's no longer visible afterwards
+func nsBuildRoot(req *nsRequest) (*os.File, error) {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return nil, fmt.Errorf("couldn't make mounts private: %w", err)
+	}
+	if err := unix.Mount("tmpfs", req.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=755,size=1m"); err != nil {
+		return nil, fmt.Errorf("couldn't mount box root: %w", err)
+	}
+	for _, mount := range req.Mounts {
+		if err := nsMountDir(req.Root, mount); err != nil {
+			return nil, fmt.Errorf("couldn't mount %s: %w", mount.Inside, err)
+		}
+	}
+
+	exe, err := os.Open("/proc/self/exe")
+	if err != nil {
+		return nil, err
+	}
+	if err := nsPivotRoot(req.Root); err != nil {
+		exe.Close()
+		return nil, err
+	}
+	return exe, nil
+}
+
+func nsPivotRoot(root string) error {
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	// Stacks the old root under the new one, so it can be detached without a temporary directory
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("couldn't pivot root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("couldn't detach old root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("couldn't make root read-only: %w", err)
+	}
+	return unix.Sethostname([]byte("box"))
+}
+
+func nsMountDir(root string, mount nsMount) error {
+	target := path.Join(root, mount.Inside)
+	switch {
+	case mount.Inside == "/proc":
+		// Mounting proc requires the one of the host to still be visible, so it's done before pivoting
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		return unix.Mount("proc", target, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	case mount.Inside == "/dev":
+		return nsMountDev(target)
+	case mount.Tmp:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		flags := uintptr(unix.MS_NOSUID | unix.MS_NODEV)
+		if mount.NoExec {
+			flags |= unix.MS_NOEXEC
+		}
+		return unix.Mount("tmpfs", target, "tmpfs", flags, "mode=1777")
+	}
+
+	st, err := os.Stat(mount.Outside)
+	if err != nil {
+		if mount.Maybe && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := nsMountPoint(target, st.IsDir()); err != nil {
+		return err
+	}
+	if err := unix.Mount(mount.Outside, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID)
+	if !mount.ReadWrite {
+		flags |= unix.MS_RDONLY
+	}
+	if !mount.Dev {
+		flags |= unix.MS_NODEV
+	}
+	if mount.NoExec {
+		flags |= unix.MS_NOEXEC
+	}
+	locked, err := nsLockedFlags(target)
+	if err != nil {
+		return err
+	}
+	return unix.Mount("", target, "", flags|locked, "")
+}
+
+// nsLockedFlags returns the flags of the mount which can't be cleared from inside a user namespace.
+// They must be kept when remounting, otherwise the kernel refuses the remount
+func nsLockedFlags(target string) (uintptr, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return 0, err
+	}
+	var flags uintptr
+	for stFlag, msFlag := range map[int64]uintptr{
+		unix.ST_RDONLY:      unix.MS_RDONLY,
+		unix.ST_NOSUID:      unix.MS_NOSUID,
+		unix.ST_NODEV:       unix.MS_NODEV,
+		unix.ST_NOEXEC:      unix.MS_NOEXEC,
+		unix.ST_NOATIME:     unix.MS_NOATIME,
+		unix.ST_NODIRATIME:  unix.MS_NODIRATIME,
+		unix.ST_RELATIME:    unix.MS_RELATIME,
+		unix.ST_SYNCHRONOUS: unix.MS_SYNCHRONOUS,
+	} {
+		if st.Flags&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	return flags, nil
+}
+
+// nsMountPoint creates the target of a bind mount
+func nsMountPoint(target string, dir bool) error {
+	if dir {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// nsMountDev creates a minimal /dev, with only the harmless devices
+func nsMountDev(target string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", target, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=755,size=64k"); err != nil {
+		return err
+	}
+	for _, dev := range []string{"null", "zero", "full", "random", "urandom"} {
+		if err := nsMountPoint(path.Join(target, dev), false); err != nil {
+			return err
+		}
+		if err := unix.Mount(path.Join("/dev", dev), path.Join(target, dev), "", unix.MS_BIND, ""); err != nil {
+			return err
+		}
+	}
+	for name, dest := range map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(dest, path.Join(target, name)); err != nil {
+			return err
+		}
+	}
+	return unix.Mount("", target, "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NOEXEC, "")
+}
+
+// nsOpenRedirects opens the standard input, output and error of the program, from inside the box
+func nsOpenRedirects(req *nsRequest) ([3]*os.File, error) {
+	var files [3]*os.File
+	var err error
+	open := func(p string, flag int) (*os.File, error) {
+		if p == "" {
+			p = "/dev/null"
+		}
+		return os.OpenFile(p, flag, 0644)
+	}
+	if files[0], err = open(req.InputPath, os.O_RDONLY); err != nil {
+		return files, fmt.Errorf("couldn't open input file: %w", err)
+	}
+	if files[1], err = open(req.OutputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC); err != nil {
+		files[0].Close()
+		return files, fmt.Errorf("couldn't open output file: %w", err)
+	}
+	if req.StderrToStdout {
+		files[2] = files[1]
+	} else if files[2], err = open(req.StderrPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC); err != nil {
+		files[0].Close()
+		files[1].Close()
+		return files, fmt.Errorf("couldn't open error file: %w", err)
+	}
+	return files, nil
+}
+
+// nsExec runs in the process that becomes the program. It moves itself to the cgroup of the program,
+// so that the limits and statistics only cover the program, drops all privileges and executes the program.
+// Capabilities and seccomp filters are per thread, so everything happens on the thread that calls execve
+func nsExec() {
+	runtime.LockOSThread()
+	syscall.CloseOnExec(nsExecErrors)
+	errPipe := os.NewFile(nsExecErrors, "errors")
+	fail := func(format string, args ...any) {
+		fmt.Fprintf(errPipe, format, args...)
+		os.Exit(127)
+	}
+
+	if _, err := syscall.Write(nsExecCgroup, []byte("0")); err != nil {
+		fail("Couldn't enter the cgroup of the program: %v", err)
+	}
+	syscall.Close(nsExecCgroup)
+
+	argv := os.Args[1:]
+	binary, err := exec.LookPath(argv[0])
+	if err != nil {
+		fail("execve(%q) failed: %v", argv[0], err)
+	}
+	if err := nsDropPrivileges(); err != nil {
+		fail("Couldn't drop privileges: %v", err)
+	}
+	if err := installSeccomp(); err != nil {
+		fail("Couldn't install seccomp filter: %v", err)
+	}
+	err = syscall.Exec(binary, argv, os.Environ())
+	fail("execve(%q) failed: %v", binary, err)
+}
+
+// securebits, from linux/securebits.h. They make sure root inside the box never gets capabilities back
+const (
+	secbitNoRoot             = 1 << 0
+	secbitNoRootLocked       = 1 << 1
+	secbitNoSetuidFixup      = 1 << 2
+	secbitNoSetuidFixupLock  = 1 << 3
+	secbitKeepCapsLocked     = 1 << 5
+	secbitNoCapAmbientRaise  = 1 << 6
+	secbitNoCapAmbientLocked = 1 << 7
+)
+
+func nsDropPrivileges() error {
+	for capability := 0; ; capability++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil {
+			if errors.Is(err, unix.EINVAL) { // Past the last capability
+				break
+			}
+			return err
+		}
+	}
+	securebits := secbitNoRoot | secbitNoRootLocked | secbitNoSetuidFixup | secbitNoSetuidFixupLock |
+		secbitKeepCapsLocked | secbitNoCapAmbientRaise | secbitNoCapAmbientLocked
+	if err := unix.Prctl(unix.PR_SET_SECUREBITS, uintptr(securebits), 0, 0, 0); err != nil {
+		return err
+	}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}, &data[0]); err != nil {
+		return err
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return err
+	}
+	// Like isolate, don't limit the stack more than the memory limit already does
+	var stack unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_STACK, &stack); err != nil {
+		return err
+	}
+	stack.Cur = stack.Max
+	return unix.Setrlimit(unix.RLIMIT_STACK, &stack)
+}
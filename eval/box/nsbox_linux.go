@@ -0,0 +1,528 @@
+package box
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KiloProjects/kilonova/eval"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+var _ eval.Sandbox = &NamespaceBox{}
+
+const (
+	// nsboxUIDBase is the start of the host UIDs given to boxes when running as root, same as isolate's default
+	nsboxUIDBase = 60000
+	// nsboxPollInterval is how often the time limits are checked while the program runs
+	nsboxPollInterval = 10 * time.Millisecond
+)
+
+// NamespaceBox is a sandbox implemented natively, without isolate, using Linux user namespaces,
+// seccomp and a delegated cgroup v2 directory (see config.Eval.CgroupRoot). It doesn't require root,
+// but then the user of the server needs subordinate IDs (see subuid(5)) for the boxes. SetupNamespaceSandbox must be called before creating boxes.
+//
+// Every run starts an init process (pid 1 of a new set of namespaces) that builds the filesystem of the box,
+// from the same directory rules isolate uses, and starts the program in its own cgroup, which enforces the memory limit
+// and measures the CPU time and memory usage. See nsbox_init_linux.go for the part running inside the box.
+type NamespaceBox struct {
+	mu    sync.Mutex
+	path  string
+	boxID int
+
+	// uid and gid are the host user and group the programs in the box run as, never the ones of the server
+	uid int
+	gid int
+
+	memoryQuota int64
+
+	logger *zap.SugaredLogger
+}
+
+var (
+	nsboxCheckOnce sync.Once
+	nsboxCheckErr  error
+)
+
+// nsboxDefaultRules are the directory rules applied before the ones of the run, same as isolate's.
+// /proc and /dev are not bind mounted from the host, see nsMount
+var nsboxDefaultRules = []eval.Directory{
+	{In: "/box", Opts: "rw"},
+	{In: "/bin"},
+	{In: "/dev", Opts: "dev"},
+	{In: "/lib"},
+	{In: "/lib64", Opts: "maybe"},
+	{In: "/proc"},
+	{In: "/usr"},
+}
+
+func (b *NamespaceBox) GetID() int {
+	return b.boxID
+}
+
+func (b *NamespaceBox) MemoryQuota() int64 {
+	return b.memoryQuota
+}
+
+// getFilePath returns a path to the file location on disk of a box file
+func (b *NamespaceBox) getFilePath(boxpath string) string {
+	return path.Join(b.path, boxpath)
+}
+
+func (b *NamespaceBox) ReadFile(fpath string, w io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return readFile(b.getFilePath(fpath), w)
+}
+
+func (b *NamespaceBox) WriteFile(fpath string, r io.Reader, mode fs.FileMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return writeFile(b.getFilePath(fpath), r, mode)
+}
+
+func (b *NamespaceBox) FileExists(fpath string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return checkFile(b.getFilePath(fpath))
+}
+
+func (b *NamespaceBox) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return os.RemoveAll(b.path)
+}
+
+// buildMounts resolves the directory rules of the run, like isolate does: later rules replace earlier ones with the same path
+func (b *NamespaceBox) buildMounts(dirs []eval.Directory) ([]nsMount, error) {
+	var mounts []nsMount
+	for _, dir := range append(slices.Clone(nsboxDefaultRules), dirs...) {
+		inside := path.Join("/", dir.In)
+		if inside == "/" {
+			return nil, errors.New("the root of the box can't be replaced")
+		}
+		mounts = slices.DeleteFunc(mounts, func(m nsMount) bool { return m.Inside == inside })
+		if dir.Removes {
+			continue
+		}
+
+		mount := nsMount{Inside: inside, Outside: dir.Out}
+		if mount.Outside == "" {
+			mount.Outside = inside
+		}
+		for _, opt := range strings.Split(dir.Opts, ",") {
+			switch opt {
+			case "":
+			case "rw":
+				mount.ReadWrite = true
+			case "maybe":
+				mount.Maybe = true
+			case "tmp":
+				mount.Tmp = true
+			case "noexec":
+				mount.NoExec = true
+			case "dev":
+				mount.Dev = true
+			default:
+				return nil, fmt.Errorf("unsupported directory option %q for %s", opt, dir.In)
+			}
+		}
+		switch {
+		case inside == "/box":
+			mount.Outside = b.getFilePath("/box")
+		case inside == "/proc" || inside == "/dev" || mount.Tmp:
+			mount.Outside = ""
+		}
+		mounts = append(mounts, mount)
+	}
+	// Parents must be mounted before their children
+	slices.SortFunc(mounts, func(a, b nsMount) int { return strings.Compare(a.Inside, b.Inside) })
+	return mounts, nil
+}
+
+func (b *NamespaceBox) buildEnv(conf *eval.RunConfig) []string {
+	var env []string
+	if conf.InheritEnv {
+		env = os.Environ()
+	} else {
+		env = append(env, "PATH="+defaultPath)
+		for _, key := range conf.EnvToInherit {
+			if val, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+val)
+			}
+		}
+	}
+	for key, val := range conf.EnvToSet {
+		env = append(env, key+"="+val)
+	}
+	return env
+}
+
+func (b *NamespaceBox) RunCommand(ctx context.Context, command []string, conf *eval.RunConfig) (*eval.RunStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if conf.MemoryLimit != 0 && b.memoryQuota > 0 && int64(conf.MemoryLimit) > b.memoryQuota {
+		zap.S().Info("Memory limit supplied exceeds quota")
+		conf.MemoryLimit = int(b.memoryQuota)
+	}
+
+	mounts, err := b.buildMounts(conf.Directories)
+	if err != nil {
+		return nil, err
+	}
+	req := &nsRequest{
+		Root:           b.getFilePath("/root"),
+		Mounts:         mounts,
+		Command:        command,
+		Env:            b.buildEnv(conf),
+		InputPath:      conf.InputPath,
+		OutputPath:     conf.OutputPath,
+		StderrPath:     conf.StderrPath,
+		StderrToStdout: conf.StderrToStdout,
+	}
+	return b.run(ctx, req, conf)
+}
+
+// run starts the init process of the box with the request and waits for it to finish, enforcing the limits in conf
+func (b *NamespaceBox) run(ctx context.Context, req *nsRequest, conf *eval.RunConfig) (*eval.RunStats, error) {
+	root, err := cgroupRoot()
+	if err != nil {
+		return nil, err
+	}
+	boxCg, err := newCgroup(root, "box-"+strconv.Itoa(b.boxID), true)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create cgroup: %w", err)
+	}
+	defer func() {
+		// Just in case something survived the death of the init process
+		boxCg.write("cgroup.kill", "1")
+		if err := boxCg.remove(); err != nil {
+			zap.S().Warnf("Couldn't remove cgroup of box %d: %v", b.boxID, err)
+		}
+	}()
+	initCg, err := newCgroup(string(boxCg), "init", false)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create cgroup: %w", err)
+	}
+	runCg, err := newCgroup(string(boxCg), "run", false)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create cgroup: %w", err)
+	}
+	if err := runCg.setLimits(conf.MemoryLimit); err != nil {
+		return nil, fmt.Errorf("couldn't set cgroup limits: %w", err)
+	}
+	// The program moves itself to the run cgroup, see nsExec. Without root, it can only use the file descriptor opened here
+	unprivileged := os.Getuid() != 0
+	if !unprivileged {
+		for _, file := range []string{boxCg.path("cgroup.procs"), runCg.path("cgroup.procs")} {
+			if err := os.Chown(file, b.uid, b.gid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	initCgDir, err := os.Open(string(initCg))
+	if err != nil {
+		return nil, err
+	}
+	defer initCgDir.Close()
+	runProcs, err := os.OpenFile(runCg.path("cgroup.procs"), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer runProcs.Close()
+
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer reqR.Close()
+	defer reqW.Close()
+	resR, resW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer resR.Close()
+	defer resW.Close()
+
+	var initOut bytes.Buffer
+	cmd := &exec.Cmd{
+		Path:       "/proc/self/exe",
+		Args:       []string{nsInitArg},
+		Env:        []string{},
+		Stdout:     &initOut,
+		Stderr:     &initOut,
+		ExtraFiles: []*os.File{reqR, resW, runProcs},
+		SysProcAttr: &syscall.SysProcAttr{
+			Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+			Pdeathsig:   syscall.SIGKILL,
+			UseCgroupFD: true,
+			CgroupFD:    int(initCgDir.Fd()),
+		},
+	}
+	if !unprivileged {
+		// The process switches to the mapped user, dropping the supplementary groups of root
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: b.uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: b.gid, Size: 1}}
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0, Groups: []uint32{}}
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = true
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("couldn't start box: %w", err)
+	}
+	reqR.Close()
+	resW.Close()
+	if unprivileged {
+		// Only the setuid helpers can map the subordinate IDs. The init process waits for the request before switching to them
+		if err := b.mapSubordinateIDs(cmd.Process.Pid); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, err
+		}
+		req.SwitchUser = true
+	}
+	if err := json.NewEncoder(reqW).Encode(req); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("couldn't send request to box: %w", err)
+	}
+	reqW.Close()
+
+	var res nsResult
+	resDone := make(chan error, 1)
+	go func() {
+		resDone <- json.NewDecoder(resR).Decode(&res)
+	}()
+	waitDone := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(waitDone)
+	}()
+
+	// Killing the init process kills everything in the box, since it's pid 1 of its namespace
+	var timedOut, wallTimedOut, cancelled bool
+	var sampledMemory int
+	ticker := time.NewTicker(nsboxPollInterval)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-waitDone:
+			break loop
+		case <-ctx.Done():
+			cancelled = true
+			cmd.Process.Kill()
+		case <-ticker.C:
+			sampledMemory = max(sampledMemory, runCg.memory())
+			switch {
+			case conf.TimeLimit > 0 && runCg.cpuTime() > conf.TimeLimit:
+				timedOut = true
+				cmd.Process.Kill()
+			case conf.WallTimeLimit > 0 && time.Since(start).Seconds() > conf.WallTimeLimit:
+				wallTimedOut = true
+				cmd.Process.Kill()
+			}
+		}
+	}
+	wallTime := time.Since(start).Seconds()
+	if cancelled {
+		return nil, ctx.Err()
+	}
+	resErr := <-resDone
+
+	stats := &eval.RunStats{
+		Time:            runCg.cpuTime(),
+		WallTime:        wallTime,
+		OOMKilled:       runCg.oomKilled(),
+		InternalMessage: initOut.String(),
+	}
+	if peak, ok := runCg.peakMemory(); ok {
+		stats.Memory = peak
+	} else {
+		stats.Memory = max(sampledMemory, runCg.memory())
+	}
+	killed := timedOut || wallTimedOut
+	if resErr != nil && !killed {
+		return nil, fmt.Errorf("box exited unexpectedly: %q", initOut.String())
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	if res.WallTime > 0 {
+		stats.WallTime = res.WallTime
+	}
+	stats.MaxRSS = res.MaxRSS
+	stats.VoluntaryCSW, stats.ForcedCSW = res.VoluntaryCSW, res.ForcedCSW
+	stats.InternalMessage += res.Message
+
+	switch {
+	case timedOut || (conf.TimeLimit > 0 && stats.Time > conf.TimeLimit):
+		stats.Killed = killed
+		stats.Status = "TO"
+		stats.Message = "Time limit exceeded"
+	case wallTimedOut:
+		stats.Killed = true
+		stats.Status = "TO"
+		stats.Message = "Time limit exceeded (wall clock)"
+	case res.ExitSignal != 0:
+		stats.ExitSignal = res.ExitSignal
+		stats.Killed = true
+		stats.Status = "SG"
+		stats.Message = "Caught fatal signal " + strconv.Itoa(stats.ExitSignal)
+	case res.ExitCode != 0:
+		stats.ExitCode = res.ExitCode
+		stats.Status = "RE"
+		stats.Message = "Exited with error status " + strconv.Itoa(stats.ExitCode)
+	}
+	return stats, nil
+}
+
+// checkNamespaces builds an empty box once, to make sure user namespaces and the cgroup are usable
+func (b *NamespaceBox) checkNamespaces() error {
+	nsboxCheckOnce.Do(func() {
+		mounts, err := b.buildMounts(nil)
+		if err != nil {
+			nsboxCheckErr = err
+			return
+		}
+		_, nsboxCheckErr = b.run(context.Background(), &nsRequest{Root: b.getFilePath("/root"), Mounts: mounts}, &eval.RunConfig{WallTimeLimit: 10})
+	})
+	return nsboxCheckErr
+}
+
+// NewNamespace returns a new native box instance from the specified ID
+func NewNamespace(id int, memQuota int64, logger *zap.SugaredLogger) (eval.Sandbox, error) {
+	dirname := path.Join(os.TempDir(), fmt.Sprintf("kn-nsbox-%d", id))
+	// Try to clear existing box first, if it exited without cleanup
+	if err := os.RemoveAll(dirname); err != nil {
+		return nil, err
+	}
+	// The box "home" directory and the mount point of the root of the box
+	for _, dir := range []string{"box", "root"} {
+		if err := os.MkdirAll(path.Join(dirname, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	var uid, gid int
+	if os.Getuid() == 0 {
+		// Don't run programs as the real root, even inside a namespace
+		uid, gid = nsboxUIDBase+id, nsboxUIDBase+id
+		if err := os.Chown(path.Join(dirname, "box"), uid, gid); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		uid, gid, err = subordinateIDs(id)
+		if err != nil {
+			os.RemoveAll(dirname)
+			return nil, fmt.Errorf("native sandbox needs a subordinate UID and GID when not running as root: %w", err)
+		}
+		// The box directory can't be given to a subordinate user, so it's writable by anyone instead.
+		// Programs run with umask 0 (see nsInit), so the server can remove whatever they create
+		if err := os.Chmod(path.Join(dirname, "box"), 0777); err != nil {
+			return nil, err
+		}
+	}
+
+	b := &NamespaceBox{
+		path:        dirname,
+		boxID:       id,
+		uid:         uid,
+		gid:         gid,
+		memoryQuota: memQuota,
+		logger:      logger,
+	}
+	if err := b.checkNamespaces(); err != nil {
+		os.RemoveAll(dirname)
+		return nil, fmt.Errorf("native sandbox is not available (kernel %s): %w", nsKernelVersion(), err)
+	}
+	return b, nil
+}
+
+// mapSubordinateIDs maps root inside the namespace of the process to the user and group of the box
+func (b *NamespaceBox) mapSubordinateIDs(pid int) error {
+	for _, cmd := range [][]string{
+		{"newuidmap", strconv.Itoa(pid), "0", strconv.Itoa(b.uid), "1"},
+		{"newgidmap", strconv.Itoa(pid), "0", strconv.Itoa(b.gid), "1"},
+	} {
+		if out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s failed: %w (%s)", cmd[0], err, bytes.TrimSpace(out))
+		}
+	}
+	return nil
+}
+
+// subordinateIDs returns the user and group of the box with the given ID, from the subordinate IDs of the current user.
+// They are used when running without root, which would otherwise have to run the programs as the user of the server
+func subordinateIDs(id int) (int, int, error) {
+	for _, helper := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(helper); err != nil {
+			return 0, 0, fmt.Errorf("%s was not found (it's usually in the uidmap package)", helper)
+		}
+	}
+	uid, uidCount, err := subordinateRange("/etc/subuid")
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, gidCount, err := subordinateRange("/etc/subgid")
+	if err != nil {
+		return 0, 0, err
+	}
+	if id >= uidCount || id >= gidCount {
+		return 0, 0, fmt.Errorf("box %d doesn't fit in the subordinate IDs of the user", id)
+	}
+	return uid + id, gid + id, nil
+}
+
+// subordinateRange returns the first subordinate ID of the current user from /etc/subuid or /etc/subgid, along with the number of IDs
+func subordinateRange(file string) (int, int, error) {
+	current, err := user.Current()
+	if err != nil {
+		return 0, 0, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) != 3 || (fields[0] != current.Username && fields[0] != current.Uid) {
+			continue
+		}
+		start, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || count <= 0 {
+			return 0, 0, fmt.Errorf("invalid line in %s: %q", file, line)
+		}
+		return start, count, nil
+	}
+	return 0, 0, fmt.Errorf("user %s has no subordinate IDs in %s", current.Username, file)
+}
+
+// nsKernelVersion is used in error messages, since most failures come from old or restricted kernels
+func nsKernelVersion() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return "unknown"
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package box
+
+import (
+	"errors"
+
+	"github.com/KiloProjects/kilonova/eval"
+	"go.uber.org/zap"
+)
+
+// NewNamespace is only available on Linux
+func NewNamespace(id int, memQuota int64, logger *zap.SugaredLogger) (eval.Sandbox, error) {
+	return nil, errors.New("the native sandbox is only available on Linux")
+}
+
+// NamespaceSandboxAvailable is only available on Linux
+func NamespaceSandboxAvailable() error {
+	return errors.New("the native sandbox is only available on Linux")
+}
+
+// SetupNamespaceSandbox is only available on Linux
+func SetupNamespaceSandbox() error {
+	return errors.New("the native sandbox is only available on Linux")
+}
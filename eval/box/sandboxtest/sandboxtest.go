@@ -0,0 +1,277 @@
+// Package sandboxtest is a conformance suite for eval.Sandbox implementations.
+// The programs it runs only need a POSIX shell and coreutils in the /bin and /usr directories of the box.
+package sandboxtest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova/eval"
+)
+
+// NewBoxFunc creates a box with the given ID for a test. It should skip the test if the sandbox is not available
+type NewBoxFunc func(t *testing.T, id int) eval.Sandbox
+
+// Options describe the guarantees of the tested sandbox
+type Options struct {
+	// Isolated sandboxes only show the directories given in RunConfig.Directories (plus the defaults) to programs,
+	// and enforce the read-only ones. The stupid sandbox isn't isolated
+	Isolated bool
+}
+
+// Run runs the whole suite on the sandbox
+func Run(t *testing.T, newBox NewBoxFunc, opts Options) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b eval.Sandbox, opts Options)
+	}{
+		{"files", testFiles},
+		{"redirects", testRedirects},
+		{"stderrToStdout", testStderrToStdout},
+		{"exitCode", testExitCode},
+		{"signal", testSignal},
+		{"timeLimit", testTimeLimit},
+		{"wallTimeLimit", testWallTimeLimit},
+		{"memoryLimit", testMemoryLimit},
+		{"env", testEnv},
+		{"directories", testDirectories},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newBox(t, i+1)
+			defer b.Close()
+			test.fn(t, b, opts)
+		})
+	}
+}
+
+func writeFile(t *testing.T, b eval.Sandbox, path string, contents string, mode os.FileMode) {
+	t.Helper()
+	if err := b.WriteFile(path, strings.NewReader(contents), mode); err != nil {
+		t.Fatalf("Couldn't write %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, b eval.Sandbox, path string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.ReadFile(path, &buf); err != nil {
+		t.Fatalf("Couldn't read %s: %v", path, err)
+	}
+	return buf.String()
+}
+
+// run runs a shell script in the box, with its output in /box/out, and checks that the sandbox didn't fail
+func run(t *testing.T, b eval.Sandbox, script string, conf *eval.RunConfig) *eval.RunStats {
+	t.Helper()
+	if conf.OutputPath == "" {
+		conf.OutputPath = "/box/out"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stats, err := b.RunCommand(ctx, []string{"/bin/sh", "-c", script}, conf)
+	if err != nil {
+		t.Fatalf("Couldn't run %q: %v", script, err)
+	}
+	if stats == nil {
+		t.Fatalf("No stats for %q", script)
+	}
+	if stats.Status == "XX" {
+		t.Fatalf("Sandbox error running %q: %s", script, stats.InternalMessage)
+	}
+	return stats
+}
+
+func expectSuccess(t *testing.T, stats *eval.RunStats) {
+	t.Helper()
+	if stats.Status != "" || stats.ExitCode != 0 || stats.ExitSignal != 0 {
+		t.Fatalf("Program failed: %#v", stats)
+	}
+}
+
+func testFiles(t *testing.T, b eval.Sandbox, _ Options) {
+	const contents = "line 1\nline 2\n\x00binary\xff"
+	writeFile(t, b, "/box/data", contents, 0644)
+	if !b.FileExists("/box/data") {
+		t.Fatal("Written file doesn't exist")
+	}
+	if b.FileExists("/box/missing") {
+		t.Fatal("Missing file exists")
+	}
+	if got := readFile(t, b, "/box/data"); got != contents {
+		t.Fatalf("Wanted %q, got %q", contents, got)
+	}
+
+	// Files written to the box can be executed by programs, and files written by programs can be read.
+	// Programs start in /box
+	writeFile(t, b, "/box/script.sh", "#!/bin/sh\necho \"script $1\" > result\n", 0755)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stats, err := b.RunCommand(ctx, []string{"/box/script.sh", "ran"}, &eval.RunConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSuccess(t, stats)
+	if got := readFile(t, b, "/box/result"); got != "script ran\n" {
+		t.Fatalf("Unexpected script output %q", got)
+	}
+}
+
+func testRedirects(t *testing.T, b eval.Sandbox, _ Options) {
+	const input = "3\n1 2 3\n"
+	writeFile(t, b, "/box/in", input, 0644)
+	stats := run(t, b, "cat; echo error >&2", &eval.RunConfig{InputPath: "/box/in", StderrPath: "/box/err"})
+	expectSuccess(t, stats)
+	if got := readFile(t, b, "/box/out"); got != input {
+		t.Fatalf("Wanted output %q, got %q", input, got)
+	}
+	if got := readFile(t, b, "/box/err"); got != "error\n" {
+		t.Fatalf("Wanted stderr %q, got %q", "error\n", got)
+	}
+	if stats.WallTime <= 0 {
+		t.Fatalf("Wall time not measured: %#v", stats)
+	}
+
+	// Without redirects, the standard input is empty
+	stats = run(t, b, "wc -c", &eval.RunConfig{})
+	expectSuccess(t, stats)
+	if got := strings.TrimSpace(readFile(t, b, "/box/out")); got != "0" {
+		t.Fatalf("Standard input should be empty, got %q bytes", got)
+	}
+}
+
+func testStderrToStdout(t *testing.T, b eval.Sandbox, _ Options) {
+	stats := run(t, b, "echo out; echo err >&2; echo out2", &eval.RunConfig{StderrToStdout: true})
+	expectSuccess(t, stats)
+	if got := readFile(t, b, "/box/out"); got != "out\nerr\nout2\n" {
+		t.Fatalf("Unexpected combined output %q", got)
+	}
+}
+
+func testExitCode(t *testing.T, b eval.Sandbox, _ Options) {
+	stats := run(t, b, "exit 3", &eval.RunConfig{})
+	if stats.Status != "RE" || stats.ExitCode != 3 || stats.Message != "Exited with error status 3" {
+		t.Fatalf("Unexpected stats for exit code: %#v", stats)
+	}
+}
+
+func testSignal(t *testing.T, b eval.Sandbox, _ Options) {
+	stats := run(t, b, "kill -SEGV $$", &eval.RunConfig{})
+	if stats.Status != "SG" || stats.ExitSignal != 11 || stats.Message != "Caught fatal signal 11" {
+		t.Fatalf("Unexpected stats for signal: %#v", stats)
+	}
+}
+
+func testTimeLimit(t *testing.T, b eval.Sandbox, _ Options) {
+	// The wall time limit is only a safety net, the CPU time limit must be reported
+	stats := run(t, b, "while :; do :; done", &eval.RunConfig{TimeLimit: 0.3, WallTimeLimit: 3})
+	if stats.Status != "TO" || stats.Message != "Time limit exceeded" || !stats.Killed {
+		t.Fatalf("Unexpected stats for time limit: %#v", stats)
+	}
+	if stats.Time < 0.3 {
+		t.Fatalf("Time limit exceeded with time %f", stats.Time)
+	}
+
+	stats = run(t, b, "echo fast", &eval.RunConfig{TimeLimit: 1, WallTimeLimit: 3})
+	expectSuccess(t, stats)
+	if stats.Time >= 1 {
+		t.Fatalf("Time too big for a fast program: %f", stats.Time)
+	}
+}
+
+func testWallTimeLimit(t *testing.T, b eval.Sandbox, _ Options) {
+	start := time.Now()
+	stats := run(t, b, "sleep 10", &eval.RunConfig{TimeLimit: 1, WallTimeLimit: 0.3})
+	if stats.Status != "TO" || stats.Message != "Time limit exceeded (wall clock)" || !stats.Killed {
+		t.Fatalf("Unexpected stats for wall time limit: %#v", stats)
+	}
+	if stats.WallTime < 0.3 {
+		t.Fatalf("Wall time limit exceeded with wall time %f", stats.WallTime)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Program wasn't killed in time (%s)", elapsed)
+	}
+}
+
+func testMemoryLimit(t *testing.T, b eval.Sandbox, _ Options) {
+	// dd fills its whole buffer, so the memory is actually used
+	stats := run(t, b, "dd if=/dev/zero of=/dev/null bs=256M count=1 2>/dev/null", &eval.RunConfig{MemoryLimit: 64 * 1024, TimeLimit: 5, WallTimeLimit: 10})
+	if stats.Status != "SG" || !stats.OOMKilled {
+		t.Fatalf("Unexpected stats for memory limit: %#v", stats)
+	}
+
+	stats = run(t, b, "dd if=/dev/zero of=/dev/null bs=16M count=1 2>/dev/null", &eval.RunConfig{MemoryLimit: 64 * 1024, TimeLimit: 5, WallTimeLimit: 10})
+	expectSuccess(t, stats)
+	if stats.OOMKilled || stats.Memory < 16*1024 {
+		t.Fatalf("Unexpected stats for program under memory limit: %#v", stats)
+	}
+}
+
+func testEnv(t *testing.T, b eval.Sandbox, _ Options) {
+	t.Setenv("KN_SANDBOXTEST_SECRET", "secret")
+	t.Setenv("KN_SANDBOXTEST_INHERITED", "inherited")
+	stats := run(t, b, `echo "$FOO|$KN_SANDBOXTEST_SECRET|$KN_SANDBOXTEST_INHERITED|$PATH"`, &eval.RunConfig{
+		EnvToSet:     map[string]string{"FOO": "bar baz"},
+		EnvToInherit: []string{"KN_SANDBOXTEST_INHERITED"},
+	})
+	expectSuccess(t, stats)
+	got := strings.Split(strings.TrimSpace(readFile(t, b, "/box/out")), "|")
+	if len(got) != 4 || got[0] != "bar baz" || got[1] != "" || got[2] != "inherited" || got[3] == "" {
+		t.Fatalf("Unexpected environment %q", got)
+	}
+}
+
+func testDirectories(t *testing.T, b eval.Sandbox, opts Options) {
+	roDir, rwDir, hiddenDir := shareableDir(t), shareableDir(t), shareableDir(t)
+	if err := os.WriteFile(filepath.Join(roDir, "file"), []byte("mounted\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(rwDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	dirs := []eval.Directory{{In: roDir}, {In: rwDir, Opts: "rw"}}
+
+	stats := run(t, b, "cat "+roDir+"/file; echo written > "+rwDir+"/file", &eval.RunConfig{Directories: dirs})
+	expectSuccess(t, stats)
+	if got := readFile(t, b, "/box/out"); got != "mounted\n" {
+		t.Fatalf("Unexpected contents of mounted file %q", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(rwDir, "file")); err != nil || string(data) != "written\n" {
+		t.Fatalf("Write to read-write directory didn't reach the host: %q %v", data, err)
+	}
+
+	if !opts.Isolated {
+		return
+	}
+	stats = run(t, b, "echo x > "+roDir+"/new", &eval.RunConfig{Directories: dirs})
+	if stats.Status != "RE" {
+		t.Fatalf("Write to read-only directory succeeded: %#v", stats)
+	}
+	stats = run(t, b, "test -e "+hiddenDir, &eval.RunConfig{Directories: dirs})
+	if stats.Status != "RE" {
+		t.Fatalf("Directory that isn't mounted is visible: %#v", stats)
+	}
+	// The directory can also be mounted somewhere else
+	stats = run(t, b, "cat /data/file", &eval.RunConfig{Directories: []eval.Directory{{In: "/data", Out: roDir}}})
+	expectSuccess(t, stats)
+	if got := readFile(t, b, "/box/out"); got != "mounted\n" {
+		t.Fatalf("Unexpected contents of mounted file %q", got)
+	}
+}
+
+// shareableDir returns a temporary directory which can be read by other users, such as the ones sandboxes use
+func shareableDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, p := range []string{filepath.Dir(dir), dir} {
+		if err := os.Chmod(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,10 @@
+package box
+
+import "golang.org/x/sys/unix"
+
+const (
+	seccompArch        = unix.AUDIT_ARCH_X86_64
+	seccompSyscallMask = 0x40000000 // __X32_SYSCALL_BIT
+)
+
+var seccompArchBlocked = []uint32{unix.SYS_IOPL, unix.SYS_IOPERM}
@@ -0,0 +1,10 @@
+package box
+
+import "golang.org/x/sys/unix"
+
+const (
+	seccompArch        = unix.AUDIT_ARCH_AARCH64
+	seccompSyscallMask = 0
+)
+
+var seccompArchBlocked []uint32
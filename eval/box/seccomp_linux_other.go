@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64
+
+package box
+
+// The native box doesn't have a seccomp filter for this architecture, so it refuses to run programs
+const (
+	seccompArch        = 0
+	seccompSyscallMask = 0
+)
+
+var seccompArchBlocked []uint32
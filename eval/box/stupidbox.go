@@ -7,8 +7,13 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/KiloProjects/kilonova/eval"
 	"go.uber.org/zap"
@@ -16,9 +21,16 @@ import (
 
 var _ eval.Sandbox = &StupidSandbox{}
 
+// defaultPath is the PATH given to programs that don't inherit the environment, same as in isolate
+const defaultPath = "/usr/local/bin:/usr/bin:/bin"
+
 // StupidSandbox can be used for testing.
 // NOTE: should not be used in a proper environment. It has no proper memory limit
 // And time limits are based on manually killing the program
+//
+// There is no chroot, so programs see the whole filesystem and mounts are ignored.
+// Instead, all /box paths in the command, environment and redirects are rewritten to the box directory on disk,
+// and so are the paths inside directories mounted from another host directory.
 type StupidSandbox struct {
 	mu    sync.Mutex
 	path  string
@@ -41,17 +53,138 @@ func (b *StupidSandbox) RunCommand(ctx context.Context, command []string, conf *
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// TODO: Mounts (for chroot)
+	args := make([]string, 0, len(command))
+	for _, arg := range command {
+		args = append(args, b.hostPaths(arg, conf))
+	}
+
+	runCtx := ctx
+	if conf.WallTimeLimit > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(conf.WallTimeLimit*float64(time.Second)))
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+	cmd.Dir = b.getFilePath("/box")
+	// Kill the whole process group, compilers and runtimes (such as the JVM) start other processes
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.Env = b.buildEnv(conf)
+
+	if conf.InputPath != "" {
+		f, err := os.Open(b.hostPaths(conf.InputPath, conf))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	if conf.OutputPath != "" {
+		f, err := os.OpenFile(b.hostPaths(conf.OutputPath, conf), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		cmd.Stdout = f
+	}
+	if conf.StderrToStdout {
+		cmd.Stderr = cmd.Stdout
+	} else if conf.StderrPath != "" {
+		f, err := os.OpenFile(b.hostPaths(conf.StderrPath, conf), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		cmd.Stderr = f
+	}
 
-	// TODO: Running command
+	start := time.Now()
+	err := cmd.Run()
+	stats := &eval.RunStats{WallTime: time.Since(start).Seconds()}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
-	// TODO: Goroutine to wait for time limit and kill program
+	if usage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		stats.Time = time.Duration(usage.Utime.Nano() + usage.Stime.Nano()).Seconds()
+		stats.MaxRSS = int(usage.Maxrss)
+		stats.Memory = stats.MaxRSS
+		stats.VoluntaryCSW = int(usage.Nvcsw)
+		stats.ForcedCSW = int(usage.Nivcsw)
+	}
 
-	// TODO: Read sysprocattr for runstats
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	switch {
+	case conf.TimeLimit > 0 && stats.Time > conf.TimeLimit:
+		// Also reported if the program was killed by the wall time limit, like isolate does
+		stats.Killed = runCtx.Err() != nil
+		stats.Status = "TO"
+		stats.Message = "Time limit exceeded"
+	case runCtx.Err() != nil:
+		stats.Killed = true
+		stats.Status = "TO"
+		stats.Message = "Time limit exceeded (wall clock)"
+	case status.Signaled():
+		stats.ExitSignal = int(status.Signal())
+		stats.Killed = true
+		stats.Status = "SG"
+		stats.Message = "Caught fatal signal " + strconv.Itoa(stats.ExitSignal)
+	case status.ExitStatus() != 0:
+		stats.ExitCode = status.ExitStatus()
+		stats.Status = "RE"
+		stats.Message = "Exited with error status " + strconv.Itoa(stats.ExitCode)
+	case conf.MemoryLimit > 0 && stats.Memory > conf.MemoryLimit:
+		// The memory limit is only checked after the program finishes
+		stats.OOMKilled = true
+		stats.Status = "SG"
+		stats.Message = "Memory limit exceeded"
+	}
+	return stats, nil
+}
 
-	// TODO: Unmounts
+// hostPaths rewrites all box paths in s to the box directory on disk.
+// Paths in mounted directories are rewritten to their host directory, if they are whole arguments
+func (b *StupidSandbox) hostPaths(s string, conf *eval.RunConfig) string {
+	for _, dir := range conf.Directories {
+		if dir.Out == "" || dir.Out == dir.In || dir.Removes {
+			continue
+		}
+		if s == dir.In {
+			return dir.Out
+		}
+		if rest, ok := strings.CutPrefix(s, dir.In+"/"); ok {
+			return path.Join(dir.Out, rest)
+		}
+	}
+	if s == "/box" {
+		return b.getFilePath("/box")
+	}
+	return strings.ReplaceAll(s, "/box/", b.getFilePath("/box")+"/")
+}
 
-	panic("TODO")
+func (b *StupidSandbox) buildEnv(conf *eval.RunConfig) []string {
+	var env []string
+	if conf.InheritEnv {
+		env = os.Environ()
+	} else {
+		env = append(env, "PATH="+defaultPath)
+		for _, key := range conf.EnvToInherit {
+			if val, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+val)
+			}
+		}
+	}
+	for key, val := range conf.EnvToSet {
+		env = append(env, key+"="+b.hostPaths(val, conf))
+	}
+	return env
 }
 
 func (b *StupidSandbox) Close() error {
@@ -78,7 +211,7 @@ func NewStupid(boxID int, memoryQuota int64, logger *zap.SugaredLogger) (eval.Sa
 		boxID:       boxID,
 		memoryQuota: memoryQuota,
 		logger:      logger,
-	}, errors.New("stupid sandbox is still work in progress")
+	}, nil
 }
 
 func (b *StupidSandbox) ReadFile(fpath string, w io.Writer) error {
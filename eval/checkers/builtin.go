@@ -0,0 +1,156 @@
+package checkers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+const maxTokenSize = 64 * 1024 * 1024 // bytes
+
+var _ Checker = &BuiltinChecker{}
+
+// BuiltinChecker compares outputs in-process, without spawning a sandbox.
+// Note that DiffChecker is still used for the default BuiltinCheckerDiff type
+type BuiltinChecker struct {
+	Type       kilonova.BuiltinCheckerType
+	AbsEpsilon float64
+	RelEpsilon float64
+}
+
+func (c *BuiltinChecker) Prepare(_ context.Context) (string, error) { return "", nil }
+
+func (c *BuiltinChecker) Cleanup(_ context.Context) error { return nil }
+
+func (c *BuiltinChecker) RunChecker(_ context.Context, pOut, _, cOut io.Reader) (string, decimal.Decimal) {
+	var ok bool
+	var err error
+	switch c.Type {
+	case kilonova.BuiltinCheckerExact:
+		ok, err = compareExact(pOut, cOut)
+	case kilonova.BuiltinCheckerTokens:
+		ok, err = compareTokens(pOut, cOut, func(p, c string) bool { return p == c })
+	case kilonova.BuiltinCheckerTokensCI:
+		ok, err = compareTokens(pOut, cOut, strings.EqualFold)
+	case kilonova.BuiltinCheckerFloat:
+		ok, err = compareTokens(pOut, cOut, c.floatEqual)
+	case kilonova.BuiltinCheckerUnorderedLines:
+		ok, err = compareUnorderedLines(pOut, cOut)
+	case kilonova.BuiltinCheckerYesNo:
+		ok, err = compareTokens(pOut, cOut, yesNoEqual)
+	default:
+		return ErrOut, decimal.Zero
+	}
+	if err != nil {
+		return ErrOut, decimal.Zero
+	}
+	if !ok {
+		return WrongOut, decimal.Zero
+	}
+	return CorrectOut, decimal.NewFromInt(100)
+}
+
+// floatEqual accepts the contestant's number if it's within either epsilon of the correct one.
+// Tokens that are not numbers must match exactly
+func (c *BuiltinChecker) floatEqual(pTok, cTok string) bool {
+	expected, err := strconv.ParseFloat(cTok, 64)
+	if err != nil || math.IsNaN(expected) || math.IsInf(expected, 0) {
+		return pTok == cTok
+	}
+	got, err := strconv.ParseFloat(pTok, 64)
+	if err != nil || math.IsNaN(got) || math.IsInf(got, 0) {
+		return false
+	}
+	diff := math.Abs(got - expected)
+	return diff <= c.AbsEpsilon || diff <= c.RelEpsilon*math.Abs(expected)
+}
+
+func yesNoEqual(pTok, cTok string) bool {
+	if !strings.EqualFold(pTok, "yes") && !strings.EqualFold(pTok, "no") {
+		return false
+	}
+	return strings.EqualFold(pTok, cTok)
+}
+
+func compareExact(pOut, cOut io.Reader) (bool, error) {
+	pBuf, cBuf := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		pn, pErr := io.ReadFull(pOut, pBuf)
+		cn, cErr := io.ReadFull(cOut, cBuf)
+		if !bytes.Equal(pBuf[:pn], cBuf[:cn]) {
+			return false, nil
+		}
+		pEOF, cEOF := isEOF(pErr), isEOF(cErr)
+		if pErr != nil && !pEOF {
+			return false, pErr
+		}
+		if cErr != nil && !cEOF {
+			return false, cErr
+		}
+		if pEOF || cEOF {
+			return pEOF && cEOF, nil
+		}
+	}
+}
+
+func isEOF(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+func compareTokens(pOut, cOut io.Reader, equal func(pTok, cTok string) bool) (bool, error) {
+	pScanner, cScanner := newTokenScanner(pOut, bufio.ScanWords), newTokenScanner(cOut, bufio.ScanWords)
+	for {
+		pMore, cMore := pScanner.Scan(), cScanner.Scan()
+		if !pMore || !cMore {
+			if err := cScanner.Err(); err != nil {
+				return false, err
+			}
+			// An error on the contestant's side (such as a huge token) is just a wrong answer
+			return pMore == cMore && pScanner.Err() == nil, nil
+		}
+		if !equal(pScanner.Text(), cScanner.Text()) {
+			return false, nil
+		}
+	}
+}
+
+// compareUnorderedLines checks if the outputs have the same multiset of non-empty lines, ignoring whitespace differences inside lines
+func compareUnorderedLines(pOut, cOut io.Reader) (bool, error) {
+	pLines, err := readNormalizedLines(pOut)
+	if err != nil {
+		return false, nil
+	}
+	cLines, err := readNormalizedLines(cOut)
+	if err != nil {
+		return false, err
+	}
+	slices.Sort(pLines)
+	slices.Sort(cLines)
+	return slices.Equal(pLines, cLines), nil
+}
+
+func readNormalizedLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := newTokenScanner(r, bufio.ScanLines)
+	for scanner.Scan() {
+		if line := strings.Join(strings.Fields(scanner.Text()), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func newTokenScanner(r io.Reader, split bufio.SplitFunc) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTokenSize)
+	scanner.Split(split)
+	return scanner
+}
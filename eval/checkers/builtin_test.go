@@ -0,0 +1,87 @@
+package checkers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+type builtinCheckerTest struct {
+	Type     kilonova.BuiltinCheckerType
+	Output   string
+	Expected string
+	Verdict  string
+}
+
+var builtinCheckerExamples = map[string]builtinCheckerTest{
+	"exactSame":            {Type: kilonova.BuiltinCheckerExact, Output: "1 2\n3\n", Expected: "1 2\n3\n", Verdict: CorrectOut},
+	"exactNoNewline":       {Type: kilonova.BuiltinCheckerExact, Output: "1 2\n3", Expected: "1 2\n3\n", Verdict: WrongOut},
+	"exactSpaces":          {Type: kilonova.BuiltinCheckerExact, Output: "1  2\n3\n", Expected: "1 2\n3\n", Verdict: WrongOut},
+	"exactEmpty":           {Type: kilonova.BuiltinCheckerExact, Output: "", Expected: "", Verdict: CorrectOut},
+	"exactMissing":         {Type: kilonova.BuiltinCheckerExact, Output: "", Expected: "1\n", Verdict: WrongOut},
+	"exactLong":            {Type: kilonova.BuiltinCheckerExact, Output: strings.Repeat("ab", 50000), Expected: strings.Repeat("ab", 50000), Verdict: CorrectOut},
+	"exactLongDiff":        {Type: kilonova.BuiltinCheckerExact, Output: strings.Repeat("ab", 50000) + "c", Expected: strings.Repeat("ab", 50000), Verdict: WrongOut},
+	"tokensWhitespace":     {Type: kilonova.BuiltinCheckerTokens, Output: "1   2\r\n\n3", Expected: "1 2\n3\n", Verdict: CorrectOut},
+	"tokensExtra":          {Type: kilonova.BuiltinCheckerTokens, Output: "1 2 3 4\n", Expected: "1 2 3\n", Verdict: WrongOut},
+	"tokensMissing":        {Type: kilonova.BuiltinCheckerTokens, Output: "1 2\n", Expected: "1 2 3\n", Verdict: WrongOut},
+	"tokensEmpty":          {Type: kilonova.BuiltinCheckerTokens, Output: "\n", Expected: "", Verdict: CorrectOut},
+	"tokensEmptyOutput":    {Type: kilonova.BuiltinCheckerTokens, Output: "", Expected: "0\n", Verdict: WrongOut},
+	"tokensCase":           {Type: kilonova.BuiltinCheckerTokens, Output: "Yes\n", Expected: "yes\n", Verdict: WrongOut},
+	"tokensCI":             {Type: kilonova.BuiltinCheckerTokensCI, Output: "HeLLo  World", Expected: "hello world\n", Verdict: CorrectOut},
+	"tokensCIDiff":         {Type: kilonova.BuiltinCheckerTokensCI, Output: "hello word", Expected: "hello world\n", Verdict: WrongOut},
+	"floatExact":           {Type: kilonova.BuiltinCheckerFloat, Output: "0.5 abc\n", Expected: "0.5 abc\n", Verdict: CorrectOut},
+	"floatAbs":             {Type: kilonova.BuiltinCheckerFloat, Output: "0.1000005\n", Expected: "0.1\n", Verdict: CorrectOut},
+	"floatRel":             {Type: kilonova.BuiltinCheckerFloat, Output: "1000000.5\n", Expected: "1000000\n", Verdict: CorrectOut},
+	"floatFar":             {Type: kilonova.BuiltinCheckerFloat, Output: "0.11\n", Expected: "0.1\n", Verdict: WrongOut},
+	"floatNotation":        {Type: kilonova.BuiltinCheckerFloat, Output: "1e-3\n", Expected: "0.001\n", Verdict: CorrectOut},
+	"floatWords":           {Type: kilonova.BuiltinCheckerFloat, Output: "Case 1: 2.0", Expected: "Case 1: 2", Verdict: CorrectOut},
+	"floatWordsDiff":       {Type: kilonova.BuiltinCheckerFloat, Output: "case 1: 2", Expected: "Case 1: 2", Verdict: WrongOut},
+	"floatNotNumber":       {Type: kilonova.BuiltinCheckerFloat, Output: "abc\n", Expected: "1\n", Verdict: WrongOut},
+	"floatNaN":             {Type: kilonova.BuiltinCheckerFloat, Output: "nan\n", Expected: "1\n", Verdict: WrongOut},
+	"floatExpectedNaN":     {Type: kilonova.BuiltinCheckerFloat, Output: "nan\n", Expected: "nan\n", Verdict: CorrectOut},
+	"floatExpectedNaNDiff": {Type: kilonova.BuiltinCheckerFloat, Output: "0\n", Expected: "nan\n", Verdict: WrongOut},
+	"floatInf":             {Type: kilonova.BuiltinCheckerFloat, Output: "inf\n", Expected: "1e308\n", Verdict: WrongOut},
+	"floatExpectedInf":     {Type: kilonova.BuiltinCheckerFloat, Output: "inf\n", Expected: "inf\n", Verdict: CorrectOut},
+	"floatExpectedInfDiff": {Type: kilonova.BuiltinCheckerFloat, Output: "1e308\n", Expected: "+Inf\n", Verdict: WrongOut},
+	"floatEmpty":           {Type: kilonova.BuiltinCheckerFloat, Output: "", Expected: "", Verdict: CorrectOut},
+	"unorderedLines":       {Type: kilonova.BuiltinCheckerUnorderedLines, Output: "3 4\n1   2\n", Expected: "1 2\n3 4\n", Verdict: CorrectOut},
+	"unorderedBlankLines":  {Type: kilonova.BuiltinCheckerUnorderedLines, Output: "\n1 2\n\n3 4", Expected: "1 2\n3 4\n", Verdict: CorrectOut},
+	"unorderedDuplicates":  {Type: kilonova.BuiltinCheckerUnorderedLines, Output: "1 2\n1 2\n3 4\n", Expected: "1 2\n3 4\n3 4\n", Verdict: WrongOut},
+	"unorderedSplitLine":   {Type: kilonova.BuiltinCheckerUnorderedLines, Output: "1\n2\n", Expected: "1 2\n", Verdict: WrongOut},
+	"unorderedEmpty":       {Type: kilonova.BuiltinCheckerUnorderedLines, Output: "", Expected: "\n", Verdict: CorrectOut},
+	"yesNo":                {Type: kilonova.BuiltinCheckerYesNo, Output: "Yes\nNO\n", Expected: "YES\nno", Verdict: CorrectOut},
+	"yesNoWrong":           {Type: kilonova.BuiltinCheckerYesNo, Output: "no\n", Expected: "yes\n", Verdict: WrongOut},
+	"yesNoOther":           {Type: kilonova.BuiltinCheckerYesNo, Output: "maybe\n", Expected: "maybe\n", Verdict: WrongOut},
+	"yesNoEmpty":           {Type: kilonova.BuiltinCheckerYesNo, Output: "", Expected: "yes\n", Verdict: WrongOut},
+	"unknownType":          {Type: "unknown", Output: "1\n", Expected: "1\n", Verdict: ErrOut},
+}
+
+func TestBuiltinChecker(t *testing.T) {
+	for name, tc := range builtinCheckerExamples {
+		t.Run(name, func(t *testing.T) {
+			checker := &BuiltinChecker{Type: tc.Type, AbsEpsilon: 1e-6, RelEpsilon: 1e-6}
+			verdict, score := checker.RunChecker(context.Background(), strings.NewReader(tc.Output), strings.NewReader(""), strings.NewReader(tc.Expected))
+			if verdict != tc.Verdict {
+				t.Fatalf("Expected verdict %q, got %q", tc.Verdict, verdict)
+			}
+			expectedScore := decimal.Zero
+			if verdict == CorrectOut {
+				expectedScore = decimal.NewFromInt(100)
+			}
+			if !score.Equal(expectedScore) {
+				t.Fatalf("Expected score %s, got %s", expectedScore, score)
+			}
+		})
+	}
+}
+
+func TestBuiltinCheckerHugeToken(t *testing.T) {
+	checker := &BuiltinChecker{Type: kilonova.BuiltinCheckerTokens}
+	huge := strings.Repeat("a", maxTokenSize+1)
+	if verdict, _ := checker.RunChecker(context.Background(), strings.NewReader(huge), strings.NewReader(""), strings.NewReader("a\n")); verdict != WrongOut {
+		t.Fatalf("Expected a huge contestant token to be a wrong answer, got %q", verdict)
+	}
+}
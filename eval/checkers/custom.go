@@ -30,6 +30,11 @@ var _ Checker = &customChecker{}
 //go:embed checkerdata/testlib.h
 var testlibFile []byte
 
+// TestlibHeader returns the testlib.h file bundled with the checkers, for other problem helpers (such as interactors) that need it
+func TestlibHeader() []byte {
+	return testlibFile
+}
+
 type customCheckerInput struct {
 	c    *customChecker
 	pOut io.Reader
@@ -55,9 +60,17 @@ type customChecker struct {
 
 	Logger *zap.SugaredLogger
 
-	legacy bool
+	kind checkerKind
 }
 
+type checkerKind int
+
+const (
+	checkerKindStandard checkerKind = iota
+	checkerKindLegacy
+	checkerKindTestlib
+)
+
 // Prepare compiles the checker for the submission
 func (c *customChecker) Prepare(ctx context.Context) (string, error) {
 	var shouldCompile bool
@@ -81,10 +94,15 @@ func (c *customChecker) Prepare(ctx context.Context) (string, error) {
 	checkerPrepareMu.Lock()
 	defer checkerPrepareMu.Unlock()
 
+	code := c.code
+	if c.kind == checkerKindTestlib {
+		code = testlibCheckerSource(c.filename, code)
+	}
+
 	resp, err := tasks.GetCompileTask(c.Logger).Run(ctx, c.mgr, 0, &tasks.CompileRequest{
 		ID: -c.pb.ID,
 		CodeFiles: map[string][]byte{
-			eval.Langs[eval.GetLangByFilename(c.filename)].SourceName: c.code,
+			eval.Langs()[eval.GetLangByFilename(c.filename)].SourceName: code,
 		}, HeaderFiles: map[string][]byte{
 			"/box/testlib.h": testlibFile,
 		},
@@ -109,8 +127,11 @@ func (c *customChecker) RunChecker(ctx context.Context, pOut, cIn, cOut io.Reade
 	var out checkerResult
 
 	var task eval.Task[customCheckerInput, checkerResult] = standardCheckerTask
-	if c.legacy {
+	switch c.kind {
+	case checkerKindLegacy:
 		task = legacyCheckerTask
+	case checkerKindTestlib:
+		task = testlibCheckerTask
 	}
 
 	resp, err := task.Run(ctx, c.mgr, checkerMemoryLimit, &customCheckerInput{
@@ -134,9 +155,15 @@ func (c *customChecker) Cleanup(_ context.Context) error {
 }
 
 func NewLegacyCustomChecker(mgr eval.BoxScheduler, logger *zap.SugaredLogger, pb *kilonova.Problem, filename string, code []byte, subCode []byte, lastUpdatedAt time.Time) Checker {
-	return &customChecker{mgr, pb, filename, code, subCode, lastUpdatedAt, logger, true}
+	return &customChecker{mgr, pb, filename, code, subCode, lastUpdatedAt, logger, checkerKindLegacy}
 }
 
 func NewStandardCustomChecker(mgr eval.BoxScheduler, logger *zap.SugaredLogger, pb *kilonova.Problem, filename string, code []byte, subCode []byte, lastUpdatedAt time.Time) Checker {
-	return &customChecker{mgr, pb, filename, code, subCode, lastUpdatedAt, logger, false}
+	return &customChecker{mgr, pb, filename, code, subCode, lastUpdatedAt, logger, checkerKindStandard}
+}
+
+// NewTestlibCustomChecker returns a checker that follows the upstream testlib conventions (such as the ones in Polygon packages):
+// it's called as `checker <input> <output> <answer> <result file>` and reports the verdict through its exit code and the result file.
+func NewTestlibCustomChecker(mgr eval.BoxScheduler, logger *zap.SugaredLogger, pb *kilonova.Problem, filename string, code []byte, subCode []byte, lastUpdatedAt time.Time) Checker {
+	return &customChecker{mgr, pb, filename, code, subCode, lastUpdatedAt, logger, checkerKindTestlib}
 }
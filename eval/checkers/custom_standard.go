@@ -17,7 +17,7 @@ import (
 
 func standardCheckerTask(ctx context.Context, box eval.Sandbox, job *customCheckerInput) (*checkerResult, error) {
 	rez := &checkerResult{}
-	lang, ok := eval.Langs[eval.GetLangByFilename(job.c.filename)]
+	lang, ok := eval.Langs()[eval.GetLangByFilename(job.c.filename)]
 	if !ok {
 		rez.Output = ErrOut
 		return rez, nil
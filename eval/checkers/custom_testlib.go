@@ -0,0 +1,183 @@
+package checkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Exit codes of upstream testlib checkers
+const (
+	testlibExitOK            = 0
+	testlibExitWA            = 1
+	testlibExitPE            = 2
+	testlibExitFail          = 3
+	testlibExitDirt          = 4
+	testlibExitPoints        = 7
+	testlibExitUnexpectedEOF = 8
+)
+
+// testlibResult is the result file written by testlib checkers in appes mode
+type testlibResult struct {
+	Outcome string `xml:"outcome,attr"`
+	Points  string `xml:"points,attr"`
+	PCType  string `xml:"pctype,attr"`
+	Message string `xml:",chardata"`
+}
+
+// testlibCheckerSource disables the Kilonova-specific behavior of the bundled testlib.h (enabled through -DKNOVA in the compile commands),
+// so the checker behaves exactly like it would on Polygon
+func testlibCheckerSource(filename string, code []byte) []byte {
+	lang := eval.GetLangByFilename(filename)
+	if lang != "c" && !strings.HasPrefix(lang, "cpp") {
+		return code
+	}
+	return append([]byte("#undef KNOVA\n"), code...)
+}
+
+func testlibCheckerTask(ctx context.Context, box eval.Sandbox, job *customCheckerInput) (*checkerResult, error) {
+	rez := &checkerResult{}
+	lang, ok := eval.Langs()[eval.GetLangByFilename(job.c.filename)]
+	if !ok {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+
+	if err := box.WriteFile("/box/program.out", job.pOut, 0644); err != nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+	if err := box.WriteFile("/box/correct.in", job.cIn, 0644); err != nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+	if err := box.WriteFile("/box/correct.out", job.cOut, 0644); err != nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+	if err := eval.CopyInBox(box, datastore.GetBucket(datastore.BucketTypeCheckers), fmt.Sprintf("%d.bin", job.c.pb.ID), lang.CompiledName); err != nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+
+	goodCmd, err := eval.MakeGoodCommand(lang.RunCommand)
+	if err != nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+	// -appes makes testlib write the outcome in the result file as well, since partial verdicts can't be told apart by exit code alone
+	goodCmd = append(goodCmd, "/box/correct.in", "/box/program.out", "/box/correct.out", "/box/checker_result.xml", "-appes")
+
+	conf := &eval.RunConfig{
+		OutputPath: "/box/checker_verdict.out",
+		StderrPath: "/box/checker_verdict.err",
+
+		MemoryLimit: checkerMemoryLimit,
+
+		WallTimeLimit: 20,
+	}
+
+	stats, err := box.RunCommand(ctx, goodCmd, conf)
+	if err != nil || stats == nil {
+		rez.Output = ErrOut
+		return rez, nil
+	}
+
+	var out bytes.Buffer
+	if err := box.ReadFile("/box/checker_result.xml", &out); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			zap.S().Warn("Couldn't read checker result file: ", err)
+		}
+		out.Reset()
+	}
+
+	rez.Percentage, rez.Output = testlibVerdict(stats.ExitCode, out.Bytes())
+	return rez, nil
+}
+
+// testlibVerdict maps the exit code and result file of a testlib checker to a percentage and a message.
+// If the result file is missing or malformed, the verdict is based only on the exit code
+func testlibVerdict(exitCode int, resultFile []byte) (decimal.Decimal, string) {
+	var res testlibResult
+	dec := xml.NewDecoder(bytes.NewReader(resultFile))
+	// testlib declares the file as windows-1251, but messages are escaped to printable characters anyway
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&res); err != nil {
+		res = testlibResult{}
+		switch exitCode {
+		case testlibExitOK:
+			res.Outcome = "accepted"
+		case testlibExitWA:
+			res.Outcome = "wrong-answer"
+		case testlibExitPE, testlibExitDirt:
+			res.Outcome = "presentation-error"
+		case testlibExitUnexpectedEOF:
+			res.Outcome = "unexpected-eof"
+		default:
+			// Includes testlibExitPoints, since points can't be read without the result file
+			res.Outcome = "fail"
+		}
+	}
+	message := strings.TrimSpace(res.Message)
+
+	switch res.Outcome {
+	case "accepted":
+		if message == "" {
+			message = CorrectOut
+		}
+		return decimal.NewFromInt(100), message
+	case "wrong-answer", "presentation-error", "unexpected-eof":
+		if message == "" {
+			message = WrongOut
+		}
+		return decimal.Zero, message
+	case "points", "relative-scoring":
+		// The message starts with the points, which are a number in the [0, 1] range, like for standard checkers
+		points, err := decimal.NewFromString(res.Points)
+		if err != nil {
+			return decimal.Zero, ErrOut
+		}
+		if points.IsNegative() || points.GreaterThan(decimal.NewFromInt(1)) {
+			zap.S().Warnf("Testlib checker returned points outside of the [0, 1] range: %s", res.Points)
+			return decimal.Zero, ErrOut
+		}
+		message = strings.TrimSpace(strings.TrimPrefix(message, res.Points))
+		return partialVerdict(points.Shift(2), message)
+	case "partially-correct":
+		// Same scale as the partial verdicts of the bundled testlib.h
+		pctype, err := strconv.Atoi(res.PCType)
+		if err != nil {
+			return decimal.Zero, ErrOut
+		}
+		return partialVerdict(decimal.NewFromInt(int64(pctype)).Div(decimal.NewFromInt(2)), message)
+	default:
+		zap.S().Warnf("Testlib checker failed (exit code %d): %q", exitCode, message)
+		return decimal.Zero, ErrOut
+	}
+}
+
+func partialVerdict(percentage decimal.Decimal, message string) (decimal.Decimal, string) {
+	percentage = decimal.Max(decimal.Zero, decimal.Min(percentage, decimal.NewFromInt(100)))
+	if message == "" {
+		switch {
+		case percentage.Equal(decimal.NewFromInt(100)):
+			message = CorrectOut
+		case percentage.IsZero():
+			message = WrongOut
+		default:
+			message = PartialOut
+		}
+	}
+	return percentage, message
+}
@@ -0,0 +1,58 @@
+package checkers
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type testlibVerdictTest struct {
+	ExitCode   int
+	ResultFile string
+	Percentage int64
+	Message    string
+}
+
+const testlibResultHeader = `<?xml version="1.0" encoding="windows-1251"?>`
+
+var testlibVerdictExamples = map[string]testlibVerdictTest{
+	"ok":                {ExitCode: testlibExitOK, ResultFile: testlibResultHeader + `<result outcome="accepted">ok 3 numbers</result>`, Percentage: 100, Message: "ok 3 numbers"},
+	"okEmpty":           {ExitCode: testlibExitOK, ResultFile: testlibResultHeader + `<result outcome="accepted"></result>`, Percentage: 100, Message: CorrectOut},
+	"wa":                {ExitCode: testlibExitWA, ResultFile: testlibResultHeader + `<result outcome="wrong-answer">wrong answer expected 3, found 4</result>`, Percentage: 0, Message: "wrong answer expected 3, found 4"},
+	"pe":                {ExitCode: testlibExitPE, ResultFile: testlibResultHeader + `<result outcome="presentation-error">wrong output format</result>`, Percentage: 0, Message: "wrong output format"},
+	"unexpectedEOF":     {ExitCode: testlibExitUnexpectedEOF, ResultFile: testlibResultHeader + `<result outcome="unexpected-eof">unexpected eof</result>`, Percentage: 0, Message: "unexpected eof"},
+	"fail":              {ExitCode: testlibExitFail, ResultFile: testlibResultHeader + `<result outcome="fail">bad test</result>`, Percentage: 0, Message: ErrOut},
+	"points":            {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="0.25">0.25 partial answer</result>`, Percentage: 25, Message: "partial answer"},
+	"pointsFull":        {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="1">1</result>`, Percentage: 100, Message: CorrectOut},
+	"pointsZero":        {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="0">0</result>`, Percentage: 0, Message: WrongOut},
+	"pointsTooHigh":     {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="1.5">1.5</result>`, Percentage: 0, Message: ErrOut},
+	"pointsNegative":    {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="-0.5">-0.5</result>`, Percentage: 0, Message: ErrOut},
+	"pointsInvalid":     {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="points" points="abc">abc</result>`, Percentage: 0, Message: ErrOut},
+	"relativeScoring":   {ExitCode: testlibExitPoints, ResultFile: testlibResultHeader + `<result outcome="relative-scoring" points="0.5">0.5 half</result>`, Percentage: 50, Message: "half"},
+	"partiallyCorrect":  {ExitCode: 16 + 50, ResultFile: testlibResultHeader + `<result outcome="partially-correct" pctype="50">partially correct</result>`, Percentage: 25, Message: "partially correct"},
+	"noFileOK":          {ExitCode: testlibExitOK, Percentage: 100, Message: CorrectOut},
+	"noFileWA":          {ExitCode: testlibExitWA, Percentage: 0, Message: WrongOut},
+	"noFilePE":          {ExitCode: testlibExitPE, Percentage: 0, Message: WrongOut},
+	"noFileDirt":        {ExitCode: testlibExitDirt, Percentage: 0, Message: WrongOut},
+	"noFileFail":        {ExitCode: testlibExitFail, Percentage: 0, Message: ErrOut},
+	"noFilePoints":      {ExitCode: testlibExitPoints, Percentage: 0, Message: ErrOut},
+	"noFileCrash":       {ExitCode: 139, Percentage: 0, Message: ErrOut},
+	"malformedFile":     {ExitCode: testlibExitWA, ResultFile: "<result outcome=", Percentage: 0, Message: WrongOut},
+	"unknownOutcome":    {ExitCode: testlibExitOK, ResultFile: testlibResultHeader + `<result outcome="something">text</result>`, Percentage: 0, Message: ErrOut},
+	"escapedMessage":    {ExitCode: testlibExitWA, ResultFile: testlibResultHeader + `<result outcome="wrong-answer">expected &quot;a&quot; &lt; b</result>`, Percentage: 0, Message: `expected "a" < b`},
+	"trimmedWhitespace": {ExitCode: testlibExitOK, ResultFile: testlibResultHeader + "<result outcome=\"accepted\">\n  ok  \n</result>", Percentage: 100, Message: "ok"},
+}
+
+func TestTestlibVerdict(t *testing.T) {
+	for name, tc := range testlibVerdictExamples {
+		t.Run(name, func(t *testing.T) {
+			percentage, message := testlibVerdict(tc.ExitCode, []byte(tc.ResultFile))
+			if !percentage.Equal(decimal.NewFromInt(tc.Percentage)) {
+				t.Fatalf("Expected percentage %d, got %s", tc.Percentage, percentage)
+			}
+			if message != tc.Message {
+				t.Fatalf("Expected message %q, got %q", tc.Message, message)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ const (
 	ErrOut     = "translate:internal_error"
 	CorrectOut = "translate:success"
 	WrongOut   = "translate:wrong"
+	PartialOut = "translate:partial"
 )
 
 var _ Checker = &DiffChecker{}
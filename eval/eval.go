@@ -75,8 +75,15 @@ type RunStats struct {
 	Message string `json:"message"`
 	Status  string `json:"status"`
 
-	Time float64 `json:"time"`
+	Time     float64 `json:"time"`
+	WallTime float64 `json:"wall_time"`
+
+	// MaxRSS is the maximum resident set size of the process, in kilobytes. Memory is the peak memory usage of the whole control group
+	MaxRSS    int  `json:"max_rss"`
+	OOMKilled bool `json:"oom_killed"`
+
+	VoluntaryCSW int `json:"csw_voluntary"`
+	ForcedCSW    int `json:"csw_forced"`
 
 	InternalMessage string `json:"internal_msg"`
-	// WallTime float64 `json:"wall_time"`
 }
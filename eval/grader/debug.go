@@ -0,0 +1,119 @@
+package grader
+
+import (
+	"context"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/checkers"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	// Sanitized programs are a few times slower and need more memory, for the shadow memory and redzones
+	debugTimeMultiplier   = 3
+	debugMemoryMultiplier = 2
+	debugMemoryOverhead   = 64 * 1024 // KB
+
+	// The output of debug runs is kept in memory for the checker, a longer output is wrong anyway
+	debugOutputLimit = 16 * 1024 * 1024 // bytes
+)
+
+// DebugRun compiles the code with the debug profile of its language and runs it on the given tests of the problem, with relaxed limits.
+// Nothing is saved, the compiled binary and the outputs are removed afterwards.
+// Debug runs always run locally, on a box reserved from the grader scheduler
+func (h *Handler) DebugRun(ctx context.Context, problem *kilonova.Problem, tests []*kilonova.Test, code []byte, lang string) (*kilonova.DebugRun, error) {
+	graderLogger.Infof("Starting debug run for problem %d", problem.ID)
+	prepared, release, err := h.startRun(ctx, problem, code, lang, eval.ProfileDebug)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	run := &kilonova.DebugRun{CompileError: !prepared.compile.Success, CompileMessage: prepared.compile.Output, Tests: []*kilonova.DebugRunTest{}}
+	if !prepared.compile.Success {
+		return run, nil
+	}
+
+	checker, err1 := getAppropriateChecker(ctx, h.base, prepared.runner, code, problem, prepared.settings)
+	if err1 != nil {
+		return nil, kilonova.WrapError(err1, "Couldn't get checker")
+	}
+	if _, err := checker.Prepare(ctx); err != nil {
+		return nil, kilonova.WrapError(err, "Could not prepare checker")
+	}
+	defer func() {
+		if err := checker.Cleanup(ctx); err != nil {
+			zap.S().Warn("Couldn't remove checker artifact: ", err)
+		}
+	}()
+
+	for _, test := range tests {
+		result, err := h.debugRunTest(ctx, prepared.runner, checker, problem, lang, prepared.binaryName, test)
+		if err != nil {
+			return nil, err
+		}
+		run.Tests = append(run.Tests, result)
+	}
+	return run, nil
+}
+
+func (h *Handler) debugRunTest(ctx context.Context, runner eval.BoxScheduler, checker checkers.Checker, problem *kilonova.Problem, lang string, binaryName string, test *kilonova.Test) (*kilonova.DebugRunTest, error) {
+	tin, err := h.base.TestInput(test.ID)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't open test input")
+	}
+	defer tin.Close()
+
+	output := &limitedBuffer{limit: debugOutputLimit}
+	execRequest := &tasks.ExecRequest{
+		Filename:    problem.TestName,
+		MemoryLimit: problem.MemoryLimit*debugMemoryMultiplier + debugMemoryOverhead,
+		TimeLimit:   problem.TimeLimit * debugTimeMultiplier,
+		Lang:        lang,
+		TestInput:   tin,
+
+		LanguageLimits: problem.LanguageLimits[lang],
+
+		BinaryName:    binaryName,
+		Profile:       eval.ProfileDebug,
+		CaptureStderr: true,
+		Output:        output,
+	}
+	if problem.ConsoleInput {
+		execRequest.Filename = "stdin"
+	}
+	_, memoryLimit := execRequest.Limits()
+	resp, err := tasks.GetExecuteTask(graderLogger).Run(ctx, runner, int64(memoryLimit), execRequest)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't execute test")
+	}
+
+	result := &kilonova.DebugRunTest{
+		VisibleID:  test.VisibleID,
+		Verdict:    resp.Comments,
+		Time:       resp.Time,
+		Memory:     resp.Memory,
+		Stderr:     resp.Stderr,
+		Percentage: decimal.Zero,
+	}
+	if resp.Comments != "" {
+		return result, nil
+	}
+
+	tin2, err := h.base.TestInput(test.ID)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't open test input")
+	}
+	defer tin2.Close()
+	tout, err := h.base.TestOutput(test.ID)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't open test output")
+	}
+	defer tout.Close()
+
+	result.Verdict, result.Percentage = checker.RunChecker(ctx, &output.buf, tin2, tout)
+	return result, nil
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/remote"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/sudoapi"
 	"go.uber.org/zap"
@@ -16,8 +18,9 @@ import (
 )
 
 var (
-	waitingSubs   = kilonova.SubmissionFilter{Status: kilonova.StatusWaiting, Ascending: true, Limit: 41}
-	reevalingSubs = kilonova.SubmissionFilter{Status: kilonova.StatusReevaling, Ascending: true, Limit: 6}
+	// Only the oldest submissions are considered when building the queue
+	waitingSubs   = kilonova.SubmissionFilter{Status: kilonova.StatusWaiting, Ascending: true, Limit: 500}
+	reevalingSubs = kilonova.SubmissionFilter{Status: kilonova.StatusReevaling, Ascending: true, Limit: 500}
 	workingUpdate = kilonova.SubmissionUpdate{Status: kilonova.StatusWorking}
 
 	// If future me is running multiple grader handlers
@@ -34,6 +37,15 @@ type Handler struct {
 	base  *sudoapi.BaseAPI
 
 	wakeChan chan struct{}
+	queue    *subQueue
+
+	// runner is set while the grader is running, it's also used for debug runs and custom invocations. Use currentRunner to read it
+	runnerMu sync.RWMutex
+	runner   eval.BoxScheduler
+	// activeRuns is the number of debug runs and custom invocations in progress, over all users
+	activeRuns atomic.Int64
+
+	evalTimes evalTimeTracker
 }
 
 func NewHandler(ctx context.Context, base *sudoapi.BaseAPI) (*Handler, *kilonova.StatusError) {
@@ -49,10 +61,23 @@ func NewHandler(ctx context.Context, base *sudoapi.BaseAPI) (*Handler, *kilonova
 		graderLogger = zap.New(kilonova.GetZapCore(config.Common.Debug, false, logFile), zap.AddCaller()).Sugar()
 	})
 
-	return &Handler{ctx, ch, base, wCh}, nil
+	return &Handler{ctx: ctx, sChan: ch, base: base, wakeChan: wCh, queue: newSubQueue()}, nil
+}
+
+// UseRemoteWorkers makes the grader hand out compile and execute jobs to the workers connected to the dispatcher
+func (h *Handler) UseRemoteWorkers(d *remote.Dispatcher) {
+	remoteWorkers = d
 }
 
+// Wake makes the grader reload its queue and schedule the queued submissions.
+// It must be called whenever submissions are created or reset
 func (h *Handler) Wake() {
+	h.queue.markStale()
+	h.wake()
+}
+
+// wake makes the grader schedule the queued submissions, without reloading the queue
+func (h *Handler) wake() {
 	select {
 	case h.wakeChan <- struct{}{}:
 	default:
@@ -69,7 +94,7 @@ func (h *Handler) ScheduleSubmission(runner eval.BoxScheduler, sub *kilonova.Sub
 			subRunner = r
 		}
 	} else {
-		r, err := runner.SubRunner(h.ctx, 1)
+		r, err := runner.SubRunner(h.ctx, h.minimumBoxes(runner, sub))
 		if err != nil {
 			return err
 		} else {
@@ -77,17 +102,45 @@ func (h *Handler) ScheduleSubmission(runner eval.BoxScheduler, sub *kilonova.Sub
 		}
 	}
 	if err := h.base.UpdateSubmission(h.ctx, sub.ID, workingUpdate); err != nil {
+		subRunner.Close(h.ctx)
 		return err
 	}
+	h.queue.started(sub)
 	go func(sub *kilonova.Submission, r eval.BoxScheduler) {
+		defer h.queue.finished(sub.UserID)
 		defer r.Close(h.ctx)
+		start := time.Now()
 		if err := executeSubmission(h.ctx, h.base, r, sub); err != nil {
 			zap.S().Warn("Couldn't run submission: ", err)
+			return
 		}
+		h.evalTimes.add(time.Since(start))
 	}(sub, subRunner)
 	return nil
 }
 
+// minimumBoxes returns the number of boxes that must be available at once to evaluate the submission.
+// Interactive problems need an extra box for the interactor, communication problems one for the manager and one for every process
+func (h *Handler) minimumBoxes(runner eval.BoxScheduler, sub *kilonova.Submission) int64 {
+	settings, err := h.base.ProblemSettings(h.ctx, sub.ProblemID)
+	if err != nil {
+		zap.S().Warn("Couldn't get problem settings: ", err)
+		return 1
+	}
+	if settings.ManagerName != "" {
+		pb, err := h.base.Problem(h.ctx, sub.ProblemID)
+		if err != nil {
+			zap.S().Warn("Couldn't get problem: ", err)
+			return 1
+		}
+		return min(int64(pb.NumProcesses+1), runner.NumConcurrent())
+	}
+	if settings.InteractorName != "" {
+		return min(2, runner.NumConcurrent())
+	}
+	return 1
+}
+
 func (h *Handler) handle(runner eval.BoxScheduler) error {
 	for {
 		select {
@@ -100,53 +153,44 @@ func (h *Handler) handle(runner eval.BoxScheduler) error {
 			if !more {
 				return nil
 			}
-			var rewake bool
+			h.scheduleQueue(runner)
+		}
+	}
+}
 
-			subs, err := h.base.RawSubmissions(h.ctx, waitingSubs)
-			if err != nil {
-				zap.S().Warn(err)
-			} else if len(subs) > 0 {
-				graderLogger.Infof("Found %d submissions", len(subs))
-				if len(subs) > 40 {
-					subs = subs[:40]
-					rewake = true
-				}
-				for _, sub := range subs {
-					if err := h.ScheduleSubmission(runner, sub); err != nil {
-						zap.S().Warn(err)
-					}
-				}
-			}
+// scheduleQueue schedules the queued submissions one by one, in priority order.
+// Since scheduling blocks until enough boxes are available, the head of the queue is taken every time, so newer higher priority submissions get ahead
+func (h *Handler) scheduleQueue(runner eval.BoxScheduler) {
+	for h.ctx.Err() == nil {
+		qSub, err := h.popQueue(h.ctx)
+		if err != nil {
+			zap.S().Warn(err)
+			return
+		}
+		if qSub == nil {
+			return
+		}
+		graderLogger.Infof("Scheduling submission #%d", qSub.sub.ID)
 
-			reevalQueue, err := h.base.RawSubmissions(h.ctx, reevalingSubs)
-			if err != nil {
-				zap.S().Warn(err)
-			} else if len(reevalQueue) > 0 {
-				graderLogger.Infof("Found %d submissions for reevaluation", len(reevalQueue))
-				if len(reevalQueue) > 5 {
-					reevalQueue = reevalQueue[:5]
-					rewake = true
-				}
-				for _, sub := range reevalQueue {
-					if err := h.base.ResetSubmission(h.ctx, sub.ID); err != nil {
-						zap.S().Warn("Couldn't reset submission: ", err)
-						continue
-					}
-					sub2, err := h.base.RawSubmission(h.ctx, sub.ID)
-					if err != nil {
-						zap.S().Warn("Error refetching submission for reeval: ", err)
-						sub2 = sub
-					}
-					if err := h.ScheduleSubmission(runner, sub2); err != nil {
-						zap.S().Warn(err)
-					}
-				}
+		sub := qSub.sub
+		if qSub.priority == kilonova.QueuePriorityReevaluation {
+			if err := h.base.PrepareReevaluation(h.ctx, sub.ID); err != nil {
+				h.queue.requeue(sub.ID)
+				zap.S().Warn("Couldn't reset submission: ", err)
+				return
 			}
-
-			if rewake {
-				// Try to instantly continue working on the queue
-				h.Wake()
+			sub2, err := h.base.RawSubmission(h.ctx, sub.ID)
+			if err != nil {
+				zap.S().Warn("Error refetching submission for reeval: ", err)
+				sub2 = sub
 			}
+			sub = sub2
+		}
+		if err := h.ScheduleSubmission(runner, sub); err != nil {
+			// The submission must be picked up again
+			h.queue.requeue(sub.ID)
+			zap.S().Warn(err)
+			return
 		}
 	}
 }
@@ -157,6 +201,7 @@ func (h *Handler) Start() error {
 		return err
 	}
 
+	h.setRunner(runner)
 	h.base.RegisterGrader(h) // To allow waking from outside grader
 
 	go func() {
@@ -164,14 +209,17 @@ func (h *Handler) Start() error {
 		for {
 			select {
 			case <-ticker.C:
-				h.Wake()
+				h.wake()
 			case <-h.ctx.Done():
 				return
 			}
 		}
 	}()
 
-	defer runner.Close(h.ctx)
+	defer func() {
+		h.setRunner(nil)
+		runner.Close(h.ctx)
+	}()
 	zap.S().Info("Connected to eval")
 
 	if err = h.handle(runner); err != nil {
@@ -181,6 +229,19 @@ func (h *Handler) Start() error {
 	return nil
 }
 
+func (h *Handler) setRunner(runner eval.BoxScheduler) {
+	h.runnerMu.Lock()
+	defer h.runnerMu.Unlock()
+	h.runner = runner
+}
+
+// currentRunner returns the box scheduler of the grader, or nil if the grader is not running
+func (h *Handler) currentRunner() eval.BoxScheduler {
+	h.runnerMu.RLock()
+	defer h.runnerMu.RUnlock()
+	return h.runner
+}
+
 func (h *Handler) Close() {
 	closeAction.Do(func() {
 		if err := logFile.Close(); err != nil {
@@ -1,17 +1,22 @@
 package grader
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"path"
 	"slices"
 	"strings"
 	"sync"
 
 	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
 	"github.com/KiloProjects/kilonova/eval"
-	"github.com/KiloProjects/kilonova/eval/box"
 	"github.com/KiloProjects/kilonova/eval/checkers"
+	"github.com/KiloProjects/kilonova/eval/remote"
 	"github.com/KiloProjects/kilonova/eval/scheduler"
 	"github.com/KiloProjects/kilonova/eval/tasks"
 	"github.com/KiloProjects/kilonova/internal/config"
@@ -22,15 +27,30 @@ import (
 )
 
 var (
+	helperPrepareMu sync.Mutex
+
+	// remoteWorkers, if not nil, runs compilations and plain executions on remote workers when they are connected.
+	// Checkers, interactors and communication managers always run locally
+	remoteWorkers *remote.Dispatcher
+
 	True            = true
 	skippedVerdict  = "translate:skipped"
 	acceptedVerdict = "test_verdict.accepted"
 )
 
 func genSubCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, pb *kilonova.Problem, settings *kilonova.ProblemEvalSettings) (*tasks.CompileRequest, *kilonova.StatusError) {
+	subCode, err := base.RawSubmissionCode(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	return genCompileRequest(ctx, base, sub.ID, sub.Language, subCode, pb, settings)
+}
+
+// genCompileRequest builds the compilation request of the given code, along with the grader and header files of the problem
+func genCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, id int, language string, subCode []byte, pb *kilonova.Problem, settings *kilonova.ProblemEvalSettings) (*tasks.CompileRequest, *kilonova.StatusError) {
 	req := &tasks.CompileRequest{
-		ID:          sub.ID,
-		Lang:        sub.Language,
+		ID:          id,
+		Lang:        language,
 		CodeFiles:   make(map[string][]byte),
 		HeaderFiles: make(map[string][]byte),
 	}
@@ -38,9 +58,10 @@ func genSubCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, sub *kilon
 	if err != nil {
 		return nil, err
 	}
+	langs := eval.Langs()
 	for _, codeFile := range settings.GraderFiles {
 		lang := eval.GetLangByFilename(codeFile)
-		if lang != sub.Language && !slices.Contains(eval.Langs[sub.Language].SimilarLangs, lang) {
+		if lang != language && !slices.Contains(langs[language].SimilarLangs, lang) {
 			continue
 		}
 		for _, att := range atts {
@@ -50,22 +71,18 @@ func genSubCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, sub *kilon
 					zap.S().Warn("Couldn't get attachment data:", err)
 					return nil, kilonova.Statusf(500, "Couldn't get grader data")
 				}
-				name := strings.Replace(path.Base(att.Name), path.Ext(att.Name), eval.Langs[lang].Extensions[0], 1)
+				name := strings.Replace(path.Base(att.Name), path.Ext(att.Name), langs[lang].Extensions[0], 1)
 				req.CodeFiles[path.Join("/box", name)] = data
 			}
 		}
 	}
-	subCode, err := base.RawSubmissionCode(ctx, sub.ID)
-	if err != nil {
-		return nil, err
-	}
-	if len(settings.GraderFiles) > 0 && sub.Language == "pascal" {
+	if len(settings.GraderFiles) > 0 && language == "pascal" {
 		// In interactive problems, include the source code as header
 		// Apparently the fpc compiler allows only one file as parameter, this should solve it
-		req.HeaderFiles[eval.Langs[sub.Language].SourceName] = subCode
+		req.HeaderFiles[langs[language].SourceName] = subCode
 	} else {
 		// But by default it should be a code file
-		req.CodeFiles[eval.Langs[sub.Language].SourceName] = subCode
+		req.CodeFiles[langs[language].SourceName] = subCode
 	}
 	for _, headerFile := range settings.HeaderFiles {
 		for _, att := range atts {
@@ -116,7 +133,12 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 		return nil
 	}
 
-	checker, err := getAppropriateChecker(ctx, base, runner, sub, problem, problemSettings)
+	subCode, err1 := base.RawSubmissionCode(ctx, sub.ID)
+	if err1 != nil {
+		return kilonova.WrapError(err1, "Couldn't get submission source code")
+	}
+
+	checker, err := getAppropriateChecker(ctx, base, runner, subCode, problem, problemSettings)
 	if err != nil {
 		return kilonova.WrapError(err, "Couldn't get checker")
 	}
@@ -135,6 +157,28 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 		return kilonova.WrapError(err, "Could not prepare checker")
 	}
 
+	for name, outName := range map[string]string{
+		problemSettings.InteractorName: tasks.InteractorFilename(problem.ID),
+		problemSettings.ManagerName:    tasks.ManagerFilename(problem.ID),
+	} {
+		if name == "" {
+			continue
+		}
+		if info, err := prepareHelper(ctx, base, runner, problem, name, outName); err != nil {
+			t := true
+			info = "Helper compile error:\n" + info
+			internalErr := "test_verdict.internal_error"
+			if err := base.UpdateSubmission(ctx, sub.ID, kilonova.SubmissionUpdate{
+				Status: kilonova.StatusFinished, Score: &problem.DefaultPoints,
+				CompileError: &t, CompileMessage: &info,
+				ChangeVerdict: true, ICPCVerdict: &internalErr,
+			}); err != nil {
+				return kilonova.WrapError(err, "Error during update of compile information")
+			}
+			return kilonova.WrapError(err, "Could not prepare problem helper")
+		}
+	}
+
 	subTests, err1 := base.SubTests(ctx, sub.ID)
 	if err1 != nil {
 		internalErr := "test_verdict.internal_error"
@@ -151,12 +195,12 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 	// It is basically 2 implementations for ~ the same thing. It could be merged neater
 	switch sub.SubmissionType {
 	case kilonova.EvalTypeClassic:
-		if err := handleClassicSubmission(ctx, base, runner, sub, problem, checker, subTests); err != nil {
+		if err := handleClassicSubmission(ctx, base, runner, sub, problem, problemSettings, checker, subTests); err != nil {
 			zap.S().Warn(err)
 			return err
 		}
 	case kilonova.EvalTypeICPC:
-		if err := handleICPCSubmission(ctx, base, runner, sub, problem, checker, subTests); err != nil {
+		if err := handleICPCSubmission(ctx, base, runner, sub, problem, problemSettings, checker, subTests); err != nil {
 			zap.S().Warn(err)
 			return err
 		}
@@ -174,7 +218,21 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 	return nil
 }
 
-func handleClassicSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
+func handleClassicSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, settings *kilonova.ProblemEvalSettings, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
+	if problem.StopOnFailure {
+		subTasks, err := base.SubmissionSubTasks(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		if len(subTasks) > 0 {
+			handleShortCircuitSubTests(ctx, base, runner, sub, problem, settings, checker, subTests, subTasks)
+			if err := scoreTests(ctx, base, sub, problem); err != nil {
+				zap.S().Warn("Couldn't score test: ", err)
+			}
+			return nil
+		}
+	}
+
 	var wg sync.WaitGroup
 
 	for _, subTest := range subTests {
@@ -183,7 +241,7 @@ func handleClassicSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner
 
 		go func() {
 			defer wg.Done()
-			_, _, err := handleSubTest(ctx, base, runner, checker, sub, problem, subTest)
+			_, _, err := handleSubTest(ctx, base, runner, checker, sub, problem, settings, subTest)
 			if err != nil {
 				zap.S().Warn("Error handling subtest:", err)
 			}
@@ -199,7 +257,110 @@ func handleClassicSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner
 	return nil
 }
 
-func handleICPCSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
+// handleShortCircuitSubTests evaluates the subtests in order (by subtask, then by test) and skips the ones that can't change the score anymore:
+// once a test makes a subtask worth 0 points (according to its scoring rule, see kilonova.SubtaskScoring.FailedBy), the subtask is failed.
+// A dependent subtask is also failed once a subtask before it can't get 100% anymore.
+// A test is skipped only if all subtasks containing it failed, so subtasks depending on a failed one (by including its tests) are skipped as well.
+// Tests are still run in parallel, up to the number of available boxes, so a few tests after a failure may still be evaluated.
+func handleShortCircuitSubTests(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, settings *kilonova.ProblemEvalSettings, checker checkers.Checker, subTests []*kilonova.SubTest, subTasks []*kilonova.SubmissionSubTask) {
+	slices.SortFunc(subTasks, func(a, b *kilonova.SubmissionSubTask) int { return cmp.Compare(a.VisibleID, b.VisibleID) })
+	subTestsByID := make(map[int]*kilonova.SubTest, len(subTests))
+	for _, st := range subTests {
+		subTestsByID[st.ID] = st
+	}
+
+	// Map every subtest to the subtasks it belongs to and build the evaluation order
+	stkOf := make(map[int][]int)
+	var order []*kilonova.SubTest
+	for i, stk := range subTasks {
+		stkTests := make([]*kilonova.SubTest, 0, len(stk.Subtests))
+		for _, id := range stk.Subtests {
+			if st, ok := subTestsByID[id]; ok {
+				stkTests = append(stkTests, st)
+			}
+		}
+		slices.SortFunc(stkTests, func(a, b *kilonova.SubTest) int { return cmp.Compare(a.VisibleID, b.VisibleID) })
+		for _, st := range stkTests {
+			if _, ok := stkOf[st.ID]; !ok {
+				order = append(order, st)
+			}
+			stkOf[st.ID] = append(stkOf[st.ID], i)
+		}
+	}
+	// Tests outside of subtasks are always evaluated
+	for _, st := range subTests {
+		if _, ok := stkOf[st.ID]; !ok {
+			order = append(order, st)
+		}
+	}
+
+	var mu sync.Mutex
+	failed := make([]bool, len(subTasks))
+	// imperfect subtasks can't get 100% anymore
+	imperfect := make([]bool, len(subTasks))
+	isFailed := func(stk int) bool {
+		if failed[stk] {
+			return true
+		}
+		if subTasks[stk].Scoring == kilonova.SubtaskScoringDependent {
+			return slices.Contains(imperfect[:stk], true)
+		}
+		return false
+	}
+	canSkip := func(st *kilonova.SubTest) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		stks, ok := stkOf[st.ID]
+		if !ok {
+			return false
+		}
+		for _, stk := range stks {
+			if !isFailed(stk) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(runner.NumConcurrent(), 1))
+	for _, subTest := range order {
+		sem <- struct{}{}
+		if canSkip(subTest) {
+			<-sem
+			if err := base.UpdateSubTest(ctx, subTest.ID, kilonova.SubTestUpdate{
+				Done: &True, Skipped: &True,
+				Verdict: &skippedVerdict,
+			}); err != nil {
+				zap.S().Warn("Couldn't update skipped subtest:", err)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(subTest *kilonova.SubTest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			score, _, err := handleSubTest(ctx, base, runner, checker, sub, problem, settings, subTest)
+			if err != nil {
+				zap.S().Warn("Error handling subtest:", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, stk := range stkOf[subTest.ID] {
+				if err != nil || subTasks[stk].Scoring.FailedBy(score) {
+					failed[stk] = true
+				}
+				if err != nil || score.LessThan(decimal.NewFromInt(100)) {
+					imperfect[stk] = true
+				}
+			}
+		}(subTest)
+	}
+	wg.Wait()
+}
+
+func handleICPCSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, settings *kilonova.ProblemEvalSettings, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
 	var failed bool
 	var upd kilonova.SubmissionUpdate
 	upd.Status = kilonova.StatusFinished
@@ -214,7 +375,7 @@ func handleICPCSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eva
 			}
 			continue
 		}
-		score, verdict, err := handleSubTest(ctx, base, runner, checker, sub, problem, subTest)
+		score, verdict, err := handleSubTest(ctx, base, runner, checker, sub, problem, settings, subTest)
 		if err != nil {
 			zap.S().Warn("Error handling subtest:", err)
 			continue
@@ -262,9 +423,19 @@ func compileSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 		return kilonova.WrapError(err, "Couldn't generate compilation request")
 	}
 
-	resp, err1 := tasks.GetCompileTask(graderLogger).Run(ctx, runner, 0, req)
-	if err1 != nil {
-		return kilonova.WrapError(err1, "Error from eval")
+	var resp *tasks.CompileResponse
+	var err1 error
+	if remoteWorkers.Available(req.Lang) {
+		resp, err1 = remoteWorkers.Compile(ctx, req)
+		if err1 != nil {
+			zap.S().Warnf("Couldn't compile submission #%d remotely, compiling locally: %v", sub.ID, err1)
+		}
+	}
+	if resp == nil {
+		resp, err1 = tasks.GetCompileTask(graderLogger).Run(ctx, runner, 0, req)
+		if err1 != nil {
+			return kilonova.WrapError(err1, "Error from eval")
+		}
 	}
 	// if !resp.Success && resp.Other != "" {
 	// 	// zap.S().Warnf("Internal grader error during compilation (#%d): %s", sub.ID, resp.Other)
@@ -305,7 +476,7 @@ func compileSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 	return nil
 }
 
-func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, checker checkers.Checker, sub *kilonova.Submission, problem *kilonova.Problem, subTest *kilonova.SubTest) (decimal.Decimal, string, error) {
+func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, checker checkers.Checker, sub *kilonova.Submission, problem *kilonova.Problem, settings *kilonova.ProblemEvalSettings, subTest *kilonova.SubTest) (decimal.Decimal, string, error) {
 	if subTest.TestID == nil {
 		zap.S().Error("A subtest whose test was purged was detected.", spew.Sdump(subTest))
 		return decimal.Zero, "", kilonova.Statusf(400, "Trying to handle subtest whose test was purged. This should never happen")
@@ -317,32 +488,114 @@ func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxSc
 	}
 	defer tin.Close()
 
-	execRequest := &tasks.ExecRequest{
-		SubID:       sub.ID,
-		SubtestID:   subTest.ID,
-		Filename:    problem.TestName,
-		MemoryLimit: problem.MemoryLimit,
-		TimeLimit:   problem.TimeLimit,
-		Lang:        sub.Language,
-		TestInput:   tin,
-	}
-	if problem.ConsoleInput {
-		execRequest.Filename = "stdin"
+	// Output-only submissions may have an archive with the output of every test, which is checked directly
+	var outputFile io.ReadCloser
+	var missingOutput bool
+	if sub.Language == "outputOnly" {
+		outputFile, err = base.SubmissionOutput(sub.ID, subTest.VisibleID)
+		if errors.Is(err, sudoapi.ErrMissingOutput) {
+			missingOutput = true
+		} else if err != nil && !errors.Is(err, kilonova.ErrNotExist) {
+			return decimal.Zero, "", kilonova.WrapError(err, "Couldn't open submitted output")
+		}
 	}
 
-	resp, err := tasks.GetExecuteTask(graderLogger).Run(ctx, runner, int64(problem.MemoryLimit), execRequest)
-	if err != nil {
-		return decimal.Zero, "", kilonova.WrapError(err, "Couldn't execute subtest")
-	}
+	var resp *tasks.ExecResponse
 	var testScore decimal.Decimal
+	// Interactive and communication submissions are already scored by the interactor/manager, so the checker is skipped
+	var scored bool
+	if missingOutput {
+		resp = &tasks.ExecResponse{Comments: "translate:missing_output"}
+	} else if outputFile != nil {
+		defer outputFile.Close()
+		resp = &tasks.ExecResponse{}
+		if err := base.SaveSubtestOutput(subTest.ID, outputFile); errors.Is(err, sudoapi.ErrOutputTooLarge) {
+			resp.Comments = "translate:output_too_large"
+		} else if err != nil {
+			zap.S().Warn(err)
+			resp.Comments = "translate:internal_error"
+		}
+	} else if settings.ManagerName != "" {
+		commResp, err := tasks.RunCommunicationTask(ctx, runner, graderLogger, &tasks.CommunicationExecRequest{
+			SubID:       sub.ID,
+			SubtestID:   subTest.ID,
+			ProblemID:   problem.ID,
+			MemoryLimit: problem.MemoryLimit,
+			TimeLimit:   problem.TimeLimit,
+			Lang:        sub.Language,
+			TestInput:   tin,
+
+			NumProcesses: problem.NumProcesses,
+			ManagerLang:  eval.GetLangByFilename(settings.ManagerName),
+
+			LanguageLimits: problem.LanguageLimits[sub.Language],
+		})
+		if err != nil {
+			return decimal.Zero, "", kilonova.WrapError(err, "Couldn't execute communication subtest")
+		}
+		resp = &commResp.ExecResponse
+		testScore = commResp.Percentage
+		scored = true
+	} else if settings.InteractorName != "" {
+		intResp, err := tasks.RunInteractiveTask(ctx, runner, graderLogger, &tasks.InteractiveExecRequest{
+			SubID:       sub.ID,
+			SubtestID:   subTest.ID,
+			ProblemID:   problem.ID,
+			MemoryLimit: problem.MemoryLimit,
+			TimeLimit:   problem.TimeLimit,
+			Lang:        sub.Language,
+			TestInput:   tin,
+
+			InteractorLang: eval.GetLangByFilename(settings.InteractorName),
+			LanguageLimits: problem.LanguageLimits[sub.Language],
+		})
+		if err != nil {
+			return decimal.Zero, "", kilonova.WrapError(err, "Couldn't execute interactive subtest")
+		}
+		resp = &intResp.ExecResponse
+		testScore = intResp.Percentage
+		scored = true
+	} else {
+		execRequest := &tasks.ExecRequest{
+			SubID:       sub.ID,
+			SubtestID:   subTest.ID,
+			Filename:    problem.TestName,
+			MemoryLimit: problem.MemoryLimit,
+			TimeLimit:   problem.TimeLimit,
+			Lang:        sub.Language,
+			TestInput:   tin,
+
+			LanguageLimits: problem.LanguageLimits[sub.Language],
+		}
+		if problem.ConsoleInput {
+			execRequest.Filename = "stdin"
+		}
+
+		if remoteWorkers.Available(sub.Language) {
+			resp, err = remoteWorkers.Execute(ctx, execRequest, *subTest.TestID)
+			if err != nil {
+				zap.S().Warnf("Couldn't execute subtest %d remotely, executing locally: %v", subTest.ID, err)
+			}
+		}
+		if resp == nil {
+			_, memoryLimit := execRequest.Limits()
+			resp, err = tasks.GetExecuteTask(graderLogger).Run(ctx, runner, int64(memoryLimit), execRequest)
+			if err != nil {
+				return decimal.Zero, "", kilonova.WrapError(err, "Couldn't execute subtest")
+			}
+		}
+	}
 
 	// Make sure TLEs are fully handled
-	if resp.Time > problem.TimeLimit {
-		resp.Time = problem.TimeLimit
+	timeLimit, _ := eval.Langs()[sub.Language].Limits(problem.TimeLimit, problem.MemoryLimit, problem.LanguageLimits[sub.Language])
+	if resp.Time > timeLimit {
+		resp.Time = timeLimit
 		resp.Comments = "translate:timeout"
+		testScore = decimal.Zero
+		scored = false
 	}
 
-	if resp.Comments == "" {
+	if resp.Comments == "" && !scored {
 		var skipped bool
 		tin, err := base.TestInput(*subTest.TestID)
 		if err != nil {
@@ -384,7 +637,13 @@ func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxSc
 		}
 	}
 
-	if err := base.UpdateSubTest(ctx, subTest.ID, kilonova.SubTestUpdate{Memory: &resp.Memory, Percentage: &testScore, Time: &resp.Time, Verdict: &resp.Comments, Done: &True}); err != nil {
+	upd := kilonova.SubTestUpdate{Memory: &resp.Memory, Percentage: &testScore, Time: &resp.Time, Verdict: &resp.Comments, Done: &True}
+	if stats := resp.Stats; stats != nil {
+		upd.WallTime, upd.ExitCode, upd.ExitSignal = &stats.WallTime, &stats.ExitCode, &stats.ExitSignal
+		upd.MaxRSS, upd.OOMKilled = &stats.MaxRSS, &stats.OOMKilled
+		upd.VoluntaryCSW, upd.ForcedCSW = &stats.VoluntaryCSW, &stats.ForcedCSW
+	}
+	if err := base.UpdateSubTest(ctx, subTest.ID, upd); err != nil {
 		return decimal.Zero, "", kilonova.WrapError(err, "Error during evaltest updating")
 	}
 	return testScore, resp.Comments, nil
@@ -423,23 +682,13 @@ func scoreTests(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submis
 	var score = problem.DefaultPoints
 
 	if len(subTasks) > 0 {
-		subMap := make(map[int]*kilonova.SubTest)
+		testPercentages := make(map[int]decimal.Decimal, len(subtests))
 		for _, st := range subtests {
-			subMap[st.ID] = st
+			testPercentages[st.ID] = st.Percentage
 		}
+		percentages := kilonova.SubTaskPercentages(subTasks, testPercentages)
 		for _, stk := range subTasks {
-			percentage := decimal.NewFromInt(100)
-			if len(stk.Subtests) == 0 { // Empty subtasks should be invalidated
-				percentage = decimal.Zero
-			}
-			for _, id := range stk.Subtests {
-				st, ok := subMap[id]
-				if !ok {
-					zap.S().Warn("Couldn't find subtest. This should not really happen.")
-					continue
-				}
-				percentage = decimal.Min(percentage, st.Percentage)
-			}
+			percentage := percentages[stk.ID]
 			// subTaskScore = stk.Score * (percentage / 100) rounded to the precision
 			subTaskScore := stk.Score.Mul(percentage.Shift(-2)).Round(problem.ScorePrecision)
 			score = score.Add(subTaskScore)
@@ -468,18 +717,9 @@ func scoreTests(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submis
 var ForceSecureSandbox = config.GenFlag[bool]("feature.grader.force_secure_sandbox", true, "Force use of secure sandbox only. Should be always enabled in production environments")
 
 func getAppropriateRunner() (eval.BoxScheduler, error) {
-	var boxFunc scheduler.BoxFunc
-	var boxVersion string = "NONE"
-	if scheduler.CheckCanRun(box.New) {
-		boxFunc = box.New
-		boxVersion = box.IsolateVersion()
-	} else if scheduler.CheckCanRun(box.NewStupid) && !ForceSecureSandbox.Value() {
-		zap.S().Warn("Secure sandbox not found. Using stupid sandbox")
-		boxFunc = box.NewStupid
-		boxVersion = "stupid"
-	}
-	if boxFunc == nil {
-		zap.S().Fatal("Remote grader has not been implemented. No grader available!")
+	boxFunc, boxVersion, err := scheduler.SandboxBackend(!ForceSecureSandbox.Value())
+	if err != nil {
+		zap.S().Fatal("No sandbox available for the grader: ", err)
 	}
 
 	zap.S().Info("Trying to spin up local grader")
@@ -492,8 +732,16 @@ func getAppropriateRunner() (eval.BoxScheduler, error) {
 	return bm, nil
 }
 
-func getAppropriateChecker(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, pb *kilonova.Problem, settings *kilonova.ProblemEvalSettings) (checkers.Checker, error) {
+// getAppropriateChecker returns the checker of the problem. subCode is the checked source code, which custom checkers also receive
+func getAppropriateChecker(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, subCode []byte, pb *kilonova.Problem, settings *kilonova.ProblemEvalSettings) (checkers.Checker, error) {
 	if settings.CheckerName == "" {
+		if settings.BuiltinChecker != kilonova.BuiltinCheckerDiff {
+			return &checkers.BuiltinChecker{
+				Type:       settings.BuiltinChecker,
+				AbsEpsilon: settings.CheckerAbsEpsilon,
+				RelEpsilon: settings.CheckerRelEpsilon,
+			}, nil
+		}
 		return &checkers.DiffChecker{}, nil
 	}
 	att, err := base.ProblemAttByName(ctx, pb.ID, settings.CheckerName)
@@ -504,12 +752,59 @@ func getAppropriateChecker(ctx context.Context, base *sudoapi.BaseAPI, runner ev
 	if err != nil {
 		return nil, kilonova.WrapError(err, "Couldn't get problem checker code")
 	}
-	subCode, err := base.RawSubmissionCode(ctx, sub.ID)
-	if err != nil {
-		return nil, kilonova.WrapError(err, "Couldn't get submission source code")
-	}
 	if settings.LegacyChecker {
 		return checkers.NewLegacyCustomChecker(runner, graderLogger, pb, settings.CheckerName, data, subCode, att.LastUpdatedAt), nil
 	}
+	if settings.TestlibChecker {
+		return checkers.NewTestlibCustomChecker(runner, graderLogger, pb, settings.CheckerName, data, subCode, att.LastUpdatedAt), nil
+	}
 	return checkers.NewStandardCustomChecker(runner, graderLogger, pb, settings.CheckerName, data, subCode, att.LastUpdatedAt), nil
 }
+
+// prepareHelper compiles a problem helper (such as an interactor or a communication manager) from the given attachment, if the cached version is stale.
+// The compiled binary is saved in the checkers bucket as outName
+func prepareHelper(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, pb *kilonova.Problem, attName string, outName string) (string, error) {
+	att, err := base.ProblemAttByName(ctx, pb.ID, attName)
+	if err != nil {
+		return "", kilonova.WrapError(err, "Couldn't get problem helper metadata")
+	}
+
+	helperPrepareMu.Lock()
+	defer helperPrepareMu.Unlock()
+
+	stat, err1 := datastore.GetBucket(datastore.BucketTypeCheckers).Stat(outName)
+	if err1 == nil && !stat.ModTime().Before(att.LastUpdatedAt) {
+		graderLogger.Infof("Using cached helper %q", attName)
+		return "", nil
+	}
+	if err1 != nil && !errors.Is(err1, fs.ErrNotExist) {
+		zap.S().Warn("Helper stat error:", err1)
+	}
+
+	data, err := base.ProblemAttDataByName(ctx, pb.ID, attName)
+	if err != nil {
+		return "", kilonova.WrapError(err, "Couldn't get problem helper code")
+	}
+
+	graderLogger.Infof("Compiling helper %q for problem %d", attName, pb.ID)
+	lang := eval.GetLangByFilename(attName)
+	resp, err1 := tasks.GetCompileTask(graderLogger).Run(ctx, runner, 0, &tasks.CompileRequest{
+		ID: -pb.ID,
+		CodeFiles: map[string][]byte{
+			eval.Langs()[lang].SourceName: data,
+		},
+		HeaderFiles: map[string][]byte{
+			"/box/testlib.h": checkers.TestlibHeader(),
+		},
+		Lang:    lang,
+		OutName: outName,
+	})
+	if err1 != nil {
+		return "Couldn't compile helper", err1
+	}
+	if !resp.Success {
+		return fmt.Sprintf("Output:\n%s\nOther:\n%s", resp.Output, resp.Other), kilonova.Statusf(400, "Invalid helper code")
+	}
+
+	return "", nil
+}
@@ -0,0 +1,173 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"go.uber.org/zap"
+)
+
+// runIDs generates the names of the binaries of debug runs and custom invocations, which are not tied to a submission
+var runIDs atomic.Int64
+
+var MaxConcurrentRuns = config.GenFlag[int]("behavior.runs.max_concurrent", 2, "Maximum number of debug runs, custom invocations and test generations in progress at once, over all users. Every run takes a box away from submissions")
+
+const invocationOutputLimit = 64 * 1024 // bytes
+
+// acquireRun reserves a box from the grader scheduler for a debug run or custom invocation, so runs wait for boxes just like submissions.
+// At most MaxConcurrentRuns runs are in progress at once, further runs are refused instead of piling up.
+// The returned function must be called once the run is done
+func (h *Handler) acquireRun(ctx context.Context) (eval.BoxScheduler, func(), error) {
+	runner := h.currentRunner()
+	if runner == nil {
+		return nil, nil, kilonova.Statusf(503, "Grader is not running")
+	}
+	if h.activeRuns.Add(1) > int64(MaxConcurrentRuns.Value()) {
+		h.activeRuns.Add(-1)
+		return nil, nil, kilonova.Statusf(http.StatusTooManyRequests, "Too many programs are running right now, please try again in a bit")
+	}
+	subRunner, err := runner.SubRunner(ctx, 1)
+	if err != nil {
+		h.activeRuns.Add(-1)
+		return nil, nil, kilonova.WrapError(err, "Couldn't get a box")
+	}
+	return subRunner, func() {
+		// The box must be given back even if the request was cancelled
+		subRunner.Close(context.WithoutCancel(ctx))
+		h.activeRuns.Add(-1)
+	}, nil
+}
+
+// preparedRun is a debug run or custom invocation whose code was compiled
+type preparedRun struct {
+	runner   eval.BoxScheduler
+	settings *kilonova.ProblemEvalSettings
+
+	binaryName string
+	compile    *tasks.CompileResponse
+}
+
+// startRun reserves a box for the run and compiles the code with the given profile.
+// If it succeeds, the returned function must be called once the run is done, to remove the binary and give the box back
+func (h *Handler) startRun(ctx context.Context, problem *kilonova.Problem, code []byte, lang string, profile eval.CompileProfile) (*preparedRun, func(), error) {
+	settings, err := h.base.ProblemSettings(ctx, problem.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	runner, release, err1 := h.acquireRun(ctx)
+	if err1 != nil {
+		return nil, nil, err1
+	}
+
+	binaryName, resp, err := compileRun(ctx, h.base, runner, problem, settings, code, lang, profile)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return &preparedRun{runner: runner, settings: settings, binaryName: binaryName, compile: resp}, func() {
+		removeRunBinary(binaryName)
+		release()
+	}, nil
+}
+
+// compileRun compiles code that is not a submission (for debug runs and custom invocations) with the given profile.
+// The binary is saved in the checkers bucket with the returned name and should be removed with removeRunBinary
+func compileRun(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, problem *kilonova.Problem, settings *kilonova.ProblemEvalSettings, code []byte, lang string, profile eval.CompileProfile) (string, *tasks.CompileResponse, *kilonova.StatusError) {
+	runID := int(runIDs.Add(1))
+	req, err := genCompileRequest(ctx, base, -runID, lang, code, problem, settings)
+	if err != nil {
+		return "", nil, err
+	}
+	req.OutName = fmt.Sprintf("run-%d.bin", runID)
+	req.Profile = profile
+	resp, err1 := tasks.GetCompileTask(graderLogger).Run(ctx, runner, 0, req)
+	if err1 != nil {
+		return "", nil, kilonova.WrapError(err1, "Error from eval")
+	}
+	return req.OutName, resp, nil
+}
+
+func removeRunBinary(name string) {
+	if err := datastore.GetBucket(datastore.BucketTypeCheckers).RemoveFile(name); err != nil {
+		zap.S().Warn("Couldn't remove run binary: ", err)
+	}
+}
+
+// limitedBuffer keeps only the first limit bytes written to it
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		b.buf.Write(p[:max(remaining, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// CustomInvocation compiles the code and runs it once on the given input, with the limits of the problem.
+// The input is given and the output is read the same way as on the tests of the problem.
+// Nothing is saved, the compiled binary is removed afterwards
+func (h *Handler) CustomInvocation(ctx context.Context, problem *kilonova.Problem, code []byte, lang string, input []byte) (*kilonova.CustomInvocation, error) {
+	graderLogger.Infof("Starting custom invocation for problem %d", problem.ID)
+	run, release, err := h.startRun(ctx, problem, code, lang, eval.ProfileDefault)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	invocation := &kilonova.CustomInvocation{CompileError: !run.compile.Success, CompileMessage: run.compile.Output}
+	if !run.compile.Success {
+		return invocation, nil
+	}
+
+	output := &limitedBuffer{limit: invocationOutputLimit}
+	execRequest := &tasks.ExecRequest{
+		Filename:    problem.TestName,
+		MemoryLimit: problem.MemoryLimit,
+		TimeLimit:   problem.TimeLimit,
+		Lang:        lang,
+		TestInput:   bytes.NewReader(input),
+
+		LanguageLimits: problem.LanguageLimits[lang],
+
+		BinaryName:    run.binaryName,
+		CaptureStderr: true,
+		Output:        output,
+	}
+	if problem.ConsoleInput {
+		execRequest.Filename = "stdin"
+	}
+	_, memoryLimit := execRequest.Limits()
+	execResp, err1 := tasks.GetExecuteTask(graderLogger).Run(ctx, run.runner, int64(memoryLimit), execRequest)
+	if err1 != nil {
+		return nil, kilonova.WrapError(err1, "Couldn't execute program")
+	}
+
+	invocation.Verdict = execResp.Comments
+	invocation.Time = execResp.Time
+	invocation.Memory = execResp.Memory
+	if execResp.Stats != nil {
+		invocation.ExitCode = execResp.Stats.ExitCode
+	}
+	invocation.Stdout = strings.ToValidUTF8(output.buf.String(), "\uFFFD")
+	if output.truncated {
+		invocation.Stdout += "... (output trimmed)"
+	}
+	invocation.Stderr = execResp.Stderr
+	return invocation, nil
+}
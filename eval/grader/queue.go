@@ -0,0 +1,264 @@
+package grader
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+)
+
+// queueRefreshInterval is how often the queue is rebuilt even if it wasn't marked as stale, so contest starts and ends change priorities
+const queueRefreshInterval = time.Minute
+
+var priorityRanks = map[kilonova.QueuePriority]int{
+	kilonova.QueuePriorityContest:      0,
+	kilonova.QueuePriorityPractice:     1,
+	kilonova.QueuePriorityReevaluation: 2,
+}
+
+type queuedSub struct {
+	sub      *kilonova.Submission
+	priority kilonova.QueuePriority
+	// round is the fair-share round of the submission: the n-th queued submission of a user
+	// (counting the ones already being evaluated) is evaluated after the (n-1)-th submissions of all other users
+	round int
+}
+
+// evalTimeTracker keeps an exponential moving average of the submission evaluation times, used for estimating the queue wait
+type evalTimeTracker struct {
+	mu      sync.Mutex
+	average time.Duration
+}
+
+func (t *evalTimeTracker) add(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.average == 0 {
+		t.average = d
+		return
+	}
+	t.average = (4*t.average + d) / 5
+}
+
+func (t *evalTimeTracker) get() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.average
+}
+
+// subQueue is the in-memory submission queue of the grader. It is rebuilt from the database only when it's stale
+// (submissions were created or reset since the last build) or outdated, otherwise it's updated in place as submissions start and finish evaluating
+type subQueue struct {
+	mu   sync.Mutex
+	subs []*queuedSub
+	// working is the number of submissions of every user that are being evaluated
+	working map[int]int
+	// popped holds the IDs of the submissions taken from the queue that didn't start evaluating yet.
+	// They are still waiting in the database, so rebuilding the queue must skip them
+	popped map[int]bool
+
+	stale bool
+	// truncated is set if the last build didn't fetch all queued submissions, so the queue must be rebuilt once it's drained
+	truncated bool
+	builtAt   time.Time
+}
+
+func newSubQueue() *subQueue {
+	return &subQueue{working: make(map[int]int), popped: make(map[int]bool), stale: true}
+}
+
+func (q *subQueue) markStale() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stale = true
+}
+
+func (q *subQueue) needsRebuild() bool {
+	return q.stale || time.Since(q.builtAt) > queueRefreshInterval || (q.truncated && len(q.subs) == 0)
+}
+
+// shiftRounds moves the queued submissions of the user by delta rounds. If priority is not empty, only the submissions of that class are moved
+func (q *subQueue) shiftRounds(userID int, priority kilonova.QueuePriority, delta int) {
+	for _, qSub := range q.subs {
+		if qSub.sub.UserID == userID && (priority == "" || qSub.priority == priority) {
+			qSub.round += delta
+		}
+	}
+	q.sort()
+}
+
+// sort orders the queue by priority class, then by fair-share round and finally by ID
+func (q *subQueue) sort() {
+	slices.SortFunc(q.subs, func(a, b *queuedSub) int {
+		if c := cmp.Compare(priorityRanks[a.priority], priorityRanks[b.priority]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.round, b.round); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.sub.ID, b.sub.ID)
+	})
+}
+
+// started must be called when a submission starts being evaluated, after its status was updated
+func (q *subQueue) started(sub *kilonova.Submission) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.popped, sub.ID)
+	q.working[sub.UserID]++
+	q.shiftRounds(sub.UserID, "", 1)
+}
+
+// requeue must be called when a popped submission couldn't be started, so it's picked up again on the next rebuild
+func (q *subQueue) requeue(subID int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.popped, subID)
+	q.stale = true
+}
+
+// finished must be called when a submission of the user finishes being evaluated
+func (q *subQueue) finished(userID int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.working[userID]--
+	if q.working[userID] <= 0 {
+		delete(q.working, userID)
+	}
+	q.shiftRounds(userID, "", -1)
+}
+
+// rebuildQueue loads the waiting and reevaluating submissions from the database. It must be called with the lock held
+func (h *Handler) rebuildQueue(ctx context.Context) error {
+	q := h.queue
+	waiting, err := h.base.RawSubmissions(ctx, waitingSubs)
+	if err != nil {
+		return err
+	}
+	reevaluating, err := h.base.RawSubmissions(ctx, reevalingSubs)
+	if err != nil {
+		return err
+	}
+
+	runningContests := make(map[int]bool)
+	contestRunning := func(id int) bool {
+		running, ok := runningContests[id]
+		if !ok {
+			contest, err := h.base.Contest(ctx, id)
+			running = err == nil && contest.Running()
+			runningContests[id] = running
+		}
+		return running
+	}
+
+	q.build(waiting, reevaluating, contestRunning)
+	q.truncated = len(waiting) == waitingSubs.Limit || len(reevaluating) == reevalingSubs.Limit
+	return nil
+}
+
+// build replaces the queue with the given waiting and reevaluating submissions, skipping the popped ones.
+// Submissions of running contests get the contest priority. It must be called with the lock held
+func (q *subQueue) build(waiting, reevaluating []*kilonova.Submission, contestRunning func(int) bool) {
+	queue := make([]*queuedSub, 0, len(waiting)+len(reevaluating))
+	for _, sub := range waiting {
+		if q.popped[sub.ID] {
+			continue
+		}
+		priority := kilonova.QueuePriorityPractice
+		if sub.ContestID != nil && contestRunning(*sub.ContestID) {
+			priority = kilonova.QueuePriorityContest
+		}
+		queue = append(queue, &queuedSub{sub: sub, priority: priority})
+	}
+	for _, sub := range reevaluating {
+		if q.popped[sub.ID] {
+			continue
+		}
+		queue = append(queue, &queuedSub{sub: sub, priority: kilonova.QueuePriorityReevaluation})
+	}
+
+	// Users already being evaluated start from a later round
+	slices.SortFunc(queue, func(a, b *queuedSub) int { return cmp.Compare(a.sub.ID, b.sub.ID) })
+	type userClass struct {
+		userID   int
+		priority kilonova.QueuePriority
+	}
+	rounds := make(map[userClass]int)
+	for _, qSub := range queue {
+		key := userClass{qSub.sub.UserID, qSub.priority}
+		qSub.round = q.working[qSub.sub.UserID] + rounds[key]
+		rounds[key]++
+	}
+
+	q.subs = queue
+	q.sort()
+	q.stale = false
+	q.builtAt = time.Now()
+}
+
+// submissionQueue returns the waiting and reevaluating submissions, in the order they should be evaluated:
+// by priority class, then by fair-share round and finally by ID
+func (h *Handler) submissionQueue(ctx context.Context) ([]*queuedSub, error) {
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	if h.queue.needsRebuild() {
+		if err := h.rebuildQueue(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return slices.Clone(h.queue.subs), nil
+}
+
+// popQueue removes and returns the first submission in the queue, or nil if the queue is empty.
+// Until ScheduleSubmission starts it (or it's requeued), the submission is not added back to the queue by rebuilds
+func (h *Handler) popQueue(ctx context.Context) (*queuedSub, error) {
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	if h.queue.needsRebuild() {
+		if err := h.rebuildQueue(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return h.queue.pop(), nil
+}
+
+// pop removes and returns the first submission in the queue, or nil if the queue is empty.
+// The submission is remembered as popped until it's started or requeued. It must be called with the lock held
+func (q *subQueue) pop() *queuedSub {
+	if len(q.subs) == 0 {
+		return nil
+	}
+	qSub := q.subs[0]
+	q.subs = q.subs[1:]
+	q.popped[qSub.sub.ID] = true
+	q.shiftRounds(qSub.sub.UserID, qSub.priority, -1)
+	return qSub
+}
+
+// Queue returns the submissions waiting for evaluation, in the order they will be evaluated.
+// Note that only the first submissions of every status are considered, so positions are not exact for very large queues
+func (h *Handler) Queue(ctx context.Context) ([]*kilonova.QueuedSubmission, error) {
+	queue, err := h.submissionQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	average := h.evalTimes.get()
+	subs := make([]*kilonova.QueuedSubmission, 0, len(queue))
+	for i, qSub := range queue {
+		subs = append(subs, &kilonova.QueuedSubmission{
+			SubmissionID: qSub.sub.ID,
+			UserID:       qSub.sub.UserID,
+			ProblemID:    qSub.sub.ProblemID,
+			ContestID:    qSub.sub.ContestID,
+			CreatedAt:    qSub.sub.CreatedAt,
+			Priority:     qSub.priority,
+
+			Position:      i + 1,
+			EstimatedWait: time.Duration(i+1) * average,
+		})
+	}
+	return subs, nil
+}
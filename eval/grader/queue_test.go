@@ -0,0 +1,148 @@
+package grader
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/KiloProjects/kilonova"
+)
+
+func queueTestSub(id, userID int, contestID *int) *kilonova.Submission {
+	return &kilonova.Submission{ID: id, UserID: userID, ContestID: contestID}
+}
+
+func queueOrder(q *subQueue) []int {
+	ids := make([]int, 0, len(q.subs))
+	for _, qSub := range q.subs {
+		ids = append(ids, qSub.sub.ID)
+	}
+	return ids
+}
+
+func runningContest(id int) bool { return id == 1 }
+
+type queueBuildTest struct {
+	Waiting      []*kilonova.Submission
+	Reevaluating []*kilonova.Submission
+	Working      map[int]int
+	Order        []int
+}
+
+var (
+	runningContestID = 1
+	endedContestID   = 2
+)
+
+var queueBuildExamples = map[string]queueBuildTest{
+	"fairShare": {
+		Waiting: []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 1, nil), queueTestSub(3, 1, nil), queueTestSub(4, 2, nil), queueTestSub(5, 2, nil)},
+		Order:   []int{1, 4, 2, 5, 3},
+	},
+	"priority": {
+		Waiting:      []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 2, &endedContestID), queueTestSub(3, 3, &runningContestID)},
+		Reevaluating: []*kilonova.Submission{queueTestSub(4, 4, nil)},
+		Order:        []int{3, 1, 2, 4},
+	},
+	"priorityBeforeRounds": {
+		Waiting:      []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 1, &runningContestID), queueTestSub(3, 1, &runningContestID)},
+		Reevaluating: []*kilonova.Submission{queueTestSub(4, 2, nil)},
+		Order:        []int{2, 3, 1, 4},
+	},
+	"working": {
+		Waiting: []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 1, nil), queueTestSub(3, 2, nil)},
+		Working: map[int]int{1: 1},
+		Order:   []int{3, 1, 2},
+	},
+}
+
+func TestSubQueueBuild(t *testing.T) {
+	for name, tc := range queueBuildExamples {
+		t.Run(name, func(t *testing.T) {
+			q := newSubQueue()
+			for userID, count := range tc.Working {
+				q.working[userID] = count
+			}
+			q.build(tc.Waiting, tc.Reevaluating, runningContest)
+			if q.needsRebuild() {
+				t.Fatal("Queue needs a rebuild right after being built")
+			}
+			if order := queueOrder(q); !slices.Equal(order, tc.Order) {
+				t.Fatalf("Expected order %v, got %v", tc.Order, order)
+			}
+		})
+	}
+}
+
+func TestSubQueuePop(t *testing.T) {
+	q := newSubQueue()
+	waiting := []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 1, nil), queueTestSub(3, 2, nil)}
+	q.build(waiting, nil, runningContest)
+
+	qSub := q.pop()
+	if qSub == nil || qSub.sub.ID != 1 {
+		t.Fatalf("Expected submission 1 to be popped, got %v", qSub)
+	}
+	if order := queueOrder(q); !slices.Equal(order, []int{2, 3}) {
+		t.Fatalf("Expected order [2 3] after pop, got %v", order)
+	}
+
+	// The popped submission is still waiting in the database until it starts, so rebuilds must not add it back
+	q.build(waiting, nil, runningContest)
+	if order := queueOrder(q); !slices.Equal(order, []int{2, 3}) {
+		t.Fatalf("Expected order [2 3] after rebuilding, got %v", order)
+	}
+
+	// Once it's started, the other submissions of the user go after the ones of other users
+	q.started(qSub.sub)
+	if order := queueOrder(q); !slices.Equal(order, []int{3, 2}) {
+		t.Fatalf("Expected order [3 2] after start, got %v", order)
+	}
+	if q.popped[1] {
+		t.Fatal("Started submission is still marked as popped")
+	}
+
+	if q.pop().sub.ID != 3 || q.pop().sub.ID != 2 {
+		t.Fatal("Popped the remaining submissions in the wrong order")
+	}
+	if qSub := q.pop(); qSub != nil {
+		t.Fatalf("Expected empty queue, got submission %d", qSub.sub.ID)
+	}
+}
+
+func TestSubQueueRequeue(t *testing.T) {
+	q := newSubQueue()
+	waiting := []*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 2, nil)}
+	q.build(waiting, nil, runningContest)
+
+	qSub := q.pop()
+	q.requeue(qSub.sub.ID)
+	if !q.needsRebuild() {
+		t.Fatal("Queue doesn't need a rebuild after a requeue")
+	}
+	q.build(waiting, nil, runningContest)
+	if order := queueOrder(q); !slices.Equal(order, []int{1, 2}) {
+		t.Fatalf("Expected order [1 2] after requeue, got %v", order)
+	}
+}
+
+func TestSubQueueStartedFinished(t *testing.T) {
+	q := newSubQueue()
+	q.build([]*kilonova.Submission{queueTestSub(1, 1, nil), queueTestSub(2, 2, nil), queueTestSub(3, 1, nil)}, nil, runningContest)
+
+	// A submission of user 1 that was not in the queue (e.g. it was scheduled directly) starts evaluating
+	q.started(queueTestSub(4, 1, nil))
+	if q.working[1] != 1 {
+		t.Fatalf("Expected 1 working submission for user 1, got %d", q.working[1])
+	}
+	if order := queueOrder(q); !slices.Equal(order, []int{2, 1, 3}) {
+		t.Fatalf("Expected order [2 1 3] after start, got %v", order)
+	}
+
+	q.finished(1)
+	if _, ok := q.working[1]; ok {
+		t.Fatal("User 1 is still working after finishing")
+	}
+	if order := queueOrder(q); !slices.Equal(order, []int{1, 2, 3}) {
+		t.Fatalf("Expected order [1 2 3] after finish, got %v", order)
+	}
+}
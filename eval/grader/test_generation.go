@@ -0,0 +1,151 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"github.com/KiloProjects/kilonova/sudoapi"
+)
+
+// GenerateTests runs the generator for every invocation, checks the input with the validator and creates the output with the main solution.
+// The files are written in dir as <visible id>.in and <visible id>.out. The first failure stops the whole generation.
+// Like debug runs, generation uses a single box and counts towards MaxConcurrentRuns, so it can't take over the grader's boxes
+func (h *Handler) GenerateTests(ctx context.Context, problem *kilonova.Problem, invocations []*sudoapi.GeneratorInvocation, dir string) error {
+	settings, err := h.base.ProblemSettings(ctx, problem.ID)
+	if err != nil {
+		return err
+	}
+	runner, release, err1 := h.acquireRun(ctx)
+	if err1 != nil {
+		return err1
+	}
+	defer release()
+
+	generator, validator := tasks.GeneratorFilename(problem.ID), tasks.ValidatorFilename(problem.ID)
+	for name, outName := range map[string]string{
+		settings.GeneratorName: generator,
+		settings.ValidatorName: validator,
+	} {
+		if info, err := prepareHelper(ctx, h.base, runner, problem, name, outName); err != nil {
+			return kilonova.Statusf(400, "Couldn't compile %s:\n%s", name, info)
+		}
+	}
+
+	code, err := h.base.ProblemAttDataByName(ctx, problem.ID, settings.SolutionName)
+	if err != nil {
+		return kilonova.WrapError(err, "Couldn't get main solution")
+	}
+	solutionLang := eval.GetLangByFilename(settings.SolutionName)
+	solution, resp, err := compileRun(ctx, h.base, runner, problem, settings, code, solutionLang, eval.ProfileDefault)
+	if err != nil {
+		return err
+	}
+	defer removeRunBinary(solution)
+	if !resp.Success {
+		return kilonova.Statusf(400, "Couldn't compile main solution:\n%s", resp.Output)
+	}
+
+	graderLogger.Infof("Generating %d tests for problem %d", len(invocations), problem.ID)
+	for _, inv := range invocations {
+		prefix := path.Join(dir, strconv.Itoa(inv.VisibleID))
+		if err := h.runGenerator(ctx, runner, eval.GetLangByFilename(settings.GeneratorName), generator, inv.Args, prefix+".in"); err != nil {
+			return kilonova.Statusf(400, "Line %d (test %d): generator failed: %s", inv.Line, inv.VisibleID, err)
+		}
+		if err := h.runValidator(ctx, runner, eval.GetLangByFilename(settings.ValidatorName), validator, prefix+".in"); err != nil {
+			return kilonova.Statusf(400, "Line %d (test %d): validation failed: %s", inv.Line, inv.VisibleID, err)
+		}
+		if err := h.runSolution(ctx, runner, problem, solutionLang, solution, prefix+".in", prefix+".out"); err != nil {
+			return kilonova.Statusf(400, "Line %d (test %d): main solution failed: %s", inv.Line, inv.VisibleID, err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) runGenerator(ctx context.Context, runner eval.BoxScheduler, lang string, binaryName string, args []string, outPath string) error {
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	resp, err := tasks.GetHelperTask(graderLogger).Run(ctx, runner, tasks.HelperMemoryLimit, &tasks.HelperRequest{
+		BinaryName: binaryName,
+		Lang:       lang,
+		Args:       args,
+		Output:     out,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("%s", strings.TrimSpace(resp.Message))
+	}
+	return nil
+}
+
+func (h *Handler) runValidator(ctx context.Context, runner eval.BoxScheduler, lang string, binaryName string, inPath string) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	resp, err := tasks.GetHelperTask(graderLogger).Run(ctx, runner, tasks.HelperMemoryLimit, &tasks.HelperRequest{
+		BinaryName: binaryName,
+		Lang:       lang,
+		Input:      in,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("%s", strings.TrimSpace(resp.Message))
+	}
+	return nil
+}
+
+func (h *Handler) runSolution(ctx context.Context, runner eval.BoxScheduler, problem *kilonova.Problem, lang string, binaryName string, inPath string, outPath string) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	execRequest := &tasks.ExecRequest{
+		Filename:    problem.TestName,
+		MemoryLimit: problem.MemoryLimit,
+		TimeLimit:   problem.TimeLimit,
+		Lang:        lang,
+		TestInput:   in,
+
+		LanguageLimits: problem.LanguageLimits[lang],
+
+		BinaryName:    binaryName,
+		CaptureStderr: true,
+		Output:        out,
+	}
+	if problem.ConsoleInput {
+		execRequest.Filename = "stdin"
+	}
+	_, memoryLimit := execRequest.Limits()
+	resp, err := tasks.GetExecuteTask(graderLogger).Run(ctx, runner, int64(memoryLimit), execRequest)
+	if err != nil {
+		return err
+	}
+	if resp.Comments != "" {
+		return fmt.Errorf("%s", strings.TrimSpace(resp.Comments+"\n"+resp.Stderr))
+	}
+	return nil
+}
@@ -1,9 +1,19 @@
 package eval
 
-import "path"
+import (
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/KiloProjects/kilonova"
+)
 
 const MagicReplace = "<REPLACE>"
 
+// MagicMemoryLimit is replaced in run commands with the memory limit of the problem (in kilobytes), without the language overhead
+const MagicMemoryLimit = "<MEMORY_LIMIT>"
+
 func GetLangByFilename(filename string) string {
 	fileExt := path.Ext(filename)
 	if fileExt == "" {
@@ -14,7 +24,7 @@ func GetLangByFilename(filename string) string {
 		return "cpp17"
 	}
 	bestLang := ""
-	for k, v := range Langs {
+	for k, v := range Langs() {
 		for _, ext := range v.Extensions {
 			if ext == fileExt && (bestLang == "" || k < bestLang) {
 				bestLang = k
@@ -24,8 +34,37 @@ func GetLangByFilename(filename string) string {
 	return bestLang
 }
 
+// jvmRunCommand is used by all JVM languages. The heap gets the memory limit of the problem,
+// while the rest of the JVM (metaspace, JIT, thread stacks) fits in the language memory overhead.
+// The JVM doesn't see the cgroup of the sandbox, so without -Xmx it would size the heap based on the memory of the machine
+// and get killed by the sandbox before collecting garbage
+var jvmRunCommand = []string{"java", "-XX:+UseSerialGC", "-XX:-UsePerfData", "-Xss64m", "-Xmx" + MagicMemoryLimit + "k", "-jar", "/box/output.jar"}
+
+const jvmMemoryOverhead = 64 * 1024
+
+// sanitizerEnv is used when running C/C++ debug builds.
+// Leaks are not bugs in competitive programming and LeakSanitizer can't stop the world in the sandbox anyway
+var sanitizerEnv = map[string]string{
+	"ASAN_OPTIONS":  "detect_leaks=0",
+	"UBSAN_OPTIONS": "print_stacktrace=1",
+}
+
+var langs atomic.Pointer[map[string]Language]
+
+func init() {
+	builtins := builtinLanguages()
+	langs.Store(&builtins)
+}
+
+// Langs returns the languages currently in use. They are the built-in languages, overridden by the languages file.
+// The returned map must not be modified, since it's shared with the other callers. LoadLanguages replaces it entirely,
+// so callers that need a consistent view (such as iterating over it and then indexing it) should call Langs once
+func Langs() map[string]Language {
+	return *langs.Load()
+}
+
 // NOTE: Last extension MUST be unique (for proper detection of submissions in problem archives)
-var Langs = map[string]Language{
+var builtinLangs = map[string]Language{
 	"c": {
 		Extensions:    []string{".c"},
 		Compiled:      true,
@@ -39,6 +78,9 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 		SimilarLangs:   []string{"c", "cpp", "cpp11", "cpp14", "cpp17", "cpp20"},
 
+		DebugCompileCommand: []string{"gcc", "-fuse-ld=mold", "-std=c11", "-O1", "-g", "-Wall", "-Wextra", "-fsanitize=address,undefined", "-fno-omit-frame-pointer", "-DKNOVA", "-DONLINE_JUDGE", MagicReplace, "-lm", "-o", "/box/output"},
+		DebugRunEnv:         sanitizerEnv,
+
 		Mounts: []Directory{{In: "/etc"}},
 	},
 	"cpp11": {
@@ -54,6 +96,9 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 		SimilarLangs:   []string{"c", "cpp", "cpp11", "cpp14", "cpp17", "cpp20"},
 
+		DebugCompileCommand: []string{"g++", "-fuse-ld=mold", "-std=c++11", "-O1", "-g", "-Wall", "-Wextra", "-fsanitize=address,undefined", "-fno-omit-frame-pointer", "-DKNOVA", "-DONLINE_JUDGE", MagicReplace, "-o", "/box/output"},
+		DebugRunEnv:         sanitizerEnv,
+
 		Mounts: []Directory{{In: "/etc"}},
 	},
 	"cpp14": {
@@ -69,6 +114,9 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 		SimilarLangs:   []string{"c", "cpp", "cpp11", "cpp14", "cpp17", "cpp20"},
 
+		DebugCompileCommand: []string{"g++", "-fuse-ld=mold", "-std=c++14", "-O1", "-g", "-Wall", "-Wextra", "-fsanitize=address,undefined", "-fno-omit-frame-pointer", "-DKNOVA", "-DONLINE_JUDGE", MagicReplace, "-o", "/box/output"},
+		DebugRunEnv:         sanitizerEnv,
+
 		Mounts: []Directory{{In: "/etc"}},
 	},
 	"cpp17": {
@@ -84,6 +132,9 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 		SimilarLangs:   []string{"c", "cpp", "cpp11", "cpp14", "cpp17", "cpp20"},
 
+		DebugCompileCommand: []string{"g++", "-fuse-ld=mold", "-std=c++17", "-O1", "-g", "-Wall", "-Wextra", "-fsanitize=address,undefined", "-fno-omit-frame-pointer", "-DKNOVA", "-DONLINE_JUDGE", MagicReplace, "-o", "/box/output"},
+		DebugRunEnv:         sanitizerEnv,
+
 		Mounts: []Directory{{In: "/etc"}},
 	},
 	"cpp20": {
@@ -99,6 +150,9 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 		SimilarLangs:   []string{"c", "cpp", "cpp11", "cpp14", "cpp17", "cpp20"},
 
+		DebugCompileCommand: []string{"g++", "-fuse-ld=mold", "-std=c++20", "-O1", "-g", "-Wall", "-Wextra", "-fsanitize=address,undefined", "-fno-omit-frame-pointer", "-DKNOVA", "-DONLINE_JUDGE", MagicReplace, "-o", "/box/output"},
+		DebugRunEnv:         sanitizerEnv,
+
 		Mounts: []Directory{{In: "/etc"}},
 	},
 	"pascal": {
@@ -135,6 +189,8 @@ var Langs = map[string]Language{
 		RunEnv:   map[string]string{"GOMAXPROCS": "1"},
 
 		Mounts: []Directory{{In: "/go", Opts: "tmp", Verbatim: true}},
+
+		MemoryOverhead: 32 * 1024, // The Go runtime reserves quite a bit of memory upfront
 	},
 	"haskell": {
 		Disabled:      true, // For now
@@ -150,19 +206,46 @@ var Langs = map[string]Language{
 		CompiledName:   "/box/output",
 	},
 	"java": {
-		Disabled:      true, // For now
 		Extensions:    []string{".java"},
 		Compiled:      true,
 		PrintableName: "Java",
 		InternalName:  "java",
 		MOSSName:      "java",
 
-		CompileCommand: []string{"javac", MagicReplace},
-		RunCommand:     []string{"java", "Main"},
-		SourceName:     "/Main.java",
-		CompiledName:   "/Main.class",
+		// The public class must be called Main. Nested classes are compiled to separate files, so all of them are packed in a jar
+		CompileCommand: []string{"/bin/sh", "-c", `javac -J-XX:-UsePerfData -encoding UTF-8 -d /box/classes "$@" && jar cfe /box/output.jar Main -C /box/classes .`, "javac", MagicReplace},
+		RunCommand:     jvmRunCommand,
+		SourceName:     "/box/Main.java",
+		CompiledName:   "/box/output.jar",
+		Requires:       []string{"javac", "jar", "java"},
 
-		Mounts: []Directory{{In: "/etc"}},
+		Mounts:    []Directory{{In: "/etc"}},
+		RunMounts: []Directory{{In: "/etc"}},
+
+		TimeMultiplier: 2,
+		MemoryOverhead: jvmMemoryOverhead,
+	},
+	"kotlin": {
+		Extensions:    []string{".kt"},
+		Compiled:      true,
+		PrintableName: "Kotlin",
+		InternalName:  "kotlin",
+		MOSSName:      "ascii", // MOSS doesn't support kotlin
+
+		// The jar manifest points to the class of the main function (MainKt)
+		CompileCommand: []string{"kotlinc", "-include-runtime", "-nowarn", "-d", "/box/output.jar", MagicReplace},
+		RunCommand:     jvmRunCommand,
+		SourceName:     "/box/main.kt",
+		CompiledName:   "/box/output.jar",
+		Requires:       []string{"java"},
+
+		BuildEnv: map[string]string{"JAVA_OPTS": "-XX:-UsePerfData"},
+
+		Mounts:    []Directory{{In: "/etc"}},
+		RunMounts: []Directory{{In: "/etc"}},
+
+		TimeMultiplier: 2,
+		MemoryOverhead: jvmMemoryOverhead,
 	},
 	"python3": {
 		Extensions:    []string{".py", ".py3"},
@@ -174,6 +257,21 @@ var Langs = map[string]Language{
 		RunCommand:   []string{"python3", "/box/main.py"},
 		SourceName:   "/box/main.py",
 		CompiledName: "/box/main.py",
+
+		TimeMultiplier: 3,
+	},
+	"rust": {
+		Extensions:    []string{".rs"},
+		Compiled:      true,
+		PrintableName: "Rust",
+		InternalName:  "rust",
+		MOSSName:      "ascii", // MOSS doesn't support rust
+
+		// Note that rustup installations also need the toolchain directory mounted
+		CompileCommand: []string{"rustc", "--edition=2021", "-O", "-o", "/box/output", MagicReplace},
+		RunCommand:     []string{"/box/output"},
+		SourceName:     "/box/main.rs",
+		CompiledName:   "/box/output",
 	},
 	"outputOnly": {
 		Extensions:    []string{".output_only"},
@@ -190,33 +288,106 @@ var Langs = map[string]Language{
 
 // Language is the data available for a language
 type Language struct {
-	Disabled bool
+	Disabled bool `toml:"disabled"`
 
 	// Useful to categorize by file upload
-	Extensions []string
-	Compiled   bool
+	Extensions []string `toml:"extensions"`
+	Compiled   bool     `toml:"compiled"`
 
 	// SimilarLangs is used on resolution of grader files during evaluation
 	// to decide which of the grader files to include for interactive problems
 	SimilarLangs []string `toml:"compatible_langs"`
 
-	PrintableName string
-	InternalName  string
+	PrintableName string `toml:"printable_name"`
+	InternalName  string `toml:"internal_name"`
 
-	// Reference: http://moss.stanford.edu/general/scripts/mossnet
-	MOSSName string
+	// MOSSName is the language family used by the code similarity checker (see the plagiarism package).
+	// The names are the ones used by MOSS: http://moss.stanford.edu/general/scripts/mossnet
+	MOSSName string `toml:"moss_name"`
 
 	CompileCommand []string `toml:"compile_command"`
 	RunCommand     []string `toml:"run_command"`
 
+	// DebugCompileCommand is used for debug runs instead of CompileCommand, with warnings and sanitizers enabled.
+	// Debug runs are not available for languages without one
+	DebugCompileCommand []string `toml:"debug_compile_command"`
+	// DebugRunEnv is added to RunEnv when running debug builds
+	DebugRunEnv map[string]string `toml:"debug_run_env"`
+
 	BuildEnv map[string]string `toml:"build_env"`
 	RunEnv   map[string]string `toml:"run_env"`
 
 	// Mounts represents all directories to be mounted
-	Mounts     []Directory `toml:"mounts"`
+	Mounts []Directory `toml:"mounts"`
+	// RunMounts are mounted when running compiled programs which need more than the box, such as the JVM
+	RunMounts  []Directory `toml:"run_mounts"`
 	SourceName string      `toml:"source_name"`
 
+	// Requires lists other programs that must be available for the language to be enabled,
+	// such as the linker or, for compile commands that go through a shell, the actual compiler
+	Requires []string `toml:"requires"`
+
 	CompiledName string `toml:"compiled_name"`
+
+	// ToolchainID identifies the installed compiler (and required programs), so cached compilations are not reused after an upgrade.
+	// It's computed when the languages are loaded
+	ToolchainID string `toml:"-"`
+
+	// TimeMultiplier scales the time limit of problems for this language. 0 means no scaling.
+	// MemoryOverhead (in kilobytes) is added to the memory limit, to account for the runtime.
+	// Both can be overridden per problem
+	TimeMultiplier float64 `toml:"time_multiplier"`
+	MemoryOverhead int     `toml:"memory_overhead"`
+}
+
+// CompileProfile selects which compile command of a language is used
+type CompileProfile string
+
+const (
+	ProfileDefault CompileProfile = ""
+	// ProfileDebug uses DebugCompileCommand, for debug runs
+	ProfileDebug CompileProfile = "debug"
+)
+
+// CompileCmd returns the compile command of the given profile
+func (l Language) CompileCmd(profile CompileProfile) []string {
+	if profile == ProfileDebug {
+		return l.DebugCompileCommand
+	}
+	return l.CompileCommand
+}
+
+// SupportsDebugRuns returns true if programs in this language can be compiled with the debug profile
+func (l Language) SupportsDebugRuns() bool {
+	return l.Compiled && len(l.DebugCompileCommand) > 0
+}
+
+// RunCmd returns the full run command of a program with the given memory limit (in kilobytes, without the language overhead)
+func (l Language) RunCmd(memoryLimit int) ([]string, error) {
+	cmd, err := MakeGoodCommand(l.RunCommand)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cmd {
+		cmd[i] = strings.ReplaceAll(cmd[i], MagicMemoryLimit, strconv.Itoa(memoryLimit))
+	}
+	return cmd, nil
+}
+
+// Limits returns the time limit (in seconds) and memory limit (in kilobytes) a program in this language gets,
+// given the limits of the problem and its overrides for this language, if any
+func (l Language) Limits(timeLimit float64, memoryLimit int, override *kilonova.LanguageLimits) (float64, int) {
+	multiplier, overhead := l.TimeMultiplier, l.MemoryOverhead
+	if override != nil && override.TimeMultiplier != nil {
+		multiplier = *override.TimeMultiplier
+	}
+	if override != nil && override.MemoryOverhead != nil {
+		overhead = *override.MemoryOverhead
+	}
+	if multiplier > 0 {
+		timeLimit *= multiplier
+	}
+	return timeLimit, memoryLimit + overhead
 }
 
 // Directory represents a directory rule
@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+var (
+	languagesPath string
+	// loadLangsMu makes sure concurrent reloads don't overwrite each other
+	loadLangsMu sync.Mutex
+)
+
+// SetLanguagesPath sets the path of the languages file. By convention, it's languages.toml, next to config.toml
+func SetLanguagesPath(path string) {
+	languagesPath = path
+}
+
+// LoadLanguages (re)loads the languages file on top of the built-in languages and disables the languages not available on the system.
+// If the file is invalid, the current languages are kept
+func LoadLanguages() error {
+	loadLangsMu.Lock()
+	defer loadLangsMu.Unlock()
+
+	newLangs, err := readLanguages(languagesPath)
+	if err != nil {
+		return err
+	}
+	checkLanguages(newLangs)
+	langs.Store(&newLangs)
+	return nil
+}
+
+// readLanguages decodes the languages file, if it exists. Every table either overrides some fields of a built-in language,
+// or defines a new language, such as:
+//
+//	[rust]
+//	extensions = [".rs"]
+//	compiled = true
+//	printable_name = "Rust"
+//	compile_command = ["rustc", "--edition=2021", "-O", "-o", "/box/output", "<REPLACE>"]
+//	run_command = ["/box/output"]
+//	source_name = "/box/main.rs"
+//	compiled_name = "/box/output"
+func readLanguages(path string) (map[string]Language, error) {
+	langs := builtinLanguages()
+	if path == "" {
+		return langs, nil
+	}
+
+	var file map[string]toml.Primitive
+	md, err := toml.DecodeFile(path, &file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return langs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read languages file: %w", err)
+	}
+
+	for name, prim := range file {
+		lang := langs[name]
+		if err := md.PrimitiveDecode(prim, &lang); err != nil {
+			return nil, fmt.Errorf("invalid language %q: %w", name, err)
+		}
+		if lang.InternalName == "" {
+			lang.InternalName = name
+		}
+		if lang.PrintableName == "" {
+			lang.PrintableName = name
+		}
+		if err := validateLanguage(name, lang); err != nil {
+			return nil, err
+		}
+		langs[name] = lang
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, fmt.Errorf("unknown keys in languages file: %s", strings.Join(keys, ", "))
+	}
+
+	zap.S().Infof("Loaded %d language definition(s) from %s", len(file), path)
+	return langs, nil
+}
+
+func validateLanguage(name string, lang Language) error {
+	switch {
+	case lang.InternalName != name:
+		return fmt.Errorf("language %q has a different internal name (%q)", name, lang.InternalName)
+	case len(lang.Extensions) == 0:
+		return fmt.Errorf("language %q has no extensions", name)
+	case lang.SourceName == "":
+		return fmt.Errorf("language %q has no source name", name)
+	case len(lang.RunCommand) == 0:
+		return fmt.Errorf("language %q has no run command", name)
+	case lang.TimeMultiplier < 0:
+		return fmt.Errorf("language %q has a negative time multiplier", name)
+	case lang.MemoryOverhead < 0:
+		return fmt.Errorf("language %q has a negative memory overhead", name)
+	}
+	if lang.Compiled {
+		switch {
+		case len(lang.CompileCommand) == 0:
+			return fmt.Errorf("language %q is compiled, but has no compile command", name)
+		case !slices.Contains(lang.CompileCommand, MagicReplace):
+			return fmt.Errorf("compile command of language %q must contain %q, which is replaced with the source files", name, MagicReplace)
+		case lang.CompiledName == "":
+			return fmt.Errorf("language %q is compiled, but has no compiled name", name)
+		case len(lang.DebugCompileCommand) > 0 && !slices.Contains(lang.DebugCompileCommand, MagicReplace):
+			return fmt.Errorf("debug compile command of language %q must contain %q", name, MagicReplace)
+		}
+	}
+	return nil
+}
+
+// builtinLanguages returns a deep copy of the built-in languages, since decoding the languages file may reuse their slices and maps
+func builtinLanguages() map[string]Language {
+	langs := make(map[string]Language, len(builtinLangs))
+	for name, lang := range builtinLangs {
+		lang.Extensions = slices.Clone(lang.Extensions)
+		lang.SimilarLangs = slices.Clone(lang.SimilarLangs)
+		lang.CompileCommand = slices.Clone(lang.CompileCommand)
+		lang.RunCommand = slices.Clone(lang.RunCommand)
+		lang.DebugCompileCommand = slices.Clone(lang.DebugCompileCommand)
+		lang.Mounts = slices.Clone(lang.Mounts)
+		lang.RunMounts = slices.Clone(lang.RunMounts)
+		lang.Requires = slices.Clone(lang.Requires)
+		lang.BuildEnv = maps.Clone(lang.BuildEnv)
+		lang.RunEnv = maps.Clone(lang.RunEnv)
+		lang.DebugRunEnv = maps.Clone(lang.DebugRunEnv)
+		langs[name] = lang
+	}
+	return langs
+}
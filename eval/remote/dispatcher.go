@@ -0,0 +1,479 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	WorkerToken = config.GenFlag[string]("feature.grader.worker_token", "", "Token used by remote grader workers to authenticate. Remote workers are disabled if it's empty")
+
+	// ErrNoWorkers is returned when there is no connected worker that can run the job
+	ErrNoWorkers = errors.New("no remote worker available")
+)
+
+const (
+	// workerTimeout is the time after which a worker that didn't poll or send a heartbeat is considered lost
+	workerTimeout = 45 * time.Second
+	// jobTimeout is the maximum time a worker may take to finish a job
+	jobTimeout = 5 * time.Minute
+	// pollTimeout is how long a job request is held until there is a job for the worker
+	pollTimeout = 25 * time.Second
+)
+
+type WorkerInfo struct {
+	ID        int
+	Name      string
+	Boxes     int
+	Languages []string
+
+	LastSeen time.Time
+}
+
+type pendingJob struct {
+	job *Job
+
+	// worker is the ID of the worker running the job, or 0 if it's still queued
+	worker     int
+	assignedAt time.Time
+
+	done chan *JobResult
+}
+
+// Dispatcher is the main node side of the protocol. It keeps track of the connected workers and the jobs handed out to them
+type Dispatcher struct {
+	token string
+
+	compileBucket *datastore.Bucket
+	subtestBucket *datastore.Bucket
+	testBucket    *datastore.Bucket
+
+	mu           sync.Mutex
+	workers      map[int]*WorkerInfo
+	lastWorkerID int
+	lastJobID    int64
+	jobs         map[string]*pendingJob
+	queue        []*pendingJob
+	// notify is closed (and replaced) whenever a new job is queued, to wake up polling workers
+	notify chan struct{}
+}
+
+// NewDispatcher creates a dispatcher that reads and writes binaries, test data and outputs in the given buckets
+func NewDispatcher(token string, compileBucket, subtestBucket, testBucket *datastore.Bucket) *Dispatcher {
+	return &Dispatcher{
+		token: token,
+
+		compileBucket: compileBucket,
+		subtestBucket: subtestBucket,
+		testBucket:    testBucket,
+
+		workers: make(map[int]*WorkerInfo),
+		jobs:    make(map[string]*pendingJob),
+		notify:  make(chan struct{}),
+	}
+}
+
+// Start removes lost workers and stuck jobs until the context is canceled
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.reap()
+		}
+	}
+}
+
+// Available reports if there is a connected worker that can run programs in the given language.
+// It's safe to call on a nil dispatcher
+func (d *Dispatcher) Available(lang string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.supportedLocked(lang)
+}
+
+func (d *Dispatcher) supportedLocked(lang string) bool {
+	for _, w := range d.workers {
+		if time.Since(w.LastSeen) < workerTimeout && slices.Contains(w.Languages, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// Workers returns the currently connected workers
+func (d *Dispatcher) Workers() []WorkerInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	workers := make([]WorkerInfo, 0, len(d.workers))
+	for _, w := range d.workers {
+		workers = append(workers, *w)
+	}
+	slices.SortFunc(workers, func(a, b WorkerInfo) int { return a.ID - b.ID })
+	return workers
+}
+
+// Compile compiles a submission on a remote worker. The binary is saved in the compiles bucket, just like a local compilation.
+// Helpers (requests with OutName set) are not supported, they must be compiled locally
+func (d *Dispatcher) Compile(ctx context.Context, req *tasks.CompileRequest) (*tasks.CompileResponse, error) {
+	if req.OutName != "" || req.ID <= 0 {
+		return nil, errors.New("only submissions can be compiled remotely")
+	}
+	res, err := d.run(ctx, &Job{Type: JobCompile, Compile: &CompileJob{
+		SubID:       req.ID,
+		CodeFiles:   req.CodeFiles,
+		HeaderFiles: req.HeaderFiles,
+		Lang:        req.Lang,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if res.Compile == nil {
+		return nil, errors.New("worker didn't send compilation result")
+	}
+	return res.Compile, nil
+}
+
+// Execute runs a submission on a remote worker. The test input is read by the worker from the tests bucket using testID,
+// so req.TestInput is not consumed and may still be used to run the test locally if an error is returned
+func (d *Dispatcher) Execute(ctx context.Context, req *tasks.ExecRequest, testID int) (*tasks.ExecResponse, error) {
+	res, err := d.run(ctx, &Job{Type: JobExecute, Execute: &ExecuteJob{
+		SubID:       req.SubID,
+		SubtestID:   req.SubtestID,
+		TestID:      testID,
+		Filename:    req.Filename,
+		MemoryLimit: req.MemoryLimit,
+		TimeLimit:   req.TimeLimit,
+		Lang:        req.Lang,
+
+		LanguageLimits: req.LanguageLimits,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if res.Execute == nil {
+		return nil, errors.New("worker didn't send execution result")
+	}
+	return res.Execute, nil
+}
+
+func (d *Dispatcher) run(ctx context.Context, job *Job) (*JobResult, error) {
+	d.mu.Lock()
+	if !d.supportedLocked(job.lang()) {
+		d.mu.Unlock()
+		return nil, ErrNoWorkers
+	}
+	d.lastJobID++
+	job.ID = strconv.FormatInt(d.lastJobID, 10)
+	pJob := &pendingJob{job: job, done: make(chan *JobResult, 1)}
+	d.jobs[job.ID] = pJob
+	d.queue = append(d.queue, pJob)
+	close(d.notify)
+	d.notify = make(chan struct{})
+	d.mu.Unlock()
+
+	select {
+	case res := <-pJob.done:
+		if res.Error != "" {
+			return nil, fmt.Errorf("remote worker error: %s", res.Error)
+		}
+		return res, nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		d.removeJobLocked(pJob)
+		d.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// finishJobLocked removes the job and sends the result to the waiting grader
+func (d *Dispatcher) finishJobLocked(pJob *pendingJob, res *JobResult) {
+	if _, ok := d.jobs[pJob.job.ID]; !ok {
+		return
+	}
+	d.removeJobLocked(pJob)
+	pJob.done <- res
+}
+
+func (d *Dispatcher) removeJobLocked(pJob *pendingJob) {
+	delete(d.jobs, pJob.job.ID)
+	d.queue = slices.DeleteFunc(d.queue, func(j *pendingJob) bool { return j == pJob })
+}
+
+// reap removes workers that haven't been seen in a while. Their jobs are queued again, unless no other worker can run them.
+// Jobs that take too long are failed, so they can be run locally
+func (d *Dispatcher) reap() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id, w := range d.workers {
+		if time.Since(w.LastSeen) > workerTimeout {
+			zap.S().Warnf("Lost remote worker %d (%s)", id, w.Name)
+			delete(d.workers, id)
+		}
+	}
+	for _, pJob := range d.jobs {
+		if pJob.worker == 0 {
+			continue
+		}
+		if _, ok := d.workers[pJob.worker]; !ok {
+			pJob.worker = 0
+			d.queue = append(d.queue, pJob)
+		} else if time.Since(pJob.assignedAt) > jobTimeout {
+			d.finishJobLocked(pJob, &JobResult{Error: "job timed out"})
+		}
+	}
+	for _, pJob := range slices.Clone(d.queue) {
+		if !d.supportedLocked(pJob.job.lang()) {
+			d.finishJobLocked(pJob, &JobResult{Error: ErrNoWorkers.Error()})
+		}
+	}
+	close(d.notify)
+	d.notify = make(chan struct{})
+}
+
+// nextJob waits for a job that the worker can run
+func (d *Dispatcher) nextJob(ctx context.Context, workerID int) (*Job, bool) {
+	timer := time.NewTimer(pollTimeout)
+	defer timer.Stop()
+	for {
+		d.mu.Lock()
+		w, ok := d.workers[workerID]
+		if !ok {
+			d.mu.Unlock()
+			return nil, false
+		}
+		w.LastSeen = time.Now()
+		for i, pJob := range d.queue {
+			if slices.Contains(w.Languages, pJob.job.lang()) {
+				d.queue = slices.Delete(d.queue, i, i+1)
+				pJob.worker = workerID
+				pJob.assignedAt = time.Now()
+				d.mu.Unlock()
+				return pJob.job, true
+			}
+		}
+		notify := d.notify
+		d.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, true
+		case <-ctx.Done():
+			return nil, true
+		}
+	}
+}
+
+// Handler returns the HTTP handler with the worker endpoints
+func (d *Dispatcher) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(d.authenticate)
+	r.Post("/register", d.register)
+	r.With(d.workerContext).Post("/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.With(d.workerContext).Get("/jobs/next", d.pollJob)
+	r.Route("/jobs/{jobID}", func(r chi.Router) {
+		r.Use(d.workerContext)
+		r.Use(d.jobContext)
+		r.Get("/input", d.serveInput)
+		r.Get("/binary", d.serveBinary)
+		r.Put("/artifact", d.saveArtifact)
+		r.Post("/result", d.saveResult)
+	})
+	return r
+}
+
+type ctxKey string
+
+const (
+	workerKey ctxKey = "worker"
+	jobKey    ctxKey = "job"
+)
+
+func (d *Dispatcher) authenticate(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + d.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "Invalid worker token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// workerContext makes sure the request comes from a registered worker, and marks it as seen
+func (d *Dispatcher) workerContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		workerID, err := strconv.Atoi(r.Header.Get(WorkerHeader))
+		if err != nil {
+			http.Error(w, "Invalid worker ID", http.StatusBadRequest)
+			return
+		}
+		d.mu.Lock()
+		worker, ok := d.workers[workerID]
+		if ok {
+			worker.LastSeen = time.Now()
+		}
+		d.mu.Unlock()
+		if !ok {
+			// Workers should register again after receiving this
+			http.Error(w, "Unknown worker", http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workerKey, workerID)))
+	})
+}
+
+// jobContext makes sure the job exists and is assigned to the requesting worker
+func (d *Dispatcher) jobContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		workerID := r.Context().Value(workerKey).(int)
+		d.mu.Lock()
+		pJob, ok := d.jobs[chi.URLParam(r, "jobID")]
+		if ok && pJob.worker != workerID {
+			ok = false
+		}
+		d.mu.Unlock()
+		if !ok {
+			// The job might have been canceled or reassigned in the meantime
+			http.Error(w, "Job not found", http.StatusGone)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jobKey, pJob)))
+	})
+}
+
+func (d *Dispatcher) register(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid registration request", http.StatusBadRequest)
+		return
+	}
+	if req.Boxes <= 0 {
+		http.Error(w, "Worker must have at least one box", http.StatusBadRequest)
+		return
+	}
+
+	d.mu.Lock()
+	d.lastWorkerID++
+	id := d.lastWorkerID
+	d.workers[id] = &WorkerInfo{
+		ID:        id,
+		Name:      req.Name,
+		Boxes:     req.Boxes,
+		Languages: req.Languages,
+		LastSeen:  time.Now(),
+	}
+	d.mu.Unlock()
+
+	zap.S().Infof("Registered remote worker %d (%s) with %d boxes", id, req.Name, req.Boxes)
+	writeJSON(w, &RegisterResponse{WorkerID: id})
+}
+
+func (d *Dispatcher) pollJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := d.nextJob(r.Context(), r.Context().Value(workerKey).(int))
+	if !ok {
+		http.Error(w, "Unknown worker", http.StatusNotFound)
+		return
+	}
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, job)
+}
+
+func (d *Dispatcher) serveInput(w http.ResponseWriter, r *http.Request) {
+	pJob := r.Context().Value(jobKey).(*pendingJob)
+	if pJob.job.Type != JobExecute {
+		http.Error(w, "Job has no test input", http.StatusBadRequest)
+		return
+	}
+	d.serveFile(w, d.testBucket, strconv.Itoa(pJob.job.Execute.TestID)+".in")
+}
+
+func (d *Dispatcher) serveBinary(w http.ResponseWriter, r *http.Request) {
+	pJob := r.Context().Value(jobKey).(*pendingJob)
+	if pJob.job.Type != JobExecute {
+		http.Error(w, "Job has no binary", http.StatusBadRequest)
+		return
+	}
+	d.serveFile(w, d.compileBucket, strconv.Itoa(pJob.job.Execute.SubID)+".bin")
+}
+
+func (d *Dispatcher) serveFile(w http.ResponseWriter, bucket *datastore.Bucket, name string) {
+	f, err := bucket.Reader(name)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		zap.S().Warn("Couldn't send file to remote worker: ", err)
+	}
+}
+
+// saveArtifact stores the compiled binary or the program output, as they would be stored by a local run
+func (d *Dispatcher) saveArtifact(w http.ResponseWriter, r *http.Request) {
+	pJob := r.Context().Value(jobKey).(*pendingJob)
+	var err error
+	switch pJob.job.Type {
+	case JobCompile:
+		err = d.compileBucket.WriteFile(strconv.Itoa(pJob.job.Compile.SubID)+".bin", r.Body, 0777)
+	case JobExecute:
+		err = d.subtestBucket.WriteFile(strconv.Itoa(pJob.job.Execute.SubtestID), r.Body, 0644)
+	default:
+		http.Error(w, "Invalid job type", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		zap.S().Warn("Couldn't save remote worker artifact: ", err)
+		http.Error(w, "Couldn't save artifact", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *Dispatcher) saveResult(w http.ResponseWriter, r *http.Request) {
+	pJob := r.Context().Value(jobKey).(*pendingJob)
+	var res JobResult
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		http.Error(w, "Invalid job result", http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	d.finishJobLocked(pJob, &res)
+	d.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, val any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(val); err != nil {
+		zap.S().Warn("Couldn't encode response for remote worker: ", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package remote implements the protocol used by the main node to hand out compile and execute jobs to remote grader workers.
+//
+// Workers register themselves, long-poll for jobs, fetch the data they need (test inputs, compiled binaries),
+// upload the produced artifact (compiled binary or program output) and finally post the result.
+// The main node keeps the scheduler role: it decides what gets evaluated and when, and still runs checkers,
+// interactive and communication tasks locally.
+package remote
+
+import (
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+)
+
+// WorkerHeader identifies the worker making the request, for all requests except registration
+const WorkerHeader = "Kilonova-Worker"
+
+type JobType string
+
+const (
+	JobCompile JobType = "compile"
+	JobExecute JobType = "execute"
+)
+
+type RegisterRequest struct {
+	Name string `json:"name"`
+	// Boxes is the number of jobs the worker runs at once
+	Boxes int `json:"boxes"`
+	// Languages are the internal names of the languages enabled on the worker
+	Languages []string `json:"languages"`
+}
+
+type RegisterResponse struct {
+	WorkerID int `json:"worker_id"`
+}
+
+type Job struct {
+	ID   string  `json:"id"`
+	Type JobType `json:"type"`
+
+	Compile *CompileJob `json:"compile,omitempty"`
+	Execute *ExecuteJob `json:"execute,omitempty"`
+}
+
+// CompileJob compiles a submission. The binary is uploaded as the job artifact
+type CompileJob struct {
+	SubID       int               `json:"sub_id"`
+	CodeFiles   map[string][]byte `json:"code_files"`
+	HeaderFiles map[string][]byte `json:"header_files"`
+	Lang        string            `json:"lang"`
+}
+
+// ExecuteJob runs a compiled submission on a test. The binary and test input are downloaded from the job endpoints,
+// and the program output is uploaded as the job artifact
+type ExecuteJob struct {
+	SubID       int     `json:"sub_id"`
+	SubtestID   int     `json:"subtest_id"`
+	TestID      int     `json:"test_id"`
+	Filename    string  `json:"filename"`
+	MemoryLimit int     `json:"memory_limit"`
+	TimeLimit   float64 `json:"time_limit"`
+	Lang        string  `json:"lang"`
+
+	// LanguageLimits are the problem overrides for the language, applied by the worker on top of its own language definitions
+	LanguageLimits *kilonova.LanguageLimits `json:"language_limits,omitempty"`
+}
+
+func (j *Job) lang() string {
+	switch j.Type {
+	case JobCompile:
+		return j.Compile.Lang
+	case JobExecute:
+		return j.Execute.Lang
+	default:
+		return ""
+	}
+}
+
+type JobResult struct {
+	Compile *tasks.CompileResponse `json:"compile,omitempty"`
+	Execute *tasks.ExecResponse    `json:"execute,omitempty"`
+
+	// Error is set if the worker couldn't run the job. The main node may then run it locally
+	Error string `json:"error,omitempty"`
+}
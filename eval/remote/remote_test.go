@@ -0,0 +1,220 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"go.uber.org/zap"
+)
+
+// fakeSandbox keeps files in memory. "Compiling" concatenates the sources into the binary,
+// and "running" the binary writes its contents followed by the input to the output file
+type fakeSandbox struct {
+	id    int
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (b *fakeSandbox) ReadFile(path string, w io.Writer) error {
+	b.mu.Lock()
+	data, ok := b.files[path]
+	b.mu.Unlock()
+	if !ok {
+		return fs.ErrNotExist
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func (b *fakeSandbox) WriteFile(path string, r io.Reader, _ fs.FileMode) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files[path] = data
+	return nil
+}
+
+func (b *fakeSandbox) FileExists(path string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.files[path]
+	return ok
+}
+
+func (b *fakeSandbox) GetID() int         { return b.id }
+func (b *fakeSandbox) MemoryQuota() int64 { return 0 }
+func (b *fakeSandbox) Close() error       { return nil }
+
+func (b *fakeSandbox) RunCommand(_ context.Context, cmd []string, conf *eval.RunConfig) (*eval.RunStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cmd[0] == "/box/output" {
+		b.files[conf.OutputPath] = append(bytes.Clone(b.files["/box/output"]), b.files[conf.InputPath]...)
+		return &eval.RunStats{Time: 0.1, Memory: 1024}, nil
+	}
+	var bin []byte
+	for _, arg := range cmd {
+		if strings.HasSuffix(arg, ".c") {
+			bin = append(bin, b.files[arg]...)
+		}
+	}
+	b.files["/box/output"] = bin
+	b.files[conf.OutputPath] = []byte("compiled")
+	return &eval.RunStats{}, nil
+}
+
+type fakeScheduler struct {
+	mu     sync.Mutex
+	lastID int
+	boxes  int64
+}
+
+func (s *fakeScheduler) SubRunner(context.Context, int64) (eval.BoxScheduler, error) { return s, nil }
+func (s *fakeScheduler) NumConcurrent() int64                                        { return s.boxes }
+func (s *fakeScheduler) ReleaseBox(eval.Sandbox)                                     {}
+func (s *fakeScheduler) Close(context.Context) error                                 { return nil }
+
+func (s *fakeScheduler) GetBox(context.Context, int64) (eval.Sandbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	return &fakeSandbox{id: s.lastID, files: make(map[string][]byte)}, nil
+}
+
+func readBucketFile(t *testing.T, bucket *datastore.Bucket, name string) string {
+	t.Helper()
+	r, err := bucket.Reader(name)
+	if err != nil {
+		t.Fatalf("Couldn't open %q from bucket %s: %v", name, bucket.Name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWorker(t *testing.T) {
+	// The worker uses the global datastore, the main node has its own buckets
+	if err := datastore.InitBuckets(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	mainDir := t.TempDir()
+	var mainBuckets []*datastore.Bucket
+	for _, name := range []string{"compiles", "subtests", "tests"} {
+		bucket, err := datastore.NewBucket(mainDir, name, datastore.NoCompression, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mainBuckets = append(mainBuckets, bucket)
+	}
+	compiles, subtests, tests := mainBuckets[0], mainBuckets[1], mainBuckets[2]
+	if err := tests.WriteFile("3.in", strings.NewReader("1 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dispatcher := NewDispatcher("secret", compiles, subtests, tests)
+	server := httptest.NewServer(dispatcher.Handler())
+	defer server.Close()
+
+	t.Run("unauthorized", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/register", "application/json", strings.NewReader(`{"boxes": 1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	if dispatcher.Available("c") {
+		t.Fatal("Dispatcher reports workers before any registered")
+	}
+	if _, err := dispatcher.Compile(ctx, &tasks.CompileRequest{ID: 1, Lang: "c"}); err != ErrNoWorkers {
+		t.Fatalf("Expected ErrNoWorkers, got %v", err)
+	}
+
+	worker := NewWorker(server.URL, "secret", "test", &fakeScheduler{boxes: 2}, zap.NewNop().Sugar())
+	go worker.Run(ctx)
+	for !dispatcher.Available("c") {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Worker didn't register")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if workers := dispatcher.Workers(); len(workers) != 1 || workers[0].Boxes != 2 {
+		t.Fatalf("Unexpected workers: %#v", workers)
+	}
+
+	compileResp, err := dispatcher.Compile(ctx, &tasks.CompileRequest{
+		ID:        5,
+		CodeFiles: map[string][]byte{"/box/main.c": []byte("BIN|")},
+		Lang:      "c",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compileResp.Success || compileResp.Output != "compiled" {
+		t.Fatalf("Unexpected compilation response: %#v", compileResp)
+	}
+	if bin := readBucketFile(t, compiles, "5.bin"); bin != "BIN|" {
+		t.Fatalf("Unexpected binary %q", bin)
+	}
+
+	var wg sync.WaitGroup
+	for subtestID := 10; subtestID < 14; subtestID++ {
+		wg.Add(1)
+		go func(subtestID int) {
+			defer wg.Done()
+			execResp, err := dispatcher.Execute(ctx, &tasks.ExecRequest{
+				SubID:       5,
+				SubtestID:   subtestID,
+				Filename:    "stdin",
+				MemoryLimit: 1024,
+				TimeLimit:   1,
+				Lang:        "c",
+			}, 3)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if execResp.Comments != "" || execResp.Time != 0.1 {
+				t.Errorf("Unexpected execution response: %#v", execResp)
+			}
+		}(subtestID)
+	}
+	wg.Wait()
+
+	for subtestID := 10; subtestID < 14; subtestID++ {
+		if out := readBucketFile(t, subtests, fmt.Sprint(subtestID)); out != "BIN|1 2\n" {
+			t.Fatalf("Unexpected output %q", out)
+		}
+		if _, err := datastore.GetBucket(datastore.BucketTypeSubtests).Stat(fmt.Sprint(subtestID)); err == nil {
+			t.Fatal("Worker didn't clean up the uploaded output")
+		}
+	}
+	if _, err := datastore.GetBucket(datastore.BucketTypeCompiles).Stat("5.bin"); err == nil {
+		t.Fatal("Worker didn't clean up the downloaded binary")
+	}
+}
@@ -0,0 +1,365 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"go.uber.org/zap"
+)
+
+var errUnknownWorker = errors.New("worker is not registered")
+
+// Worker is the remote side of the protocol. It runs the jobs using the local boxes and datastore,
+// with one job poller for every box of the runner
+type Worker struct {
+	server string
+	token  string
+	name   string
+
+	runner eval.BoxScheduler
+	logger *zap.SugaredLogger
+	client *http.Client
+
+	// regMu makes sure only one poller registers the worker again after the main node forgets it
+	regMu sync.Mutex
+	idMu  sync.Mutex
+	id    int
+
+	// binRefs counts the running jobs of every submission, so the downloaded binary is removed only after the last one finishes
+	binMu   sync.Mutex
+	binRefs map[int]int
+}
+
+// NewWorker creates a worker for the main node at the given address (ie. https://kilonova.ro/grader)
+func NewWorker(server, token, name string, runner eval.BoxScheduler, logger *zap.SugaredLogger) *Worker {
+	return &Worker{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		name:   name,
+
+		runner: runner,
+		logger: logger,
+		client: &http.Client{},
+
+		binRefs: make(map[int]int),
+	}
+}
+
+// Run registers the worker and runs jobs until the context is canceled
+func (w *Worker) Run(ctx context.Context) error {
+	if err := w.register(ctx, 0); err != nil {
+		return err
+	}
+
+	go w.heartbeat(ctx)
+
+	var wg sync.WaitGroup
+	for i := int64(0); i < w.runner.NumConcurrent(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.pollLoop(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) workerID() int {
+	w.idMu.Lock()
+	defer w.idMu.Unlock()
+	return w.id
+}
+
+// register registers the worker with the main node, retrying until it succeeds or the context is canceled.
+// If staleID is not the current ID, another poller already registered again, so nothing is done
+func (w *Worker) register(ctx context.Context, staleID int) error {
+	w.regMu.Lock()
+	defer w.regMu.Unlock()
+	if w.workerID() != staleID {
+		return nil
+	}
+
+	var langs []string
+	for name, lang := range eval.Langs() {
+		if !lang.Disabled {
+			langs = append(langs, name)
+		}
+	}
+	slices.Sort(langs)
+	body, err := json.Marshal(&RegisterRequest{Name: w.name, Boxes: int(w.runner.NumConcurrent()), Languages: langs})
+	if err != nil {
+		return err
+	}
+
+	for {
+		var resp RegisterResponse
+		err := w.doJSON(ctx, http.MethodPost, "/register", body, &resp)
+		if err == nil {
+			w.idMu.Lock()
+			w.id = resp.WorkerID
+			w.idMu.Unlock()
+			zap.S().Infof("Registered as remote worker %d", resp.WorkerID)
+			return nil
+		}
+		zap.S().Warn("Couldn't register worker: ", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// heartbeat keeps the worker marked as alive while all pollers are busy running jobs
+func (w *Worker) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(workerTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			id := w.workerID()
+			err := w.doJSON(ctx, http.MethodPost, "/heartbeat", nil, nil)
+			if errors.Is(err, errUnknownWorker) {
+				w.register(ctx, id)
+			} else if err != nil && ctx.Err() == nil {
+				zap.S().Warn("Couldn't send heartbeat: ", err)
+			}
+		}
+	}
+}
+
+func (w *Worker) pollLoop(ctx context.Context) {
+	for ctx.Err() == nil {
+		id := w.workerID()
+		var job Job
+		err := w.doJSON(ctx, http.MethodGet, "/jobs/next", nil, &job)
+		switch {
+		case errors.Is(err, errUnknownWorker):
+			w.register(ctx, id)
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			zap.S().Warn("Couldn't poll for jobs: ", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		case job.ID != "":
+			res := w.runJob(ctx, &job)
+			if err := w.sendResult(ctx, &job, res); err != nil {
+				zap.S().Warnf("Couldn't send result for job %s: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+func (w *Worker) sendResult(ctx context.Context, job *Job, res *JobResult) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return w.doJSON(ctx, http.MethodPost, "/jobs/"+job.ID+"/result", body, nil)
+}
+
+func (w *Worker) runJob(ctx context.Context, job *Job) *JobResult {
+	var res *JobResult
+	var err error
+	switch job.Type {
+	case JobCompile:
+		res, err = w.compile(ctx, job)
+	case JobExecute:
+		res, err = w.execute(ctx, job)
+	default:
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+	if err != nil {
+		w.logger.Warnf("Remote job %s failed: %v", job.ID, err)
+		return &JobResult{Error: err.Error()}
+	}
+	return res
+}
+
+func (w *Worker) compile(ctx context.Context, job *Job) (*JobResult, error) {
+	cJob := job.Compile
+	resp, err := tasks.GetCompileTask(w.logger).Run(ctx, w.runner, 0, &tasks.CompileRequest{
+		ID:          cJob.SubID,
+		CodeFiles:   cJob.CodeFiles,
+		HeaderFiles: cJob.HeaderFiles,
+		Lang:        cJob.Lang,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Success {
+		bucket, name := datastore.GetBucket(datastore.BucketTypeCompiles), binaryName(cJob.SubID)
+		if err := w.uploadArtifact(ctx, job, bucket, name); err != nil {
+			return nil, err
+		}
+		w.binMu.Lock()
+		if w.binRefs[cJob.SubID] == 0 {
+			bucket.RemoveFile(name)
+		}
+		w.binMu.Unlock()
+	}
+	return &JobResult{Compile: resp}, nil
+}
+
+func (w *Worker) execute(ctx context.Context, job *Job) (*JobResult, error) {
+	eJob := job.Execute
+	if err := w.acquireBinary(ctx, job); err != nil {
+		return nil, fmt.Errorf("couldn't download binary: %w", err)
+	}
+	defer w.releaseBinary(eJob.SubID)
+
+	input, err := w.download(ctx, job, "input")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't download test input: %w", err)
+	}
+	defer input.Close()
+
+	req := &tasks.ExecRequest{
+		SubID:       eJob.SubID,
+		SubtestID:   eJob.SubtestID,
+		Filename:    eJob.Filename,
+		MemoryLimit: eJob.MemoryLimit,
+		TimeLimit:   eJob.TimeLimit,
+		Lang:        eJob.Lang,
+		TestInput:   input,
+
+		LanguageLimits: eJob.LanguageLimits,
+	}
+	_, memoryLimit := req.Limits()
+	resp, err := tasks.GetExecuteTask(w.logger).Run(ctx, w.runner, int64(memoryLimit), req)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, name := datastore.GetBucket(datastore.BucketTypeSubtests), strconv.Itoa(eJob.SubtestID)
+	defer bucket.RemoveFile(name)
+	if _, err := bucket.Stat(name); err == nil {
+		if err := w.uploadArtifact(ctx, job, bucket, name); err != nil {
+			return nil, fmt.Errorf("couldn't upload output: %w", err)
+		}
+	}
+	return &JobResult{Execute: resp}, nil
+}
+
+func binaryName(subID int) string {
+	return strconv.Itoa(subID) + ".bin"
+}
+
+// acquireBinary downloads the submission binary in the local compiles bucket, if it's not already used by another job
+func (w *Worker) acquireBinary(ctx context.Context, job *Job) error {
+	subID := job.Execute.SubID
+	w.binMu.Lock()
+	defer w.binMu.Unlock()
+	if w.binRefs[subID] > 0 {
+		w.binRefs[subID]++
+		return nil
+	}
+
+	r, err := w.download(ctx, job, "binary")
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := datastore.GetBucket(datastore.BucketTypeCompiles).WriteFile(binaryName(subID), r, 0777); err != nil {
+		return err
+	}
+	w.binRefs[subID]++
+	return nil
+}
+
+func (w *Worker) releaseBinary(subID int) {
+	w.binMu.Lock()
+	defer w.binMu.Unlock()
+	w.binRefs[subID]--
+	if w.binRefs[subID] <= 0 {
+		delete(w.binRefs, subID)
+		if err := datastore.GetBucket(datastore.BucketTypeCompiles).RemoveFile(binaryName(subID)); err != nil {
+			zap.S().Warn("Couldn't remove downloaded binary: ", err)
+		}
+	}
+}
+
+func (w *Worker) uploadArtifact(ctx context.Context, job *Job, bucket *datastore.Bucket, name string) error {
+	r, err := bucket.Reader(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	resp, err := w.do(ctx, http.MethodPut, "/jobs/"+job.ID+"/artifact", r)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// download returns the body of one of the job files. The caller must close it
+func (w *Worker) download(ctx context.Context, job *Job, file string) (io.ReadCloser, error) {
+	resp, err := w.do(ctx, http.MethodGet, "/jobs/"+job.ID+"/"+file, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (w *Worker) doJSON(ctx context.Context, method, path string, body []byte, out any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	resp, err := w.do(ctx, method, path, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do sends a request to the main node. The response body must be closed if no error is returned
+func (w *Worker) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, w.server+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.token)
+	if id := w.workerID(); id > 0 {
+		req.Header.Set(WorkerHeader, strconv.Itoa(id))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound && strings.TrimSpace(string(msg)) == "Unknown worker" {
+			return nil, errUnknownWorker
+		}
+		return nil, kilonova.Statusf(resp.StatusCode, "%s", strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KiloProjects/kilonova/eval/box"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+type sandboxBackend struct {
+	name    string
+	boxFunc BoxFunc
+	secure  bool
+
+	// available checks if the backend can be used without changing anything, since it's also used when probing the backends
+	available func() error
+	// setup is run once the backend is selected, before creating boxes
+	setup func() error
+}
+
+var sandboxBackends = []sandboxBackend{
+	{"isolate", box.New, true, box.IsolateAvailable, nil},
+	{"native", box.NewNamespace, true, box.NamespaceSandboxAvailable, box.SetupNamespaceSandbox},
+	{"stupid", box.NewStupid, false, nil, nil},
+}
+
+// SandboxBackend returns the sandbox selected by config.Eval.Sandbox, along with its version.
+// If no sandbox is selected, the first one that works is used. The insecure (stupid) sandbox is never used unless allowInsecure is set.
+// If none can be used, the error says why every backend was skipped
+func SandboxBackend(allowInsecure bool) (BoxFunc, string, error) {
+	var unavailable []error
+	for _, backend := range sandboxBackends {
+		if config.Eval.Sandbox != "" && config.Eval.Sandbox != backend.name {
+			continue
+		}
+		if !backend.secure && !allowInsecure {
+			if config.Eval.Sandbox != "" {
+				return nil, "", fmt.Errorf("sandbox %q is insecure and not allowed", backend.name)
+			}
+			unavailable = append(unavailable, fmt.Errorf("sandbox %q is insecure and not allowed", backend.name))
+			continue
+		}
+		if backend.available != nil {
+			if err := backend.available(); err != nil {
+				if config.Eval.Sandbox != "" {
+					return nil, "", fmt.Errorf("sandbox %q is not available: %w", backend.name, err)
+				}
+				zap.S().Infof("Sandbox %q is not available: %v", backend.name, err)
+				unavailable = append(unavailable, fmt.Errorf("sandbox %q is not available: %w", backend.name, err))
+				continue
+			}
+		}
+		// Once set up, the backend is selected, so failures are not hidden by falling back to another backend
+		if backend.setup != nil {
+			if err := backend.setup(); err != nil {
+				return nil, "", fmt.Errorf("couldn't set up sandbox %q: %w", backend.name, err)
+			}
+			if !CheckCanRun(backend.boxFunc) {
+				return nil, "", fmt.Errorf("sandbox %q was set up, but boxes can't be created", backend.name)
+			}
+		} else if !CheckCanRun(backend.boxFunc) {
+			if config.Eval.Sandbox != "" {
+				return nil, "", fmt.Errorf("sandbox %q is not available: boxes can't be created", backend.name)
+			}
+			unavailable = append(unavailable, fmt.Errorf("sandbox %q is not available: boxes can't be created", backend.name))
+			continue
+		}
+
+		version := backend.name
+		switch backend.name {
+		case "isolate":
+			version = box.IsolateVersion()
+		case "stupid":
+			zap.S().Warn("Secure sandbox not found. Using stupid sandbox")
+		}
+		return backend.boxFunc, version, nil
+	}
+	if config.Eval.Sandbox != "" {
+		return nil, "", fmt.Errorf("unknown sandbox %q", config.Eval.Sandbox)
+	}
+	return nil, "", fmt.Errorf("no sandbox available: %w", errors.Join(unavailable...))
+}
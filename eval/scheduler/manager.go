@@ -67,11 +67,17 @@ func (b *BoxManager) GetBox(ctx context.Context, memQuota int64) (eval.Sandbox,
 	}
 	if memQuota > 0 {
 		if err := b.memSem.Acquire(ctx, memQuota); err != nil {
+			b.concSem.Release(1)
 			return nil, err
 		}
 	}
-	box, err := b.boxGenerator(<-b.availableIDs, memQuota, b.logger)
+	id := <-b.availableIDs
+	box, err := b.boxGenerator(id, memQuota, b.logger)
 	if err != nil {
+		// Give everything back, otherwise failed boxes would slowly starve the manager
+		b.availableIDs <- id
+		b.memSem.Release(max(memQuota, 0))
+		b.concSem.Release(1)
 		return nil, err
 	}
 	// b.logger.Infof("Acquired box %d", box.GetID())
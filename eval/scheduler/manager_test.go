@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova/eval"
+	"go.uber.org/zap"
+)
+
+type fakeSandbox struct {
+	id    int
+	quota int64
+}
+
+func (b *fakeSandbox) ReadFile(string, io.Writer) error               { return nil }
+func (b *fakeSandbox) WriteFile(string, io.Reader, fs.FileMode) error { return nil }
+func (b *fakeSandbox) FileExists(string) bool                         { return false }
+func (b *fakeSandbox) GetID() int                                     { return b.id }
+func (b *fakeSandbox) MemoryQuota() int64                             { return b.quota }
+func (b *fakeSandbox) Close() error                                   { return nil }
+func (b *fakeSandbox) RunCommand(context.Context, []string, *eval.RunConfig) (*eval.RunStats, error) {
+	return &eval.RunStats{}, nil
+}
+
+func newFakeSandbox(id int, quota int64, _ *zap.SugaredLogger) (eval.Sandbox, error) {
+	return &fakeSandbox{id: id, quota: quota}, nil
+}
+
+func newTestManager(t *testing.T, count int, maxMemory int64, boxFunc BoxFunc) *BoxManager {
+	t.Helper()
+	mgr, err := New(0, count, maxMemory, zap.NewNop().Sugar(), boxFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mgr
+}
+
+// tryGetBox returns nil if no box is available in a short while
+func tryGetBox(t *testing.T, mgr eval.BoxScheduler, memQuota int64) eval.Sandbox {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	box, err := mgr.GetBox(ctx, memQuota)
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return nil
+	}
+	return box
+}
+
+// expectBoxes checks that exactly n boxes (with the given quota) can be acquired, then releases them
+func expectBoxes(t *testing.T, mgr eval.BoxScheduler, n int, memQuota int64) {
+	t.Helper()
+	var boxes []eval.Sandbox
+	for i := 0; i < n; i++ {
+		box := tryGetBox(t, mgr, memQuota)
+		if box == nil {
+			t.Fatalf("Only %d boxes available, wanted %d", i, n)
+		}
+		boxes = append(boxes, box)
+	}
+	if box := tryGetBox(t, mgr, memQuota); box != nil {
+		t.Fatalf("More than %d boxes available", n)
+	}
+	for _, box := range boxes {
+		mgr.ReleaseBox(box)
+	}
+}
+
+func TestGetBoxConcurrency(t *testing.T) {
+	const count = 3
+	mgr := newTestManager(t, count, 1000, newFakeSandbox)
+
+	var active, maxActive atomic.Int64
+	var mu sync.Mutex
+	usedIDs := make(map[int]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			box, err := mgr.GetBox(context.Background(), 100)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			cur := active.Add(1)
+			for {
+				old := maxActive.Load()
+				if cur <= old || maxActive.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			mu.Lock()
+			if usedIDs[box.GetID()] {
+				t.Errorf("Box ID %d given out twice", box.GetID())
+			}
+			usedIDs[box.GetID()] = true
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			delete(usedIDs, box.GetID())
+			mu.Unlock()
+			active.Add(-1)
+			mgr.ReleaseBox(box)
+		}()
+	}
+	wg.Wait()
+
+	if maxActive.Load() > count {
+		t.Fatalf("%d boxes were active at once, limit is %d", maxActive.Load(), count)
+	}
+	expectBoxes(t, mgr, count, 100)
+}
+
+func TestGetBoxMemory(t *testing.T) {
+	mgr := newTestManager(t, 4, 1000, newFakeSandbox)
+
+	big := tryGetBox(t, mgr, 600)
+	if big == nil {
+		t.Fatal("Couldn't get box")
+	}
+	if box := tryGetBox(t, mgr, 600); box != nil {
+		t.Fatal("Memory quota exceeded")
+	}
+	// The failed attempt must not keep a concurrency slot
+	expectBoxes(t, mgr, 1, 400)
+	expectBoxes(t, mgr, 3, 0)
+
+	mgr.ReleaseBox(big)
+	expectBoxes(t, mgr, 1, 600)
+	expectBoxes(t, mgr, 4, 250)
+}
+
+func TestGetBoxError(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	mgr := newTestManager(t, 2, 1000, func(id int, mem int64, logger *zap.SugaredLogger) (eval.Sandbox, error) {
+		if fail.Load() {
+			return nil, errors.New("box failed")
+		}
+		return newFakeSandbox(id, mem, logger)
+	})
+
+	for i := 0; i < 10; i++ {
+		if _, err := mgr.GetBox(context.Background(), 500); err == nil {
+			t.Fatal("GetBox should fail")
+		}
+	}
+	fail.Store(false)
+	expectBoxes(t, mgr, 2, 500)
+}
+
+func TestSubRunner(t *testing.T) {
+	mgr := newTestManager(t, 4, 1000, newFakeSandbox)
+	ctx := context.Background()
+
+	sub, err := mgr.SubRunner(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.NumConcurrent() != 3 {
+		t.Fatalf("Sub runner has %d concurrent boxes, wanted 3", sub.NumConcurrent())
+	}
+	expectBoxes(t, mgr, 1, 0)
+	expectBoxes(t, sub, 3, 0)
+
+	// The sub runner shares the memory limit of its parent
+	box := tryGetBox(t, mgr, 800)
+	if box == nil {
+		t.Fatal("Couldn't get box")
+	}
+	expectBoxes(t, sub, 1, 200)
+	mgr.ReleaseBox(box)
+
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := mgr.SubRunner(shortCtx, 2); err == nil {
+		t.Fatal("Sub runner shouldn't get more boxes than available")
+	}
+
+	if err := sub.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectBoxes(t, mgr, 4, 0)
+}
@@ -0,0 +1,265 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const managerMemoryLimit = 512 * 1024
+
+type CommunicationExecRequest struct {
+	SubID       int
+	SubtestID   int
+	ProblemID   int
+	MemoryLimit int
+	TimeLimit   float64
+	Lang        string
+	TestInput   io.Reader
+
+	// NumProcesses is the number of contestant processes the manager talks to
+	NumProcesses int
+	// ManagerLang is the language of the manager, used to determine how to run it
+	ManagerLang string
+
+	// LanguageLimits are the overrides of the problem for the submission language, if any
+	LanguageLimits *kilonova.LanguageLimits
+}
+
+// ManagerFilename returns the name of the compiled communication manager of a problem in the checkers bucket
+func ManagerFilename(problemID int) string {
+	return fmt.Sprintf("%d.manager.bin", problemID)
+}
+
+// RunCommunicationTask runs the manager of a communication problem and NumProcesses copies of the submission, each in its own sandbox.
+//
+// The manager receives the test input on stdin and is called as `manager <from_user_0> <to_user_0> <from_user_1> <to_user_1> ...`.
+// Like the standard checker, it must write the score (a number in the [0, 1] range) on stdout and an optional message on stderr.
+//
+// Every contestant process has its stdin and stdout bound to its pair of pipes and gets its index as the first argument,
+// so the grader files can call different entry points depending on it.
+//
+// The manager may open the pipes in any order: the grader keeps both ends of every pair open until the contestant process
+// or the manager exits, so no open blocks waiting for the other side. The usual order is to_user_i for writing, then from_user_i
+// for reading, for every process. The manager should open all the pipes before talking to any process, since a process
+// whose pipes were released (because the manager exited) gets EOF on its stdin and SIGPIPE on writes.
+// Every copy gets the full problem memory limit, accounted for by the scheduler memory quota.
+func RunCommunicationTask(ctx context.Context, mgr eval.BoxScheduler, logger *zap.SugaredLogger, req *CommunicationExecRequest) (*InteractiveExecResponse, error) {
+	resp := &InteractiveExecResponse{}
+	numBoxes := int64(req.NumProcesses + 1)
+	if req.NumProcesses < 1 {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "Communication problems require at least one contestant process")
+	}
+	if mgr.NumConcurrent() < numBoxes {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "Communication problem requires at least %d concurrent boxes", numBoxes)
+	}
+
+	lang, ok := eval.Langs()[req.Lang]
+	if !ok {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "No language found")
+	}
+	managerLang, ok := eval.Langs()[req.ManagerLang]
+	if !ok {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "No language found for manager")
+	}
+	timeLimit, memoryLimit := lang.Limits(req.TimeLimit, req.MemoryLimit, req.LanguageLimits)
+
+	runner, err := mgr.SubRunner(ctx, numBoxes)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close(ctx)
+
+	managerBox, err := runner.GetBox(ctx, managerMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.ReleaseBox(managerBox)
+
+	userBoxes := make([]eval.Sandbox, req.NumProcesses)
+	for i := range userBoxes {
+		userBoxes[i], err = runner.GetBox(ctx, int64(memoryLimit))
+		if err != nil {
+			return nil, err
+		}
+		defer runner.ReleaseBox(userBoxes[i])
+	}
+
+	logger.Infof("Executing communication test %d (for submission #%d) using %d processes, manager in box %d", req.SubtestID, req.SubID, req.NumProcesses, managerBox.GetID())
+
+	if err := managerBox.WriteFile("/box/input.in", req.TestInput, 0644); err != nil {
+		zap.S().Info("Can't write input file:", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	if err := eval.CopyInBox(managerBox, datastore.GetBucket(datastore.BucketTypeCheckers), ManagerFilename(req.ProblemID), managerLang.CompiledName); err != nil {
+		zap.S().Warn("Couldn't copy manager in box: ", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	bucket, fileName := bucketFromIDExec(req.SubID)
+	for _, box := range userBoxes {
+		if err := eval.CopyInBox(box, bucket, fileName, lang.CompiledName); err != nil {
+			zap.S().Warn("Couldn't copy executable in box: ", err)
+			resp.Comments = "translate:internal_error"
+			return resp, err
+		}
+	}
+
+	managerConf := submissionRunConfig(managerLang, 0, managerMemoryLimit, false)
+	managerConf.InputPath = "/box/input.in"
+	managerConf.OutputPath = "/box/manager.out"
+	managerConf.StderrPath = "/box/manager.err"
+
+	managerCmd, err := managerLang.RunCmd(managerMemoryLimit)
+	if err != nil {
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+
+	userConfs := make([]*eval.RunConfig, req.NumProcesses)
+	userFifos := make([]*fifoHolder, req.NumProcesses)
+	for i := range userConfs {
+		fifoDir, err := makeFIFODir()
+		if err != nil {
+			zap.S().Warn("Couldn't create communication pipes: ", err)
+			resp.Comments = "translate:internal_error"
+			return resp, err
+		}
+		defer os.RemoveAll(fifoDir)
+		userFifos[i], err = holdFIFOs(fifoDir)
+		if err != nil {
+			zap.S().Warn("Couldn't open communication pipes: ", err)
+			resp.Comments = "translate:internal_error"
+			return resp, err
+		}
+		defer userFifos[i].release()
+
+		managerFifos := fifoBoxDir + strconv.Itoa(i)
+		managerConf.Directories = append(managerConf.Directories, eval.Directory{In: managerFifos, Out: fifoDir, Opts: "rw"})
+		managerCmd = append(managerCmd, path.Join(managerFifos, "from_sub"), path.Join(managerFifos, "to_sub"))
+
+		userConfs[i] = submissionRunConfig(lang, timeLimit, memoryLimit, false)
+		userConfs[i].InputPath = path.Join(fifoBoxDir, "to_sub")
+		userConfs[i].OutputPath = path.Join(fifoBoxDir, "from_sub")
+		userConfs[i].Directories = append(userConfs[i].Directories, eval.Directory{In: fifoBoxDir, Out: fifoDir, Opts: "rw"})
+	}
+	managerConf.WallTimeLimit = userConfs[0].WallTimeLimit + 1
+
+	userCmd, err := lang.RunCmd(req.MemoryLimit)
+	if err != nil {
+		zap.S().Warnf("MakeGoodCommand returned an error: %q. This is not good, so we'll use the command from the config file. The supplied command was %#v", err, lang.RunCommand)
+		userCmd = lang.RunCommand
+	}
+
+	var wg sync.WaitGroup
+	var managerMeta *eval.RunStats
+	var managerErr error
+	userMetas := make([]*eval.RunStats, req.NumProcesses)
+	userErrs := make([]error, req.NumProcesses)
+
+	wg.Add(req.NumProcesses + 1)
+	go func() {
+		defer wg.Done()
+		managerMeta, managerErr = managerBox.RunCommand(ctx, managerCmd, managerConf)
+		for _, fifos := range userFifos {
+			fifos.release()
+		}
+	}()
+	for i := range userBoxes {
+		go func(i int) {
+			defer wg.Done()
+			userMetas[i], userErrs[i] = userBoxes[i].RunCommand(ctx, append(slices.Clone(userCmd), strconv.Itoa(i)), userConfs[i])
+			userFifos[i].release()
+		}(i)
+	}
+	wg.Wait()
+
+	score, message, managerOK := readManagerVerdict(managerBox)
+	if managerErr != nil || managerMeta == nil {
+		zap.S().Warnf("Manager run error for submission %d: %v", req.SubID, managerErr)
+		managerOK = false
+	}
+
+	// The combined verdict is given by the first process that failed, if any. Otherwise, the manager decides.
+	var failed bool
+	for i := range userBoxes {
+		if userErrs[i] != nil {
+			resp.Comments = fmt.Sprintf("Evaluation error: %v", userErrs[i])
+			return resp, nil
+		}
+		if userMetas[i] == nil {
+			resp.Comments = "translate:internal_error"
+			return resp, nil
+		}
+		resp.Time = max(resp.Time, userMetas[i].Time)
+		resp.Memory = max(resp.Memory, userMetas[i].Memory)
+		// The detailed stats are the ones of the slowest process
+		if resp.Stats == nil || userMetas[i].Time > resp.Stats.Time {
+			resp.Stats = userMetas[i]
+		}
+
+		comments, okExit := runVerdict(logger, userBoxes[i], userMetas[i], req.SubID, req.SubtestID)
+		// Processes killed by SIGPIPE most likely wrote after the manager exited with a verdict, so the manager takes precedence
+		if !failed && !okExit && !(managerOK && userMetas[i].ExitSignal == int(syscall.SIGPIPE)) {
+			resp.Comments = comments
+			failed = true
+		}
+	}
+	if failed {
+		return resp, nil
+	}
+
+	if !managerOK {
+		logger.Warnf("Invalid manager verdict for submission %d (test %d): %#v", req.SubID, req.SubtestID, managerMeta)
+		resp.Comments = "translate:internal_error"
+		return resp, nil
+	}
+
+	resp.Percentage = score.Shift(2)
+	resp.Comments = helperVerdict(resp.Percentage, message)
+	return resp, nil
+}
+
+// readManagerVerdict parses the score (on stdout) and message (on stderr) written by the manager
+func readManagerVerdict(box eval.Sandbox) (decimal.Decimal, string, bool) {
+	var stdout, stderr bytes.Buffer
+	if err := box.ReadFile("/box/manager.out", &stdout); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			zap.S().Warn("Couldn't read manager stdout: ", err)
+		}
+		return decimal.Zero, "", false
+	}
+	if err := box.ReadFile("/box/manager.err", &stderr); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			zap.S().Warn("Couldn't read manager stderr: ", err)
+		}
+		stderr.Reset()
+	}
+
+	score, err := strconv.ParseFloat(strings.TrimSpace(stdout.String()), 64)
+	if err != nil {
+		return decimal.Zero, "", false
+	}
+	return decimal.NewFromFloat(score), strings.TrimSpace(stderr.String()), true
+}
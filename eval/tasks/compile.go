@@ -20,6 +20,13 @@ type CompileRequest struct {
 	CodeFiles   map[string][]byte
 	HeaderFiles map[string][]byte
 	Lang        string
+
+	// OutName, if set, overrides the name of the compiled artifact and saves it in the checkers bucket.
+	// It's used for problem helpers that are not checkers, such as interactors.
+	OutName string
+
+	// Profile selects the compile command of the language, such as the debug one with sanitizers
+	Profile eval.CompileProfile
 }
 
 type CompileResponse struct {
@@ -46,13 +53,21 @@ func GetCompileTask(logger *zap.SugaredLogger) eval.Task[CompileRequest, Compile
 		resp := &CompileResponse{}
 		logger.Infof("Compiling file using box %d", box.GetID())
 
-		lang, ok := eval.Langs[req.Lang]
+		lang, ok := eval.Langs()[req.Lang]
 		if !ok {
 			zap.S().Warnf("Language for submission %d could not be found: %q", req.ID, req.Lang)
 			return resp, kilonova.Statusf(500, "No language found")
 		}
 
+		compileCommand := lang.CompileCmd(req.Profile)
+		if lang.Compiled && len(compileCommand) == 0 {
+			return resp, kilonova.Statusf(400, "Language %q has no %q compile profile", req.Lang, req.Profile)
+		}
+
 		bucket, outName := bucketFromIDExec(req.ID)
+		if req.OutName != "" {
+			bucket, outName = datastore.GetBucket(datastore.BucketTypeCheckers), req.OutName
+		}
 		resp.Success = true
 
 		// If the language is interpreted, just save the code and leave
@@ -70,6 +85,16 @@ func GetCompileTask(logger *zap.SugaredLogger) eval.Task[CompileRequest, Compile
 			return resp, nil
 		}
 
+		var cacheKey string
+		if CompileCacheSize.Value() > 0 {
+			cacheKey = compileCacheKey(lang, compileCommand, req)
+			if output, ok := loadCachedCompile(cacheKey, bucket, outName); ok {
+				logger.Infof("Using cached compilation %s", cacheKey[:16])
+				resp.Output = output
+				return resp, nil
+			}
+		}
+
 		files := make(map[string][]byte)
 		sourceFiles := []string{}
 		for fName, fData := range req.CodeFiles {
@@ -80,7 +105,7 @@ func GetCompileTask(logger *zap.SugaredLogger) eval.Task[CompileRequest, Compile
 			files[fName] = fData
 		}
 
-		out, stats, err := compileFile(ctx, box, files, sourceFiles, lang)
+		out, stats, err := compileFile(ctx, box, files, sourceFiles, lang, compileCommand)
 		resp.Output = out
 		resp.Stats = stats
 
@@ -103,12 +128,16 @@ func GetCompileTask(logger *zap.SugaredLogger) eval.Task[CompileRequest, Compile
 			resp.Other = err.Error()
 			resp.Success = false
 		}
+
+		if resp.Success && cacheKey != "" {
+			saveCompileCache(cacheKey, bucket, outName, resp.Output)
+		}
 		return resp, nil
 	}
 }
 
-// compileFile compiles a file that has the corresponding language
-func compileFile(ctx context.Context, box eval.Sandbox, files map[string][]byte, compiledFiles []string, language eval.Language) (string, *eval.RunStats, error) {
+// compileFile compiles a file that has the corresponding language, using the given compile command of the language
+func compileFile(ctx context.Context, box eval.Sandbox, files map[string][]byte, compiledFiles []string, language eval.Language, compileCommand []string) (string, *eval.RunStats, error) {
 	for fileName, fileData := range files {
 		if err := box.WriteFile(fileName, bytes.NewReader(fileData), 0644); err != nil {
 			zap.S().Warn(err)
@@ -129,10 +158,10 @@ func compileFile(ctx context.Context, box eval.Sandbox, files map[string][]byte,
 	conf.StderrToStdout = true
 	conf.OutputPath = "/box/compilation.out"
 
-	goodCmd, err := makeGoodCompileCommand(language.CompileCommand, compiledFiles)
+	goodCmd, err := makeGoodCompileCommand(compileCommand, compiledFiles)
 	if err != nil {
-		zap.S().Warnf("MakeGoodCompileCommand returned an error: %q. This is not good, so we'll use the command from the config file. The supplied command was %#v", err, language.CompileCommand)
-		goodCmd = language.CompileCommand
+		zap.S().Warnf("MakeGoodCompileCommand returned an error: %q. This is not good, so we'll use the command from the config file. The supplied command was %#v", err, compileCommand)
+		goodCmd = compileCommand
 	}
 
 	stats, err := box.RunCommand(ctx, goodCmd, &conf)
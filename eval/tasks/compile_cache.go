@@ -0,0 +1,130 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var CompileCacheSize = config.GenFlag[int]("feature.grader.compile_cache_size", 2048, "Maximum size (in MB) of the compiled binary cache, used to skip compiling identical sources (ie. on reevaluations). Set to 0 to disable it")
+
+var (
+	// compileCacheMu makes sure cached binaries are not read while being written or evicted
+	compileCacheMu sync.RWMutex
+	// compileCacheTotal is the size of the cache, in bytes. It's -1 until the bucket is first scanned, and is kept up to date
+	// as entries are saved, so the bucket is scanned again only when evicting
+	compileCacheTotal int64 = -1
+)
+
+// compileCacheKey returns the hash of everything that influences the compilation result:
+// the language and its toolchain, the compile command (of the requested profile) and environment, and all the code and header files
+func compileCacheKey(lang eval.Language, compileCommand []string, req *CompileRequest) string {
+	h := sha256.New()
+	writeCacheField(h, lang.InternalName)
+	writeCacheField(h, lang.ToolchainID)
+	writeCacheField(h, strings.Join(compileCommand, "\x00"))
+	for _, key := range sortedKeys(lang.BuildEnv) {
+		writeCacheField(h, key+"="+lang.BuildEnv[key])
+	}
+	for _, files := range []map[string][]byte{req.CodeFiles, req.HeaderFiles} {
+		writeCacheField(h, "files")
+		for _, name := range sortedKeys(files) {
+			writeCacheField(h, name)
+			writeCacheField(h, string(files[name]))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// writeCacheField writes a length-prefixed field, so different file sets can't have the same hash
+func writeCacheField(h hash.Hash, val string) {
+	binary.Write(h, binary.LittleEndian, int64(len(val)))
+	h.Write([]byte(val))
+}
+
+// loadCachedCompile copies the cached binary (if it exists) to the destination bucket and returns the compilation output
+func loadCachedCompile(key string, bucket *datastore.Bucket, outName string) (string, bool) {
+	compileCacheMu.RLock()
+	defer compileCacheMu.RUnlock()
+	cache := datastore.GetBucket(datastore.BucketTypeCompileCache)
+
+	r, err := cache.Reader(key + ".bin")
+	if err != nil {
+		return "", false
+	}
+	defer r.Close()
+	if err := bucket.WriteFile(outName, r, 0777); err != nil {
+		zap.S().Warn("Couldn't copy cached compilation: ", err)
+		return "", false
+	}
+
+	var out bytes.Buffer
+	if r, err := cache.Reader(key + ".out"); err == nil {
+		out.ReadFrom(r)
+		r.Close()
+	}
+
+	if err := cache.Touch(key + ".bin"); err != nil {
+		zap.S().Warn("Couldn't touch cached compilation: ", err)
+	}
+	cache.Touch(key + ".out")
+	return out.String(), true
+}
+
+// saveCompileCache saves a successfully compiled binary in the cache and evicts the least recently used entries if the cache is too big
+func saveCompileCache(key string, bucket *datastore.Bucket, outName string, output string) {
+	compileCacheMu.Lock()
+	defer compileCacheMu.Unlock()
+	cache := datastore.GetBucket(datastore.BucketTypeCompileCache)
+
+	r, err := bucket.Reader(outName)
+	if err != nil {
+		zap.S().Warn("Couldn't read compilation for caching: ", err)
+		return
+	}
+	defer r.Close()
+	if err := cache.WriteFile(key+".bin", r, 0777); err != nil {
+		zap.S().Warn("Couldn't cache compilation: ", err)
+		cache.RemoveFile(key + ".bin")
+		return
+	}
+	if err := cache.WriteFile(key+".out", strings.NewReader(output), 0644); err != nil {
+		zap.S().Warn("Couldn't cache compilation output: ", err)
+	}
+
+	maxSize := int64(CompileCacheSize.Value()) * 1024 * 1024
+	if compileCacheTotal >= 0 {
+		for _, name := range []string{key + ".bin", key + ".out"} {
+			if stat, err := cache.Stat(name); err == nil {
+				compileCacheTotal += stat.Size()
+			}
+		}
+		if compileCacheTotal <= maxSize {
+			return
+		}
+	}
+	total, err := cache.EvictOldest(maxSize)
+	if err != nil {
+		zap.S().Warn("Couldn't evict old cached compilations: ", err)
+	}
+	compileCacheTotal = total
+}
@@ -1,12 +1,16 @@
 package tasks
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"strconv"
 	"strings"
 
+	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/datastore"
 	"github.com/KiloProjects/kilonova/eval"
 	"github.com/davecgh/go-spew/spew"
@@ -21,6 +25,25 @@ type ExecRequest struct {
 	TimeLimit   float64
 	Lang        string
 	TestInput   io.Reader
+
+	// LanguageLimits are the overrides of the problem for the submission language, if any
+	LanguageLimits *kilonova.LanguageLimits
+
+	// BinaryName, if set, is the name of the program in the checkers bucket, used instead of the compiled submission.
+	// It's the counterpart of CompileRequest.OutName
+	BinaryName string
+	// Profile is the compile profile the program was built with. Debug builds also get the debug environment of the language
+	Profile eval.CompileProfile
+	// CaptureStderr saves (the start of) the error output of the program in the response
+	CaptureStderr bool
+	// Output, if set, receives the output of the program instead of the subtests bucket
+	Output io.Writer
+}
+
+// Limits returns the time and memory limits of the submission, scaled for its language.
+// The memory limit should also be used as the box memory quota
+func (r *ExecRequest) Limits() (float64, int) {
+	return eval.Langs()[r.Lang].Limits(r.TimeLimit, r.MemoryLimit, r.LanguageLimits)
 }
 
 type ExecResponse struct {
@@ -28,8 +51,16 @@ type ExecResponse struct {
 	Memory     int
 	ExitStatus int
 	Comments   string
+
+	// Stats are the full stats of the run, if the program was run at all
+	Stats *eval.RunStats
+
+	// Stderr is the error output of the program, if requested
+	Stderr string
 }
 
+const stderrLimit = 16 * 1024 // bytes
+
 func GetExecuteTask(logger *zap.SugaredLogger) eval.Task[ExecRequest, ExecResponse] {
 	return func(ctx context.Context, box eval.Sandbox, req *ExecRequest) (*ExecResponse, error) {
 		resp := &ExecResponse{}
@@ -43,40 +74,40 @@ func GetExecuteTask(logger *zap.SugaredLogger) eval.Task[ExecRequest, ExecRespon
 		consoleInput := req.Filename == "stdin"
 
 		bucket, fileName := bucketFromIDExec(req.SubID)
-		lang := eval.Langs[req.Lang]
+		if req.BinaryName != "" {
+			bucket, fileName = datastore.GetBucket(datastore.BucketTypeCheckers), req.BinaryName
+		}
+		lang := eval.Langs()[req.Lang]
 		if err := eval.CopyInBox(box, bucket, fileName, lang.CompiledName); err != nil {
 			zap.S().Warn("Couldn't copy executable in box: ", err)
 			resp.Comments = "translate:internal_error"
 			return resp, err
 		}
 
-		meta, err := runSubmission(ctx, box, eval.Langs[req.Lang], req.TimeLimit, req.MemoryLimit, consoleInput)
+		timeLimit, memoryLimit := req.Limits()
+		runConf := submissionRunConfig(lang, timeLimit, memoryLimit, consoleInput)
+		if req.Profile == eval.ProfileDebug {
+			for key, val := range lang.DebugRunEnv {
+				runConf.EnvToSet[key] = val
+			}
+		}
+		if req.CaptureStderr {
+			runConf.StderrPath = "/box/stderr.out"
+		}
+		meta, err := runSubmission(ctx, box, lang, req.MemoryLimit, runConf)
 		if err != nil {
 			resp.Comments = fmt.Sprintf("Evaluation error: %v", err)
 			return resp, nil
 		}
+		if req.CaptureStderr {
+			resp.Stderr = readStderr(box, runConf.StderrPath)
+		}
 		resp.Time = meta.Time
 		resp.Memory = meta.Memory
+		resp.Stats = meta
 
-		okExit := false
-		switch meta.Status {
-		case "TO":
-			if strings.Contains(meta.Message, "wall") {
-				resp.Comments = "translate:walltimeout"
-			} else {
-				resp.Comments = "translate:timeout"
-			}
-		case "RE":
-			resp.Comments = meta.Message
-		case "SG":
-			resp.Comments = meta.Message
-		case "XX":
-			resp.Comments = "Sandbox Error: " + meta.Message
-			zap.S().Warn("Sandbox error detected, check grader.log for more detials ", zap.Int("subtest_id", req.SubtestID), zap.Int("box_id", box.GetID()), zap.Int("sub_id", req.SubID))
-			logger.Warn("Sandbox error: ", req.SubID, req.SubtestID, box.GetID(), spew.Sdump(meta))
-		default:
-			okExit = true
-		}
+		var okExit bool
+		resp.Comments, okExit = runVerdict(logger, box, meta, req.SubID, req.SubtestID)
 		if !okExit {
 			return resp, nil
 		}
@@ -87,6 +118,14 @@ func GetExecuteTask(logger *zap.SugaredLogger) eval.Task[ExecRequest, ExecRespon
 			return resp, nil
 		}
 
+		if req.Output != nil {
+			if err := box.ReadFile(boxOut, req.Output); err != nil {
+				resp.Comments = "Could not write output file"
+				zap.S().Warn(err)
+			}
+			return resp, nil
+		}
+
 		pr, pw := io.Pipe()
 		go func() {
 			defer pw.Close()
@@ -105,11 +144,47 @@ func GetExecuteTask(logger *zap.SugaredLogger) eval.Task[ExecRequest, ExecRespon
 	}
 }
 
-// runSubmission runs a program, following the language conventions
-// filenames contains the names for input and output, used if consoleInput is true
-// timeLimit is in seconds, memoryLimit is in kilbytes
-func runSubmission(ctx context.Context, box eval.Sandbox, language eval.Language, timeLimit float64, memoryLimit int, consoleInput bool) (*eval.RunStats, error) {
+// readStderr returns the error output of a program, trimmed to stderrLimit
+func readStderr(box eval.Sandbox, path string) string {
+	var buf bytes.Buffer
+	if err := box.ReadFile(path, &buf); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			zap.S().Warn("Couldn't read program stderr: ", err)
+		}
+		return ""
+	}
+	if buf.Len() > stderrLimit {
+		return strings.ToValidUTF8(string(buf.Bytes()[:stderrLimit]), "") + "... (output trimmed)"
+	}
+	return strings.ToValidUTF8(buf.String(), "\uFFFD")
+}
 
+// runVerdict translates the stats of a submission run into a subtest comment.
+// okExit is true only if the program exited normally and its output should be checked
+func runVerdict(logger *zap.SugaredLogger, box eval.Sandbox, meta *eval.RunStats, subID, subtestID int) (comments string, okExit bool) {
+	switch meta.Status {
+	case "TO":
+		if strings.Contains(meta.Message, "wall") {
+			return "translate:walltimeout", false
+		}
+		return "translate:timeout", false
+	case "RE":
+		return meta.Message, false
+	case "SG":
+		return meta.Message, false
+	case "XX":
+		zap.S().Warn("Sandbox error detected, check grader.log for more detials ", zap.Int("subtest_id", subtestID), zap.Int("box_id", box.GetID()), zap.Int("sub_id", subID))
+		logger.Warn("Sandbox error: ", subID, subtestID, box.GetID(), spew.Sdump(meta))
+		return "Sandbox Error: " + meta.Message, false
+	default:
+		return "", true
+	}
+}
+
+// submissionRunConfig builds the sandbox configuration for running a program, following the language conventions
+// consoleInput marks if the input and output should be redirected from/to stdin.in/stdin.out
+// timeLimit is in seconds, memoryLimit is in kilbytes
+func submissionRunConfig(language eval.Language, timeLimit float64, memoryLimit int, consoleInput bool) *eval.RunConfig {
 	var runConf eval.RunConfig
 	runConf.EnvToSet = make(map[string]string)
 
@@ -118,6 +193,7 @@ func runSubmission(ctx context.Context, box eval.Sandbox, language eval.Language
 	if !language.Compiled {
 		runConf.Directories = append(runConf.Directories, language.Mounts...)
 	}
+	runConf.Directories = append(runConf.Directories, language.RunMounts...)
 
 	for key, val := range language.RunEnv {
 		runConf.EnvToSet[key] = val
@@ -135,11 +211,17 @@ func runSubmission(ctx context.Context, box eval.Sandbox, language eval.Language
 		runConf.OutputPath = "/box/stdin.out"
 	}
 
-	goodCmd, err := eval.MakeGoodCommand(language.RunCommand)
+	return &runConf
+}
+
+// runSubmission runs a program with the given configuration, following the language conventions.
+// memoryLimit is the memory limit of the problem, without the language overhead
+func runSubmission(ctx context.Context, box eval.Sandbox, language eval.Language, memoryLimit int, runConf *eval.RunConfig) (*eval.RunStats, error) {
+	goodCmd, err := language.RunCmd(memoryLimit)
 	if err != nil {
 		zap.S().Warnf("MakeGoodCommand returned an error: %q. This is not good, so we'll use the command from the config file. The supplied command was %#v", err, language.RunCommand)
 		goodCmd = language.RunCommand
 	}
 
-	return box.RunCommand(ctx, goodCmd, &runConf)
+	return box.RunCommand(ctx, goodCmd, runConf)
 }
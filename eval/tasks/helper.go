@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"go.uber.org/zap"
+)
+
+const helperTimeLimit = 10
+
+// HelperMemoryLimit is the memory limit of helpers, in KB. It should also be used as the box memory quota
+const HelperMemoryLimit = 512 * 1024
+
+// HelperRequest runs a compiled problem helper that works with plain files, such as a test generator or validator.
+// The helper gets Args as arguments, Input on stdin and writes its stdout to Output
+type HelperRequest struct {
+	// BinaryName is the name of the helper in the checkers bucket, see CompileRequest.OutName
+	BinaryName string
+	Lang       string
+	Args       []string
+
+	Input  io.Reader
+	Output io.Writer
+}
+
+type HelperResponse struct {
+	// OK is true if the helper exited normally with code 0
+	OK bool
+	// Message describes why the helper failed, followed by its stderr
+	Message string
+
+	Stats *eval.RunStats
+}
+
+// GeneratorFilename returns the name of the compiled test generator of a problem in the checkers bucket
+func GeneratorFilename(problemID int) string {
+	return fmt.Sprintf("%d.generator.bin", problemID)
+}
+
+// ValidatorFilename returns the name of the compiled test validator of a problem in the checkers bucket
+func ValidatorFilename(problemID int) string {
+	return fmt.Sprintf("%d.validator.bin", problemID)
+}
+
+func GetHelperTask(logger *zap.SugaredLogger) eval.Task[HelperRequest, HelperResponse] {
+	return func(ctx context.Context, box eval.Sandbox, req *HelperRequest) (*HelperResponse, error) {
+		resp := &HelperResponse{}
+		logger.Infof("Running helper %q using box %d", req.BinaryName, box.GetID())
+
+		lang, ok := eval.Langs()[req.Lang]
+		if !ok {
+			return nil, kilonova.Statusf(500, "No language found for helper")
+		}
+		if err := eval.CopyInBox(box, datastore.GetBucket(datastore.BucketTypeCheckers), req.BinaryName, lang.CompiledName); err != nil {
+			zap.S().Warn("Couldn't copy helper in box: ", err)
+			return nil, err
+		}
+
+		conf := submissionRunConfig(lang, helperTimeLimit, HelperMemoryLimit, false)
+		conf.OutputPath = "/box/helper.out"
+		conf.StderrPath = "/box/helper.err"
+		if req.Input != nil {
+			if err := box.WriteFile("/box/helper.in", req.Input, 0644); err != nil {
+				return nil, err
+			}
+			conf.InputPath = "/box/helper.in"
+		}
+
+		cmd, err := lang.RunCmd(HelperMemoryLimit)
+		if err != nil {
+			return nil, err
+		}
+		stats, err := box.RunCommand(ctx, append(cmd, req.Args...), conf)
+		if err != nil {
+			return nil, err
+		}
+		resp.Stats = stats
+
+		stderr := readStderr(box, conf.StderrPath)
+		if stats.Status != "" {
+			resp.Message = stats.Message
+			if stderr != "" {
+				resp.Message += "\n" + stderr
+			}
+			return resp, nil
+		}
+		resp.OK = true
+		resp.Message = stderr
+
+		if req.Output != nil {
+			if err := box.ReadFile(conf.OutputPath, req.Output); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+}
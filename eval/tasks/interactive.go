@@ -0,0 +1,301 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	interactorMemoryLimit = 512 * 1024
+
+	// fifoBoxDir is the path where the pipes between the interactor and the submission are mounted, in both sandboxes
+	fifoBoxDir = "/fifo"
+)
+
+type InteractiveExecRequest struct {
+	SubID       int
+	SubtestID   int
+	ProblemID   int
+	MemoryLimit int
+	TimeLimit   float64
+	Lang        string
+	TestInput   io.Reader
+
+	// InteractorLang is the language of the interactor, used to determine how to run it
+	InteractorLang string
+
+	// LanguageLimits are the overrides of the problem for the submission language, if any
+	LanguageLimits *kilonova.LanguageLimits
+}
+
+type InteractiveExecResponse struct {
+	ExecResponse
+
+	// Percentage is the score given by the interactor, in the [0, 100] range
+	Percentage decimal.Decimal
+}
+
+// InteractorFilename returns the name of the compiled interactor of a problem in the checkers bucket
+func InteractorFilename(problemID int) string {
+	return fmt.Sprintf("%d.interactor.bin", problemID)
+}
+
+// RunInteractiveTask runs the interactor of a problem and a submission in two sandboxes, joined by pipes.
+// The interactor is called as `interactor <input file> <verdict file>`. It reads the submission's output on stdin and writes to the submission's input on stdout.
+// The verdict file must start with the score, a number in the [0, 1] range, optionally followed by a message.
+// Two boxes are needed from the scheduler at once, so they are acquired atomically through a sub runner.
+func RunInteractiveTask(ctx context.Context, mgr eval.BoxScheduler, logger *zap.SugaredLogger, req *InteractiveExecRequest) (*InteractiveExecResponse, error) {
+	resp := &InteractiveExecResponse{}
+	if mgr.NumConcurrent() < 2 {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "Interactive problems require at least 2 concurrent boxes")
+	}
+
+	lang, ok := eval.Langs()[req.Lang]
+	if !ok {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "No language found")
+	}
+	intLang, ok := eval.Langs()[req.InteractorLang]
+	if !ok {
+		resp.Comments = "translate:internal_error"
+		return resp, kilonova.Statusf(500, "No language found for interactor")
+	}
+	timeLimit, memoryLimit := lang.Limits(req.TimeLimit, req.MemoryLimit, req.LanguageLimits)
+
+	runner, err := mgr.SubRunner(ctx, 2)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close(ctx)
+
+	subBox, err := runner.GetBox(ctx, int64(memoryLimit))
+	if err != nil {
+		return nil, err
+	}
+	defer runner.ReleaseBox(subBox)
+
+	intBox, err := runner.GetBox(ctx, interactorMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.ReleaseBox(intBox)
+
+	logger.Infof("Executing interactive test %d (for submission #%d) using boxes %d (submission) and %d (interactor)", req.SubtestID, req.SubID, subBox.GetID(), intBox.GetID())
+
+	if err := intBox.WriteFile("/box/input.in", req.TestInput, 0644); err != nil {
+		zap.S().Info("Can't write input file:", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	if err := eval.CopyInBox(intBox, datastore.GetBucket(datastore.BucketTypeCheckers), InteractorFilename(req.ProblemID), intLang.CompiledName); err != nil {
+		zap.S().Warn("Couldn't copy interactor in box: ", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	bucket, fileName := bucketFromIDExec(req.SubID)
+	if err := eval.CopyInBox(subBox, bucket, fileName, lang.CompiledName); err != nil {
+		zap.S().Warn("Couldn't copy executable in box: ", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+
+	fifoDir, err := makeFIFODir()
+	if err != nil {
+		zap.S().Warn("Couldn't create interaction pipes: ", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	defer os.RemoveAll(fifoDir)
+	fifoMount := eval.Directory{In: fifoBoxDir, Out: fifoDir, Opts: "rw"}
+
+	fifos, err := holdFIFOs(fifoDir)
+	if err != nil {
+		zap.S().Warn("Couldn't open interaction pipes: ", err)
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	defer fifos.release()
+
+	subConf := submissionRunConfig(lang, timeLimit, memoryLimit, false)
+	subConf.InputPath = path.Join(fifoBoxDir, "to_sub")
+	subConf.OutputPath = path.Join(fifoBoxDir, "from_sub")
+	subConf.Directories = append(subConf.Directories, fifoMount)
+
+	intConf := submissionRunConfig(intLang, 0, interactorMemoryLimit, false)
+	intConf.WallTimeLimit = subConf.WallTimeLimit + 1
+	intConf.InputPath = path.Join(fifoBoxDir, "from_sub")
+	intConf.OutputPath = path.Join(fifoBoxDir, "to_sub")
+	intConf.Directories = append(intConf.Directories, fifoMount)
+
+	intCmd, err := intLang.RunCmd(interactorMemoryLimit)
+	if err != nil {
+		resp.Comments = "translate:internal_error"
+		return resp, err
+	}
+	intCmd = append(intCmd, "/box/input.in", "/box/verdict.out")
+
+	var wg sync.WaitGroup
+	var subMeta, intMeta *eval.RunStats
+	var subErr, intErr error
+	wg.Add(2)
+	// Once either side exits, the other one must get EOF (or SIGPIPE) instead of waiting on the pipes held by the grader
+	go func() {
+		defer wg.Done()
+		intMeta, intErr = intBox.RunCommand(ctx, intCmd, intConf)
+		fifos.release()
+	}()
+	go func() {
+		defer wg.Done()
+		subMeta, subErr = runSubmission(ctx, subBox, lang, req.MemoryLimit, subConf)
+		fifos.release()
+	}()
+	wg.Wait()
+
+	if subErr != nil {
+		resp.Comments = fmt.Sprintf("Evaluation error: %v", subErr)
+		return resp, nil
+	}
+	if subMeta == nil {
+		resp.Comments = "translate:internal_error"
+		return resp, nil
+	}
+	resp.Time = subMeta.Time
+	resp.Memory = subMeta.Memory
+	resp.Stats = subMeta
+
+	score, message, intOK := readInteractorVerdict(intBox)
+	if intErr != nil || intMeta == nil {
+		zap.S().Warnf("Interactor run error for submission %d: %v", req.SubID, intErr)
+		intOK = false
+	}
+
+	comments, okExit := runVerdict(logger, subBox, subMeta, req.SubID, req.SubtestID)
+	// A submission killed by SIGPIPE most likely wrote after the interactor exited with a verdict, so the interactor takes precedence
+	if !okExit && !(intOK && subMeta.ExitSignal == int(syscall.SIGPIPE)) {
+		resp.Comments = comments
+		return resp, nil
+	}
+
+	if !intOK {
+		logger.Warnf("Invalid interactor verdict for submission %d (test %d): %#v", req.SubID, req.SubtestID, intMeta)
+		resp.Comments = "translate:internal_error"
+		return resp, nil
+	}
+
+	resp.Percentage = score.Shift(2)
+	resp.Comments = helperVerdict(resp.Percentage, message)
+	return resp, nil
+}
+
+// helperVerdict returns the message given by a problem helper (interactor or manager).
+// If it's empty, a generic verdict based on the percentage is used instead
+func helperVerdict(percentage decimal.Decimal, message string) string {
+	if message != "" {
+		return message
+	}
+	if percentage.Equal(decimal.NewFromInt(100)) {
+		return "translate:success"
+	}
+	return "translate:wrong"
+}
+
+// readInteractorVerdict parses the verdict file written by the interactor
+func readInteractorVerdict(box eval.Sandbox) (decimal.Decimal, string, bool) {
+	var out bytes.Buffer
+	if err := box.ReadFile("/box/verdict.out", &out); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			zap.S().Warn("Couldn't read interactor verdict: ", err)
+		}
+		return decimal.Zero, "", false
+	}
+
+	var score float64
+	if _, err := fmt.Fscanf(&out, "%f", &score); err != nil {
+		return decimal.Zero, "", false
+	}
+
+	return decimal.NewFromFloat(score), strings.TrimSpace(out.String()), true
+}
+
+// makeFIFODir creates a temporary directory with the pipes used for communicating between the interactor and the submission.
+// Both sandboxes run under different users, so everything must be world accessible.
+func makeFIFODir() (string, error) {
+	dir, err := os.MkdirTemp("", "kn-fifo-*")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	for _, name := range []string{"to_sub", "from_sub"} {
+		p := path.Join(dir, name)
+		if err := syscall.Mkfifo(p, 0666); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		// Mkfifo is affected by umask
+		if err := os.Chmod(p, 0666); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// fifoHolder keeps the pipes of a FIFO directory open in the grader while the processes using them start.
+//
+// Sandboxes open the standard input of a process before its standard output.
+// Since both processes read each other's output, each of them would block opening its input, waiting for a writer that never comes.
+// Pipes opened in read-write mode have both a reader and a writer, so all opens return immediately, regardless of their order.
+type fifoHolder struct {
+	paths []string
+	files []*os.File
+	once  sync.Once
+}
+
+func holdFIFOs(dir string) (*fifoHolder, error) {
+	h := &fifoHolder{}
+	for _, name := range []string{"to_sub", "from_sub"} {
+		p := path.Join(dir, name)
+		f, err := os.OpenFile(p, os.O_RDWR, 0)
+		if err != nil {
+			h.release()
+			return nil, err
+		}
+		h.paths = append(h.paths, p)
+		h.files = append(h.files, f)
+	}
+	return h, nil
+}
+
+// release closes the held pipes, so the remaining process gets EOF or SIGPIPE once the other side exits.
+// Processes that are still blocked opening a pipe are woken up by briefly opening it again
+func (h *fifoHolder) release() {
+	h.once.Do(func() {
+		for _, f := range h.files {
+			f.Close()
+		}
+		for _, p := range h.paths {
+			if f, err := os.OpenFile(p, os.O_RDWR|syscall.O_NONBLOCK, 0); err == nil {
+				f.Close()
+			}
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/box"
+	"github.com/KiloProjects/kilonova/eval/scheduler"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	interactionLang = "python3"
+
+	// The interactor sends the number from the input file and expects its double back
+	doublingInteractor = `import sys
+n = int(open(sys.argv[1]).read())
+print(n, flush=True)
+ans = int(sys.stdin.readline())
+with open(sys.argv[2], "w") as f:
+    f.write("1\n" if ans == 2 * n else "0 wrong answer\n")
+`
+	doublingSolution = `n = int(input())
+print(2 * n, flush=True)
+`
+)
+
+// setupInteraction returns a box manager with numBoxes stupid boxes
+func setupInteraction(t *testing.T, numBoxes int) eval.BoxScheduler {
+	t.Helper()
+	initTestEnv(t)
+	if lang, ok := eval.Langs()[interactionLang]; !ok || lang.Disabled {
+		t.Skipf("%s is not available", interactionLang)
+	}
+
+	mgr, err := scheduler.New(500, numBoxes, 4*1024*1024, zap.NewNop().Sugar(), box.NewStupid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mgr.Close(context.Background()) })
+	return mgr
+}
+
+// compileFor compiles src as the submission with the given ID and, if helperName is not empty, copies it to the checkers bucket under that name
+func compileFor(t *testing.T, ctx context.Context, subID int, src string, helperName string) {
+	t.Helper()
+	lang := eval.Langs()[interactionLang]
+	resp, err := GetCompileTask(zap.NewNop().Sugar())(ctx, newStupidBox(t, 600+subID), &CompileRequest{
+		ID:        subID,
+		CodeFiles: map[string][]byte{lang.SourceName: []byte(src)},
+		Lang:      interactionLang,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("Compilation failed: %s %s", resp.Output, resp.Other)
+	}
+	if helperName == "" {
+		return
+	}
+
+	bucket, name := bucketFromIDExec(subID)
+	r, err := bucket.Reader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := datastore.GetBucket(datastore.BucketTypeCheckers).WriteFile(helperName, r, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInteractiveTask runs a trivial interactor and solution pair, which must finish long before the time limit
+func TestInteractiveTask(t *testing.T) {
+	mgr := setupInteraction(t, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const problemID = 1
+	compileFor(t, ctx, 1, doublingInteractor, InteractorFilename(problemID))
+	compileFor(t, ctx, 2, doublingSolution, "")
+
+	start := time.Now()
+	resp, err := RunInteractiveTask(ctx, mgr, zap.NewNop().Sugar(), &InteractiveExecRequest{
+		SubID:          2,
+		SubtestID:      2,
+		ProblemID:      problemID,
+		MemoryLimit:    256 * 1024,
+		TimeLimit:      10,
+		Lang:           interactionLang,
+		TestInput:      strings.NewReader("21\n"),
+		InteractorLang: interactionLang,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Interaction took %s, the processes probably blocked on the pipes", elapsed)
+	}
+	if !resp.Percentage.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("Expected full score, got %s (%q)", resp.Percentage, resp.Comments)
+	}
+}
+
+// doublingManager opens the pipes in the reverse of the usual order, which must not block
+const doublingManager = `import sys
+n = int(sys.stdin.read())
+pipes = sys.argv[1:]
+from_users = [open(pipes[i], "r") for i in range(0, len(pipes), 2)]
+to_users = [open(pipes[i], "w") for i in range(1, len(pipes), 2)]
+ok = True
+for i, (fin, fout) in enumerate(zip(from_users, to_users)):
+    print(n + i, file=fout, flush=True)
+    ok = ok and int(fin.readline()) == 2 * (n + i)
+print(1 if ok else 0)
+`
+
+// TestCommunicationTask runs a manager talking to two copies of a trivial solution
+func TestCommunicationTask(t *testing.T) {
+	mgr := setupInteraction(t, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const problemID = 3
+	compileFor(t, ctx, 3, doublingManager, ManagerFilename(problemID))
+	compileFor(t, ctx, 4, doublingSolution, "")
+
+	start := time.Now()
+	resp, err := RunCommunicationTask(ctx, mgr, zap.NewNop().Sugar(), &CommunicationExecRequest{
+		SubID:        4,
+		SubtestID:    4,
+		ProblemID:    problemID,
+		MemoryLimit:  256 * 1024,
+		TimeLimit:    10,
+		Lang:         interactionLang,
+		TestInput:    strings.NewReader("21\n"),
+		NumProcesses: 2,
+		ManagerLang:  interactionLang,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Communication took %s, the processes probably blocked on the pipes", elapsed)
+	}
+	if !resp.Percentage.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("Expected full score, got %s (%q)", resp.Percentage, resp.Comments)
+	}
+}
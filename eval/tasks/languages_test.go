@@ -0,0 +1,161 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/box"
+	"go.uber.org/zap"
+)
+
+const cppHelloWorld = `#include <iostream>
+int main() { std::cout << "Hello, World!\n"; }
+`
+
+var helloWorlds = map[string]string{
+	"c": `#include <stdio.h>
+int main() { puts("Hello, World!"); }
+`,
+	"cpp11":  cppHelloWorld,
+	"cpp14":  cppHelloWorld,
+	"cpp17":  cppHelloWorld,
+	"cpp20":  cppHelloWorld,
+	"pascal": "begin writeln('Hello, World!'); end.\n",
+	"golang": `package main
+
+import "fmt"
+
+func main() { fmt.Println("Hello, World!") }
+`,
+	"haskell": `main = putStrLn "Hello, World!"` + "\n",
+	// The nested class makes sure all compiled classes end up in the jar
+	"java": `public class Main {
+	static class Greeter {
+		String greet() { return "Hello, World!"; }
+	}
+
+	public static void main(String[] args) {
+		System.out.println(new Greeter().greet());
+	}
+}
+`,
+	"kotlin":  "fun main() { println(\"Hello, World!\") }\n",
+	"rust":    "fn main() { println!(\"Hello, World!\"); }\n",
+	"python3": "print(\"Hello, World!\")\n",
+}
+
+var (
+	testEnvOnce sync.Once
+	testEnvErr  error
+)
+
+// initTestEnv initializes the datastore and the languages once for all the tests of the package
+func initTestEnv(t *testing.T) {
+	t.Helper()
+	testEnvOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "kn-tasks-test-*")
+		if err != nil {
+			testEnvErr = err
+			return
+		}
+		if err := datastore.InitBuckets(dir); err != nil {
+			testEnvErr = err
+			return
+		}
+		testEnvErr = eval.LoadLanguages()
+	})
+	if testEnvErr != nil {
+		t.Fatal(testEnvErr)
+	}
+}
+
+func newStupidBox(t *testing.T, id int) eval.Sandbox {
+	t.Helper()
+	b, err := box.NewStupid(id, 0, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+// TestHelloWorld compiles and runs a hello world program in every built-in language available on the system
+func TestHelloWorld(t *testing.T) {
+	initTestEnv(t)
+	logger := zap.NewNop().Sugar()
+
+	names := make([]string, 0, len(eval.Langs()))
+	for name := range eval.Langs() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for i, name := range names {
+		if name == "outputOnly" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			lang := eval.Langs()[name]
+			src, ok := helloWorlds[name]
+			if !ok {
+				t.Fatalf("No hello world program for %s", lang.PrintableName)
+			}
+			if lang.Disabled {
+				t.Skipf("%s is not available", lang.PrintableName)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			subID := 1000 + i
+
+			compileResp, err := GetCompileTask(logger)(ctx, newStupidBox(t, 2*subID), &CompileRequest{
+				ID:        subID,
+				CodeFiles: map[string][]byte{lang.SourceName: []byte(src)},
+				Lang:      name,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !compileResp.Success {
+				t.Fatalf("Compilation failed: %s %s", compileResp.Output, compileResp.Other)
+			}
+
+			execResp, err := GetExecuteTask(logger)(ctx, newStupidBox(t, 2*subID+1), &ExecRequest{
+				SubID:       subID,
+				SubtestID:   subID,
+				Filename:    "stdin",
+				MemoryLimit: 256 * 1024,
+				TimeLimit:   10,
+				Lang:        name,
+				TestInput:   strings.NewReader(""),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if execResp.Comments != "" {
+				t.Fatalf("Unexpected verdict: %s", execResp.Comments)
+			}
+
+			r, err := datastore.GetBucket(datastore.BucketTypeSubtests).Reader(strconv.Itoa(subID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != "Hello, World!\n" {
+				t.Fatalf("Unexpected output %q", out)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package eval
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,7 +12,6 @@ import (
 	"strings"
 
 	"github.com/KiloProjects/kilonova/datastore"
-	"github.com/KiloProjects/kilonova/internal/config"
 	"go.uber.org/zap"
 )
 
@@ -43,29 +45,35 @@ func MakeGoodCommand(command []string) ([]string, error) {
 		return nil, err
 	}
 
-	cmd, err = filepath.EvalSymlinks(cmd)
+	resolved, err := filepath.EvalSymlinks(cmd)
 	if err != nil {
 		return nil, err
 	}
+	if !slices.Contains(multiCallBinaries, filepath.Base(resolved)) {
+		cmd = resolved
+	}
 
 	tmp[0] = cmd
 	return tmp, nil
 }
 
+// multiCallBinaries choose what to run based on the name they were called with,
+// so they must be called through the symlink (ie. rustc -> rustup, sh -> busybox)
+var multiCallBinaries = []string{"rustup", "busybox"}
+
 func CleanCompilation(subid int) error {
 	return datastore.GetBucket(datastore.BucketTypeCompiles).RemoveFile(fmt.Sprintf("%d.bin", subid))
 }
 
-func disableLang(key string) {
-	lang := Langs[key]
+func disableLang(langs map[string]Language, key string) {
+	lang := langs[key]
 	lang.Disabled = true
-	Langs[key] = lang
+	langs[key] = lang
 }
 
 // checkLanguages disables all languages that are *not* detected by the system in the current configuration
-// It should be run at the start of the execution (and implemented more nicely tbh)
-func checkLanguages() {
-	for k, v := range Langs {
+func checkLanguages(langs map[string]Language) {
+	for k, v := range langs {
 		if v.Disabled { // Skip search if already disabled
 			continue
 		}
@@ -76,47 +84,92 @@ func checkLanguages() {
 			toSearch = v.RunCommand
 		}
 		if len(toSearch) == 0 {
-			disableLang(k)
+			disableLang(langs, k)
 			zap.S().Infof("Language %q was disabled because of empty line", k)
 			continue
 		}
-		cmd, err := exec.LookPath(toSearch[0])
+		for _, name := range append([]string{toSearch[0]}, v.Requires...) {
+			if reason := checkBinary(name); reason != "" {
+				disableLang(langs, k)
+				zap.S().Infof("Language %q was disabled because %s (%s)", k, reason, name)
+				break
+			}
+		}
+		dropMissingLinker(langs, k)
+		if lang := langs[k]; !lang.Disabled && lang.Compiled {
+			lang.ToolchainID = toolchainID(append([]string{lang.CompileCommand[0]}, lang.Requires...))
+			langs[k] = lang
+		}
+	}
+}
+
+// toolchainID returns the hash of the given programs' binaries. Programs that can't be read are skipped
+func toolchainID(names []string) string {
+	h := sha256.New()
+	for _, name := range names {
+		cmd, err := exec.LookPath(name)
 		if err != nil {
-			disableLang(k)
-			zap.S().Infof("Language %q was disabled because the compiler/interpreter was not found in PATH", k)
 			continue
 		}
-		cmd, err = filepath.EvalSymlinks(cmd)
-		if err != nil {
-			disableLang(k)
-			zap.S().Infof("Language %q was disabled because the compiler/interpreter had a bad symlink", k)
+		if cmd, err = filepath.EvalSymlinks(cmd); err != nil {
 			continue
 		}
-		stat, err := os.Stat(cmd)
+		f, err := os.Open(cmd)
 		if err != nil {
-			disableLang(k)
-			zap.S().Infof("Language %q was disabled because the compiler/interpreter binary was not found", k)
 			continue
 		}
-
-		if stat.Mode()&0111 == 0 {
-			disableLang(k)
-			zap.S().Infof("Language %q was disabled because the compiler/interpreter binary is not executable", k)
+		io.WriteString(h, cmd+"\x00")
+		if _, err := io.Copy(h, f); err != nil {
+			zap.S().Warnf("Couldn't hash %q: %v", cmd, err)
 		}
-
+		f.Close()
 	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-// Initialize should be called after reading the flags, but before manager.New
-func Initialize() error {
-
-	// Test right now if they exist
-	zap.S().Info("Isolate path: ", config.Eval.IsolatePath)
-	if _, err := os.Stat(config.Eval.IsolatePath); os.IsNotExist(err) {
-		zap.S().Fatal("Sandbox binary not found. Run scripts/init_isolate.sh to properly install it.")
+// dropMissingLinker removes the -fuse-ld=<linker> flag from the compile commands of the language if the linker is not installed,
+// so the compiler falls back to its default linker. The faster linkers (such as mold) are nice to have, but not required
+func dropMissingLinker(langs map[string]Language, key string) {
+	lang := langs[key]
+	idx := slices.IndexFunc(lang.CompileCommand, func(arg string) bool { return strings.HasPrefix(arg, "-fuse-ld=") })
+	if idx < 0 {
+		return
 	}
+	linkerFlag := lang.CompileCommand[idx]
+	linker := strings.TrimPrefix(linkerFlag, "-fuse-ld=")
+	// The compiler looks for ld.<linker>, but the linker itself is usually installed as well
+	if checkBinary("ld."+linker) == "" || checkBinary(linker) == "" {
+		return
+	}
+	isLinkerFlag := func(arg string) bool { return arg == linkerFlag }
+	lang.CompileCommand = slices.DeleteFunc(slices.Clone(lang.CompileCommand), isLinkerFlag)
+	lang.DebugCompileCommand = slices.DeleteFunc(slices.Clone(lang.DebugCompileCommand), isLinkerFlag)
+	langs[key] = lang
+	zap.S().Infof("Language %q uses the default linker, since %s was not found", key, linker)
+}
 
-	checkLanguages()
+// checkBinary returns why the given program can't be used, or an empty string if it can
+func checkBinary(name string) string {
+	cmd, err := exec.LookPath(name)
+	if err != nil {
+		return "the compiler/interpreter was not found in PATH"
+	}
+	cmd, err = filepath.EvalSymlinks(cmd)
+	if err != nil {
+		return "the compiler/interpreter had a bad symlink"
+	}
+	stat, err := os.Stat(cmd)
+	if err != nil {
+		return "the compiler/interpreter binary was not found"
+	}
+	if stat.Mode()&0111 == 0 {
+		return "the compiler/interpreter binary is not executable"
+	}
+	return ""
+}
 
-	return nil
+// Initialize should be called after reading the flags, but before manager.New.
+// The sandbox itself is checked when it's selected (see scheduler.SandboxBackend), since not every sandbox needs isolate
+func Initialize() error {
+	return LoadLanguages()
 }
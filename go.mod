@@ -22,6 +22,7 @@ require (
 	golang.org/x/crypto v0.22.0
 	golang.org/x/net v0.24.0 // indirect
 	golang.org/x/sync v0.7.0
+	golang.org/x/sys v0.19.0
 	golang.org/x/text v0.14.0
 )
 
@@ -60,6 +61,5 @@ require (
 	github.com/klauspost/cpuid/v2 v2.2.7 // indirect
 	github.com/zeebo/xxh3 v1.0.2 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/sys v0.19.0 // indirect
 	vimagination.zapto.org/dos2unix v1.0.1
 )
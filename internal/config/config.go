@@ -46,6 +46,14 @@ type EvalConf struct {
 	GlobalMaxMem  int64 `toml:"global_max_mem_kb"`
 
 	StartingBox int `toml:"starting_box"`
+
+	// Sandbox is the sandbox backend: "isolate", "native" (namespaces, seccomp and cgroup v2, see eval/box) or "stupid".
+	// If empty, the first one that works is used, in this order. The stupid sandbox is insecure and must be allowed explicitly
+	Sandbox string `toml:"sandbox"`
+	// CgroupRoot is the cgroup v2 directory delegated to the native sandbox.
+	// If empty, the cgroup of the process is used, which must be delegated to it (for example, using systemd's Delegate=yes).
+	// In that case, once the native sandbox is selected, the process moves itself into a kn-main child cgroup
+	CgroupRoot string `toml:"cgroup_root"`
 }
 
 // CommonConf is the data required for all services
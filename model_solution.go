@@ -0,0 +1,247 @@
+package kilonova
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExpectedVerdict is the outcome a model solution should have on (a subtask of) the problem
+type ExpectedVerdict string
+
+const (
+	// ExpectedNone only checks the score range of the model solution
+	ExpectedNone ExpectedVerdict = ""
+	// ExpectedAccepted means all the tests must pass
+	ExpectedAccepted ExpectedVerdict = "ac"
+	// ExpectedRejected means at least one test must fail, for any reason
+	ExpectedRejected     ExpectedVerdict = "rejected"
+	ExpectedWrongAnswer  ExpectedVerdict = "wa"
+	ExpectedTimeLimit    ExpectedVerdict = "tle"
+	ExpectedMemoryLimit  ExpectedVerdict = "mle"
+	ExpectedRuntimeError ExpectedVerdict = "re"
+	ExpectedCompileError ExpectedVerdict = "ce"
+)
+
+// expectedVerdictSuffix separates the verdict from the subtask it refers to
+const expectedVerdictSuffix = "@"
+
+var ExpectedVerdicts = []ExpectedVerdict{
+	ExpectedAccepted, ExpectedRejected, ExpectedWrongAnswer, ExpectedTimeLimit,
+	ExpectedMemoryLimit, ExpectedRuntimeError, ExpectedCompileError,
+}
+
+// SolutionExpectation is the expected result of a model solution (a submission tagged by the problem setters).
+// For example, `ac`, `tle@3` (time limit exceeded on subtask 3), `score=40-60` or `wa score=0-20`
+type SolutionExpectation struct {
+	SubmissionID int `json:"submission_id"`
+
+	Verdict ExpectedVerdict `json:"verdict"`
+	// Subtask is the visible ID of the subtask the verdict refers to. If nil, it refers to the whole problem
+	Subtask *int `json:"subtask"`
+
+	// The score of the submission must be between MinScore and MaxScore (inclusive), if they are set
+	MinScore *decimal.Decimal `json:"min_score"`
+	MaxScore *decimal.Decimal `json:"max_score"`
+}
+
+// ParseSolutionExpectation parses the text form of an expected result: an optional verdict
+// (with an optional `@subtask` suffix) and an optional `score=X` or `score=X-Y` range, separated by spaces
+func ParseSolutionExpectation(s string) (*SolutionExpectation, error) {
+	var exp SolutionExpectation
+	var hasVerdict, hasScore bool
+	for _, field := range strings.Fields(strings.ToLower(s)) {
+		if scoreStr, ok := strings.CutPrefix(field, "score="); ok {
+			if hasScore {
+				return nil, errors.New("score range given twice")
+			}
+			hasScore = true
+			minStr, maxStr, isRange := strings.Cut(scoreStr, "-")
+			if !isRange {
+				maxStr = minStr
+			}
+			minScore, err := decimal.NewFromString(minStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid score %q", minStr)
+			}
+			maxScore, err := decimal.NewFromString(maxStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid score %q", maxStr)
+			}
+			if minScore.GreaterThan(maxScore) {
+				return nil, fmt.Errorf("invalid score range %q", scoreStr)
+			}
+			exp.MinScore, exp.MaxScore = &minScore, &maxScore
+			continue
+		}
+
+		if hasVerdict {
+			return nil, errors.New("only one verdict can be expected")
+		}
+		hasVerdict = true
+		verdict, subtask, hasSubtask := strings.Cut(field, expectedVerdictSuffix)
+		exp.Verdict = normalizeExpectedVerdict(verdict)
+		if exp.Verdict == ExpectedNone {
+			return nil, fmt.Errorf("unknown verdict %q", verdict)
+		}
+		if hasSubtask {
+			id, err := strconv.Atoi(subtask)
+			if err != nil || id < 0 {
+				return nil, fmt.Errorf("invalid subtask %q", subtask)
+			}
+			if exp.Verdict == ExpectedCompileError {
+				return nil, errors.New("compile errors can't be expected on a subtask")
+			}
+			exp.Subtask = &id
+		}
+	}
+	if !hasVerdict && !hasScore {
+		return nil, errors.New("empty expectation")
+	}
+	return &exp, nil
+}
+
+// normalizeExpectedVerdict also accepts the longer names of the verdicts, including the solution tags used by Polygon
+func normalizeExpectedVerdict(verdict string) ExpectedVerdict {
+	switch verdict {
+	case "ac", "ok", "accepted", "main", "correct":
+		return ExpectedAccepted
+	case "rejected", "incorrect", "fail":
+		return ExpectedRejected
+	case "wa", "wrong", "wrong-answer", "wrong_answer", "presentation-error":
+		return ExpectedWrongAnswer
+	case "tle", "timeout", "time-limit-exceeded", "time_limit":
+		return ExpectedTimeLimit
+	case "mle", "memory-limit-exceeded", "memory_limit":
+		return ExpectedMemoryLimit
+	case "re", "rte", "runtime-error", "runtime_error", "failed":
+		return ExpectedRuntimeError
+	case "ce", "compile-error", "compile_error":
+		return ExpectedCompileError
+	}
+	return ExpectedNone
+}
+
+// String returns the expectation in the form parsed by ParseSolutionExpectation
+func (e *SolutionExpectation) String() string {
+	var parts []string
+	if e.Verdict != ExpectedNone {
+		verdict := string(e.Verdict)
+		if e.Subtask != nil {
+			verdict += expectedVerdictSuffix + strconv.Itoa(*e.Subtask)
+		}
+		parts = append(parts, verdict)
+	}
+	if e.MinScore != nil && e.MaxScore != nil {
+		if e.MinScore.Equal(*e.MaxScore) {
+			parts = append(parts, "score="+e.MinScore.String())
+		} else {
+			parts = append(parts, "score="+e.MinScore.String()+"-"+e.MaxScore.String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// SubTestOutcome classifies the result of a finished subtest. It returns ExpectedNone for skipped subtests
+func SubTestOutcome(st *SubTest) ExpectedVerdict {
+	switch {
+	case st.Skipped || !st.Done:
+		return ExpectedNone
+	case st.Verdict == "translate:timeout" || st.Verdict == "translate:walltimeout":
+		return ExpectedTimeLimit
+	case st.Verdict == "translate:memory_limit" || st.OOMKilled:
+		return ExpectedMemoryLimit
+	case st.Verdict == "translate:runtime_error" || st.ExitCode != 0 || st.ExitSignal != 0:
+		return ExpectedRuntimeError
+	case st.Percentage.GreaterThanOrEqual(decimal.NewFromInt(100)):
+		return ExpectedAccepted
+	default:
+		return ExpectedWrongAnswer
+	}
+}
+
+// Mismatch compares the expectation with the results of the finished submission.
+// It returns an empty string if the submission got the expected result, or the reason it didn't otherwise
+func (e *SolutionExpectation) Mismatch(sub *Submission, subTests []*SubTest, subTasks []*SubmissionSubTask) string {
+	if e.MinScore != nil && e.MaxScore != nil && (sub.Score.LessThan(*e.MinScore) || sub.Score.GreaterThan(*e.MaxScore)) {
+		return fmt.Sprintf("Score %s is not in range [%s, %s]", sub.Score, e.MinScore, e.MaxScore)
+	}
+
+	compileError := sub.CompileError != nil && *sub.CompileError
+	switch {
+	case e.Verdict == ExpectedNone:
+		return ""
+	case e.Verdict == ExpectedCompileError:
+		if !compileError {
+			return "Expected compile error, but the submission compiled"
+		}
+		return ""
+	case compileError:
+		return "Compile error"
+	}
+
+	where := "the problem"
+	if e.Subtask != nil {
+		where = fmt.Sprintf("subtask %d", *e.Subtask)
+		var stk *SubmissionSubTask
+		for _, subTask := range subTasks {
+			if subTask.VisibleID == *e.Subtask {
+				stk = subTask
+				break
+			}
+		}
+		if stk == nil {
+			return fmt.Sprintf("Subtask %d doesn't exist", *e.Subtask)
+		}
+		subTests = filterSubTests(subTests, stk.Subtests)
+	}
+
+	outcomes := make(map[ExpectedVerdict]bool)
+	for _, st := range subTests {
+		outcomes[SubTestOutcome(st)] = true
+	}
+	failed := outcomes[ExpectedWrongAnswer] || outcomes[ExpectedTimeLimit] || outcomes[ExpectedMemoryLimit] || outcomes[ExpectedRuntimeError]
+
+	switch e.Verdict {
+	case ExpectedAccepted:
+		if failed {
+			return fmt.Sprintf("Expected all tests of %s to pass, but some failed", where)
+		}
+	case ExpectedRejected:
+		if !failed {
+			return fmt.Sprintf("Expected a failed test on %s, but all passed", where)
+		}
+	default:
+		if !outcomes[e.Verdict] {
+			return fmt.Sprintf("Expected verdict %q on %s, but it didn't occur", e.Verdict, where)
+		}
+	}
+	return ""
+}
+
+func filterSubTests(subTests []*SubTest, ids []int) []*SubTest {
+	var rez []*SubTest
+	for _, st := range subTests {
+		if slices.Contains(ids, st.ID) {
+			rez = append(rez, st)
+		}
+	}
+	return rez
+}
+
+// SolutionVerification is the result of checking a model solution against its expectation
+type SolutionVerification struct {
+	Expectation *SolutionExpectation `json:"expectation"`
+	// ExpectationText is the expectation in text form
+	ExpectationText string      `json:"expectation_text"`
+	Submission      *Submission `json:"submission"`
+
+	// Pending is true while the submission is being evaluated
+	Pending bool `json:"pending"`
+	// Mismatch is the reason the submission didn't get the expected result. It's empty if it did
+	Mismatch string `json:"mismatch"`
+}
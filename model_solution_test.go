@@ -0,0 +1,73 @@
+package kilonova
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseSolutionExpectation(t *testing.T) {
+	var valid = map[string]string{
+		"ac":                  "ac",
+		"Accepted":            "ac",
+		"time-limit-exceeded": "tle",
+		"tle@3":               "tle@3",
+		"score=40":            "score=40",
+		"wa@2 score=0-40.5":   "wa@2 score=0-40.5",
+		"score=10-20 re":      "re score=10-20",
+	}
+	for str, expected := range valid {
+		exp, err := ParseSolutionExpectation(str)
+		if err != nil {
+			t.Fatalf("Couldn't parse %q: %v", str, err)
+		}
+		if exp.String() != expected {
+			t.Fatalf("Wanted %q for %q, got %q", expected, str, exp.String())
+		}
+	}
+
+	var invalid = []string{"", "   ", "xyz", "ac wa", "tle@x", "score=60-40", "score=1 score=2", "ce@1"}
+	for _, str := range invalid {
+		if _, err := ParseSolutionExpectation(str); err == nil {
+			t.Fatalf("Parsing %q should fail", str)
+		}
+	}
+}
+
+func TestSolutionExpectationMismatch(t *testing.T) {
+	hundred := decimal.NewFromInt(100)
+	sub := &Submission{Score: decimal.NewFromInt(30), Status: StatusFinished}
+	subTests := []*SubTest{
+		{ID: 1, Done: true, Percentage: hundred, Verdict: "translate:success"},
+		{ID: 2, Done: true, Verdict: "translate:timeout"},
+		{ID: 3, Done: true, Verdict: "translate:wrong"},
+	}
+	subTasks := []*SubmissionSubTask{
+		{VisibleID: 1, Subtests: []int{1}},
+		{VisibleID: 2, Subtests: []int{2, 3}},
+	}
+
+	var cases = map[string]bool{
+		"ac":             false,
+		"rejected":       true,
+		"tle":            true,
+		"mle":            false,
+		"ac@1":           true,
+		"wa@1":           false,
+		"wa@2":           true,
+		"tle@5":          false,
+		"score=30":       true,
+		"score=40-100":   false,
+		"tle score=0-50": true,
+		"ce":             false,
+	}
+	for str, matches := range cases {
+		exp, err := ParseSolutionExpectation(str)
+		if err != nil {
+			t.Fatalf("Couldn't parse %q: %v", str, err)
+		}
+		if mismatch := exp.Mismatch(sub, subTests, subTasks); (mismatch == "") != matches {
+			t.Fatalf("Wrong result for %q (mismatch: %q)", str, mismatch)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Package plagiarism implements a code similarity detector, in the spirit of MOSS.
+//
+// Every document is tokenized in a language-aware manner and fingerprinted by winnowing
+// the hashes of its token k-grams (Schleimer, Wilkerson, Aiken - "Winnowing: Local Algorithms for Document Fingerprinting").
+// Documents sharing fingerprints are then compared pairwise and the shared regions are reported.
+package plagiarism
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Options configures the detector
+type Options struct {
+	// K is the length, in tokens, of the k-grams that are hashed. Matches shorter than K tokens are never found
+	K int
+	// Window is the winnowing window size. Matches at least K+Window-1 tokens long are guaranteed to be found
+	Window int
+	// MaxDocFrequency is the number of documents a fingerprint may appear in before it is considered common code
+	// (like boilerplate or an often used algorithm) and ignored. Zero means fingerprints are never ignored
+	MaxDocFrequency int
+	// MaxDocFraction, if positive, raises the MaxDocFrequency threshold to this fraction of the number of documents,
+	// so it scales with big document sets. MaxDocFrequency then acts as a floor
+	MaxDocFraction float64
+	// MaxPairs is the maximum number of reported pairs, zero meaning no limit
+	MaxPairs int
+}
+
+// DefaultOptions are the options used when none are specified, similar to MOSS's defaults
+var DefaultOptions = Options{
+	K:               12,
+	Window:          8,
+	MaxDocFrequency: 10,
+	MaxPairs:        250,
+}
+
+type fingerprint struct {
+	hash uint64
+	// pos is the index of the first token of the k-gram
+	pos int
+}
+
+type document struct {
+	tokens       []Token
+	fingerprints []fingerprint
+}
+
+// Detector holds the documents that are compared against each other
+type Detector struct {
+	opts Options
+	docs []*document
+}
+
+// Match is a region of code shared between the two documents of a pair, in lines (1-indexed, inclusive)
+type Match struct {
+	LeftStart  int
+	LeftEnd    int
+	RightStart int
+	RightEnd   int
+	// Tokens is the number of tokens of the left region
+	Tokens int
+}
+
+// Pair is the similarity report between two documents, identified by their index in the order they were added
+type Pair struct {
+	Left  int
+	Right int
+
+	// LeftSimilarity and RightSimilarity are the fractions (between 0 and 1) of each document's tokens that are shared
+	LeftSimilarity  float64
+	RightSimilarity float64
+	// MatchedTokens is the number of shared tokens of the smaller side
+	MatchedTokens int
+
+	Matches []*Match
+}
+
+// New creates a detector with the given options
+func New(opts Options) *Detector {
+	opts.K = max(opts.K, 1)
+	opts.Window = max(opts.Window, 1)
+	return &Detector{opts: opts}
+}
+
+// AddDocument adds a source file, written in the given language family (one of the MOSS language names), and returns its index
+func (d *Detector) AddDocument(code []byte, lang string) int {
+	tokens := Tokenize(code, lang)
+	d.docs = append(d.docs, &document{
+		tokens:       tokens,
+		fingerprints: winnow(kgramHashes(tokens, d.opts.K), d.opts.Window),
+	})
+	return len(d.docs) - 1
+}
+
+func kgramHashes(tokens []Token, k int) []uint64 {
+	if len(tokens) < k {
+		return nil
+	}
+	const base = 1000003
+	// Rolling hash over the token hashes
+	var pow uint64 = 1
+	for range k - 1 {
+		pow *= base
+	}
+	hashes := make([]uint64, 0, len(tokens)-k+1)
+	var h uint64
+	for i, tok := range tokens {
+		if i >= k {
+			h -= tokens[i-k].Hash * pow
+		}
+		h = h*base + tok.Hash
+		if i >= k-1 {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+// winnow selects the minimum hash in every window of consecutive k-gram hashes, preferring the rightmost one on ties.
+// Every hash is recorded only once per position
+func winnow(hashes []uint64, window int) []fingerprint {
+	if len(hashes) == 0 {
+		return nil
+	}
+	window = min(window, len(hashes))
+	var fps []fingerprint
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		best := start
+		for i := start + 1; i < start+window; i++ {
+			if hashes[i] <= hashes[best] {
+				best = i
+			}
+		}
+		if best != last {
+			fps = append(fps, fingerprint{hash: hashes[best], pos: best})
+			last = best
+		}
+	}
+	return fps
+}
+
+type posting struct {
+	doc int
+	pos int
+}
+
+// Compare compares all documents against each other and returns the pairs that share code,
+// most similar first
+func (d *Detector) Compare() []*Pair {
+	index := make(map[uint64][]posting)
+	for i, doc := range d.docs {
+		for _, fp := range doc.fingerprints {
+			index[fp.hash] = append(index[fp.hash], posting{doc: i, pos: fp.pos})
+		}
+	}
+
+	// shared holds, for every pair of documents, the positions of their common fingerprints
+	maxFreq := d.maxDocFrequency()
+	shared := make(map[[2]int][][2]int)
+	for _, postings := range index {
+		docs := make(map[int][]int)
+		for _, p := range postings {
+			docs[p.doc] = append(docs[p.doc], p.pos)
+		}
+		if len(docs) < 2 || (maxFreq > 0 && len(docs) > maxFreq) {
+			continue
+		}
+		for a, aPositions := range docs {
+			for b, bPositions := range docs {
+				if a >= b {
+					continue
+				}
+				for _, aPos := range aPositions {
+					for _, bPos := range bPositions {
+						shared[[2]int{a, b}] = append(shared[[2]int{a, b}], [2]int{aPos, bPos})
+					}
+				}
+			}
+		}
+	}
+
+	pairs := make([]*Pair, 0, len(shared))
+	for docs, positions := range shared {
+		pairs = append(pairs, d.comparePair(docs[0], docs[1], positions))
+	}
+
+	slices.SortFunc(pairs, func(a, b *Pair) int {
+		if c := cmp.Compare(max(b.LeftSimilarity, b.RightSimilarity), max(a.LeftSimilarity, a.RightSimilarity)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.MatchedTokens, a.MatchedTokens); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Left, b.Left); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Right, b.Right)
+	})
+	if d.opts.MaxPairs > 0 && len(pairs) > d.opts.MaxPairs {
+		pairs = pairs[:d.opts.MaxPairs]
+	}
+	return pairs
+}
+
+// maxDocFrequency returns the number of documents a fingerprint may appear in, zero meaning no limit
+func (d *Detector) maxDocFrequency() int {
+	if d.opts.MaxDocFraction <= 0 || d.opts.MaxDocFrequency <= 0 {
+		return d.opts.MaxDocFrequency
+	}
+	return max(d.opts.MaxDocFrequency, int(math.Ceil(d.opts.MaxDocFraction*float64(len(d.docs)))))
+}
+
+func (d *Detector) comparePair(left, right int, positions [][2]int) *Pair {
+	k := d.opts.K
+	leftDoc, rightDoc := d.docs[left], d.docs[right]
+
+	leftCovered := make([]bool, len(leftDoc.tokens))
+	rightCovered := make([]bool, len(rightDoc.tokens))
+	for _, pos := range positions {
+		for i := range k {
+			leftCovered[pos[0]+i] = true
+			rightCovered[pos[1]+i] = true
+		}
+	}
+	leftCount, rightCount := countTrue(leftCovered), countTrue(rightCovered)
+
+	return &Pair{
+		Left:  left,
+		Right: right,
+
+		LeftSimilarity:  float64(leftCount) / float64(len(leftDoc.tokens)),
+		RightSimilarity: float64(rightCount) / float64(len(rightDoc.tokens)),
+		MatchedTokens:   min(leftCount, rightCount),
+
+		Matches: d.matchRegions(leftDoc, rightDoc, positions),
+	}
+}
+
+func countTrue(vals []bool) int {
+	var cnt int
+	for _, val := range vals {
+		if val {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// matchRegions merges the common fingerprints into regions.
+// Fingerprints belong to the same region if they are on the same "diagonal" (the code between them wasn't changed)
+// and close enough that no unmatched k-gram could be between them
+func (d *Detector) matchRegions(leftDoc, rightDoc *document, positions [][2]int) []*Match {
+	k, gap := d.opts.K, d.opts.K+d.opts.Window
+	positions = slices.Clone(positions)
+	slices.SortFunc(positions, func(a, b [2]int) int {
+		if c := cmp.Compare(a[1]-a[0], b[1]-b[0]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a[0], b[0])
+	})
+
+	type region struct{ leftStart, leftEnd, rightStart, rightEnd int }
+	var regions []region
+	for _, pos := range positions {
+		if len(regions) > 0 {
+			last := &regions[len(regions)-1]
+			if last.rightStart-last.leftStart == pos[1]-pos[0] && pos[0] <= last.leftEnd+gap {
+				last.leftEnd = max(last.leftEnd, pos[0]+k-1)
+				last.rightEnd = max(last.rightEnd, pos[1]+k-1)
+				continue
+			}
+		}
+		regions = append(regions, region{pos[0], pos[0] + k - 1, pos[1], pos[1] + k - 1})
+	}
+
+	// Drop regions contained in bigger ones, which usually come from repeated code
+	slices.SortFunc(regions, func(a, b region) int {
+		if c := cmp.Compare(a.leftStart, b.leftStart); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.leftEnd, a.leftEnd)
+	})
+	matches := make([]*Match, 0, len(regions))
+	for i, reg := range regions {
+		contained := false
+		for j, other := range regions {
+			if i != j && other.leftStart <= reg.leftStart && reg.leftEnd <= other.leftEnd &&
+				other.rightStart <= reg.rightStart && reg.rightEnd <= other.rightEnd &&
+				(other != reg || j < i) {
+				contained = true
+				break
+			}
+		}
+		if contained {
+			continue
+		}
+		matches = append(matches, &Match{
+			LeftStart:  leftDoc.tokens[reg.leftStart].Line,
+			LeftEnd:    leftDoc.tokens[reg.leftEnd].Line,
+			RightStart: rightDoc.tokens[reg.rightStart].Line,
+			RightEnd:   rightDoc.tokens[reg.rightEnd].Line,
+			Tokens:     reg.leftEnd - reg.leftStart + 1,
+		})
+	}
+	return matches
+}
@@ -0,0 +1,153 @@
+package plagiarism
+
+import (
+	"testing"
+)
+
+const original = `#include <bits/stdc++.h>
+using namespace std;
+
+int main() {
+	int n, s = 0;
+	cin >> n;
+	vector<int> v(n);
+	for (int i = 0; i < n; i++) {
+		cin >> v[i];
+		s += v[i];
+	}
+	sort(v.begin(), v.end());
+	for (int i = 0; i < n; i++) {
+		if (v[i] % 2 == 0) {
+			s -= v[i] / 2;
+		}
+	}
+	cout << s << '\n';
+	return 0;
+}
+`
+
+// renamed is the original with other names, comments and constants
+const renamed = `#include <iostream>
+#include <vector>
+#include <algorithm>
+using namespace std;
+
+// my own solution
+int main() {
+	int cnt, total = 0;
+	cin >> cnt;
+	vector<int> arr(cnt);
+	for (int j = 0; j < cnt; j++) {
+		cin >> arr[j]; /* read */
+		total += arr[j];
+	}
+	sort(arr.begin(), arr.end());
+	for (int j = 0; j < cnt; j++) {
+		if (arr[j] % 2 == 0) {
+			total -= arr[j] / 3;
+		}
+	}
+	cout << total << "\n";
+	return 0;
+}
+`
+
+const different = `n = int(input())
+a = list(map(int, input().split()))
+print(sum(x for x in a if x % 2 == 1))
+`
+
+func TestTokenize(t *testing.T) {
+	tokens1, tokens2 := Tokenize([]byte(original), "cc"), Tokenize([]byte(renamed), "cc")
+	if len(tokens1) != len(tokens2) {
+		t.Fatalf("Expected renamed code to have the same number of tokens, got %d and %d", len(tokens1), len(tokens2))
+	}
+	for i := range tokens1 {
+		if tokens1[i].Hash != tokens2[i].Hash {
+			t.Fatalf("Token %d differs (lines %d and %d)", i, tokens1[i].Line, tokens2[i].Line)
+		}
+	}
+	if tokens1[0].Line != 2 || tokens2[0].Line != 4 {
+		t.Fatalf("Expected includes to be skipped, first tokens are on lines %d and %d", tokens1[0].Line, tokens2[0].Line)
+	}
+
+	pascal := Tokenize([]byte("BEGIN { comment } writeln('a''b') end."), "pascal")
+	if len(pascal) != 7 || pascal[0].Hash != hashToken("begin") || pascal[3].Hash != stringHash || pascal[5].Hash != hashToken("end") {
+		t.Fatalf("Unexpected pascal tokenization: %#v", pascal)
+	}
+}
+
+func TestWinnow(t *testing.T) {
+	// Example from the winnowing paper
+	hashes := []uint64{77, 74, 42, 17, 98, 50, 17, 98, 8, 88, 67, 39, 77, 74, 42, 17, 98}
+	fps := winnow(hashes, 4)
+	expected := []fingerprint{{17, 3}, {17, 6}, {8, 8}, {39, 11}, {17, 15}}
+	if len(fps) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, fps)
+	}
+	for i := range fps {
+		if fps[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, fps)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	d := New(DefaultOptions)
+	d.AddDocument([]byte(original), "cc")
+	d.AddDocument([]byte(different), "python")
+	d.AddDocument([]byte(renamed), "cc")
+
+	pairs := d.Compare()
+	if len(pairs) != 1 {
+		t.Fatalf("Expected a single similar pair, got %d", len(pairs))
+	}
+	pair := pairs[0]
+	if pair.Left != 0 || pair.Right != 2 {
+		t.Fatalf("Expected documents 0 and 2 to match, got %d and %d", pair.Left, pair.Right)
+	}
+	if pair.LeftSimilarity < 0.95 || pair.RightSimilarity < 0.95 {
+		t.Fatalf("Expected near identical documents, got similarities %f and %f", pair.LeftSimilarity, pair.RightSimilarity)
+	}
+	if len(pair.Matches) != 1 {
+		t.Fatalf("Expected a single matched region, got %d", len(pair.Matches))
+	}
+	if m := pair.Matches[0]; m.LeftStart != 2 || m.LeftEnd != 19 || m.RightStart != 4 || m.RightEnd != 22 {
+		t.Fatalf("Unexpected matched region %#v", m)
+	}
+}
+
+func TestMaxDocFrequency(t *testing.T) {
+	// The same code submitted 4 times is common code for an absolute threshold of 3
+	d := New(Options{K: 12, Window: 8, MaxDocFrequency: 3})
+	for range 4 {
+		d.AddDocument([]byte(original), "cc")
+	}
+	if pairs := d.Compare(); len(pairs) != 0 {
+		t.Fatalf("Expected common code to be ignored, got %d pairs", len(pairs))
+	}
+
+	// But not for 10% of 40 documents
+	d = New(Options{K: 12, Window: 8, MaxDocFrequency: 3, MaxDocFraction: 0.1})
+	for range 4 {
+		d.AddDocument([]byte(original), "cc")
+	}
+	for range 36 {
+		d.AddDocument([]byte(different), "python")
+	}
+	if maxFreq := d.maxDocFrequency(); maxFreq != 4 {
+		t.Fatalf("Expected a threshold of 4 documents, got %d", maxFreq)
+	}
+	if pairs := d.Compare(); len(pairs) != 6 {
+		t.Fatalf("Expected 6 similar pairs, got %d", len(pairs))
+	}
+
+	// The floor applies to small document sets
+	d = New(Options{K: 12, Window: 8, MaxDocFrequency: 3, MaxDocFraction: 0.1})
+	for range 4 {
+		d.AddDocument([]byte(original), "cc")
+	}
+	if maxFreq := d.maxDocFrequency(); maxFreq != 3 {
+		t.Fatalf("Expected a threshold of 3 documents, got %d", maxFreq)
+	}
+}
@@ -0,0 +1,262 @@
+package plagiarism
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+)
+
+// Token is a normalized lexical token of a source file
+type Token struct {
+	Hash uint64
+	// Line is the 1-indexed line of the source file where the token starts
+	Line int
+}
+
+// langSpec describes how a family of languages is tokenized.
+// Identifiers that are not keywords, numbers and strings are replaced by placeholders,
+// so renaming variables or changing constants doesn't hide copied code
+type langSpec struct {
+	lineComments  []string
+	blockComments [][2]string
+	// stringDelims are tried in order, so longer delimiters must come first
+	stringDelims []string
+	// backslashEscapes is false for languages where quotes are escaped by doubling them
+	backslashEscapes bool
+	// primeIdents allows ' in identifiers, like in Haskell
+	primeIdents     bool
+	caseInsensitive bool
+	// skipLines are prefixes of lines that are ignored altogether, like includes
+	skipLines []string
+	keywords  map[string]bool
+}
+
+func keywordSet(kws string) map[string]bool {
+	set := make(map[string]bool)
+	for _, kw := range strings.Fields(kws) {
+		set[kw] = true
+	}
+	return set
+}
+
+// The language families are the MOSS names of the languages, so that no language configuration needs to change
+var langSpecs = map[string]*langSpec{
+	"cc": {
+		lineComments:     []string{"//"},
+		blockComments:    [][2]string{{"/*", "*/"}},
+		stringDelims:     []string{`"`, "'"},
+		backslashEscapes: true,
+		skipLines:        []string{"#include", "#pragma"},
+		keywords: keywordSet(`auto break case char const continue default do double else enum extern float for goto if int long
+			register return short signed sizeof static struct switch typedef union unsigned void volatile while bool class delete
+			false true new namespace operator private protected public template this throw try catch typename using virtual
+			inline const_cast static_cast dynamic_cast reinterpret_cast nullptr constexpr noexcept decltype define`),
+	},
+	"java": {
+		lineComments:     []string{"//"},
+		blockComments:    [][2]string{{"/*", "*/"}},
+		stringDelims:     []string{`"""`, `"`, "'"},
+		backslashEscapes: true,
+		skipLines:        []string{"import ", "package "},
+		keywords: keywordSet(`abstract assert boolean break byte case catch char class const continue default do double else enum
+			extends final finally float for goto if implements import instanceof int interface long native new package private
+			protected public return short static strictfp super switch synchronized this throw throws transient try void volatile
+			while true false null var`),
+	},
+	"python": {
+		lineComments:     []string{"#"},
+		stringDelims:     []string{`"""`, `'''`, `"`, "'"},
+		backslashEscapes: true,
+		skipLines:        []string{"import ", "from "},
+		keywords: keywordSet(`False None True and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield`),
+	},
+	"pascal": {
+		lineComments:    []string{"//"},
+		blockComments:   [][2]string{{"{", "}"}, {"(*", "*)"}},
+		stringDelims:    []string{"'"},
+		caseInsensitive: true,
+		skipLines:       []string{"uses ", "program "},
+		keywords: keywordSet(`and array begin case const div do downto else end file for function goto if in label mod nil not of or
+			packed procedure program record repeat set then to type until var while with integer longint int64 qword real double
+			boolean char string`),
+	},
+	"haskell": {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"{-", "-}"}},
+		stringDelims:  []string{`"`, "'"},
+		// Haskell escapes strings with backslashes too, but identifiers may contain quotes
+		backslashEscapes: true,
+		primeIdents:      true,
+		skipLines:        []string{"import "},
+		keywords: keywordSet(`case class data default deriving do else foreign if import in infix infixl infixr instance let module
+			newtype of then type where`),
+	},
+	// Used for all the other languages (Go, Rust, Kotlin, ...)
+	"ascii": {
+		lineComments:     []string{"//", "#"},
+		blockComments:    [][2]string{{"/*", "*/"}},
+		stringDelims:     []string{`"""`, `"`, "'", "`"},
+		backslashEscapes: true,
+		skipLines:        []string{"import ", "use ", "package "},
+		keywords: keywordSet(`if else for while return break continue func fn fun let var val const match loop struct impl package
+			import type switch case default true false nil null mut pub use mod in go defer range map chan interface class object
+			when`),
+	},
+}
+
+func specFor(lang string) *langSpec {
+	if spec, ok := langSpecs[lang]; ok {
+		return spec
+	}
+	return langSpecs["ascii"]
+}
+
+func hashToken(val string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(val))
+	return h.Sum64()
+}
+
+var (
+	identHash  = hashToken("<ID>")
+	numberHash = hashToken("<NUM>")
+	stringHash = hashToken("<STR>")
+)
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Tokenize splits the source code into normalized tokens, according to the language family.
+// Whitespace, comments and ignored lines (like includes and imports) are dropped
+func Tokenize(code []byte, lang string) []Token {
+	spec := specFor(lang)
+	var tokens []Token
+
+	line := 1
+	lineStart := true
+	i := 0
+	// advance skips to the given position, counting newlines on the way
+	advance := func(pos int) {
+		pos = min(pos, len(code))
+		line += bytes.Count(code[i:pos], []byte{'\n'})
+		i = pos
+	}
+	skipTo := func(end string) {
+		idx := bytes.Index(code[i:], []byte(end))
+		if idx < 0 {
+			advance(len(code))
+			return
+		}
+		advance(i + idx + len(end))
+	}
+
+outer:
+	for i < len(code) {
+		c := code[i]
+		if c == '\n' {
+			line++
+			i++
+			lineStart = true
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v' {
+			i++
+			continue
+		}
+
+		if lineStart {
+			lineStart = false
+			for _, prefix := range spec.skipLines {
+				if bytes.HasPrefix(code[i:], []byte(prefix)) {
+					skipTo("\n")
+					lineStart = true
+					continue outer
+				}
+			}
+		}
+
+		for _, prefix := range spec.lineComments {
+			if bytes.HasPrefix(code[i:], []byte(prefix)) {
+				// The newline is left for the main loop
+				if idx := bytes.IndexByte(code[i:], '\n'); idx >= 0 {
+					i += idx
+				} else {
+					i = len(code)
+				}
+				continue outer
+			}
+		}
+		for _, delims := range spec.blockComments {
+			if bytes.HasPrefix(code[i:], []byte(delims[0])) {
+				advance(i + len(delims[0]))
+				skipTo(delims[1])
+				continue outer
+			}
+		}
+
+		switch {
+		case isIdentStart(c):
+			start := i
+			for i < len(code) && (isIdentStart(code[i]) || isDigit(code[i]) || (spec.primeIdents && code[i] == '\'')) {
+				i++
+			}
+			word := string(code[start:i])
+			if spec.caseInsensitive {
+				word = strings.ToLower(word)
+			}
+			hash := identHash
+			if spec.keywords[word] {
+				hash = hashToken(word)
+			}
+			tokens = append(tokens, Token{Hash: hash, Line: line})
+			continue
+		case isDigit(c):
+			for i < len(code) && (isIdentStart(code[i]) || isDigit(code[i]) || code[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Hash: numberHash, Line: line})
+			continue
+		}
+
+		for _, delim := range spec.stringDelims {
+			if !bytes.HasPrefix(code[i:], []byte(delim)) {
+				continue
+			}
+			tokens = append(tokens, Token{Hash: stringHash, Line: line})
+			pos := i + len(delim)
+			for pos < len(code) {
+				if spec.backslashEscapes && code[pos] == '\\' {
+					pos += 2
+					continue
+				}
+				if bytes.HasPrefix(code[pos:], []byte(delim)) {
+					pos += len(delim)
+					// Without backslash escapes, quotes are escaped by doubling them
+					if !spec.backslashEscapes && bytes.HasPrefix(code[pos:], []byte(delim)) {
+						pos += len(delim)
+						continue
+					}
+					break
+				}
+				// Unterminated single-character delimited strings stop at the end of the line
+				if len(delim) == 1 && code[pos] == '\n' {
+					break
+				}
+				pos++
+			}
+			advance(pos)
+			continue outer
+		}
+
+		// Operators and punctuation are kept as single characters
+		tokens = append(tokens, Token{Hash: hashToken(string(c)), Line: line})
+		i++
+	}
+	return tokens
+}
@@ -21,6 +21,25 @@ const (
 	ScoringTypeICPC        ScoringType = "acm-icpc"
 )
 
+// BuiltinCheckerType is the comparator used for problems without a custom checker
+type BuiltinCheckerType string
+
+const (
+	// BuiltinCheckerDiff ignores whitespace differences, it's the default
+	BuiltinCheckerDiff           BuiltinCheckerType = ""
+	BuiltinCheckerExact          BuiltinCheckerType = "exact"
+	BuiltinCheckerTokens         BuiltinCheckerType = "tokens"
+	BuiltinCheckerTokensCI       BuiltinCheckerType = "tokens_ci"
+	BuiltinCheckerFloat          BuiltinCheckerType = "float"
+	BuiltinCheckerUnorderedLines BuiltinCheckerType = "unordered_lines"
+	BuiltinCheckerYesNo          BuiltinCheckerType = "yes_no"
+)
+
+var BuiltinCheckerTypes = []BuiltinCheckerType{
+	BuiltinCheckerDiff, BuiltinCheckerExact, BuiltinCheckerTokens, BuiltinCheckerTokensCI,
+	BuiltinCheckerFloat, BuiltinCheckerUnorderedLines, BuiltinCheckerYesNo,
+}
+
 type Problem struct {
 	ID        int       `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
@@ -45,11 +64,30 @@ type Problem struct {
 	// Eval stuff
 	ConsoleInput   bool  `json:"console_input"`
 	ScorePrecision int32 `json:"score_precision"`
+	// NumProcesses is the number of contestant processes the manager talks to, for communication problems
+	NumProcesses int `json:"num_processes"`
+	// BuiltinChecker is used when the problem has no custom checker attachment.
+	// The float comparator accepts a number if either its absolute or relative error is within the epsilons
+	BuiltinChecker    BuiltinCheckerType `json:"builtin_checker"`
+	CheckerAbsEpsilon float64            `json:"checker_abs_epsilon"`
+	CheckerRelEpsilon float64            `json:"checker_rel_epsilon"`
+	// StopOnFailure makes the grader skip the remaining tests of a subtask once one of them scores 0
+	StopOnFailure bool `json:"stop_on_failure"`
+
+	// LanguageLimits overrides, by language name, how the time and memory limits are scaled for some languages
+	LanguageLimits map[string]*LanguageLimits `json:"language_limits"`
 
 	PublishedAt     *time.Time  `json:"published_at"`
 	ScoringStrategy ScoringType `json:"scoring_strategy"`
 }
 
+// LanguageLimits overrides the time multiplier and memory overhead (in kilobytes) of a language.
+// Nil fields keep the defaults from the language definition
+type LanguageLimits struct {
+	TimeMultiplier *float64 `json:"time_multiplier,omitempty"`
+	MemoryOverhead *int     `json:"memory_overhead,omitempty"`
+}
+
 type StatementVariant struct {
 	// Language, ie. ro/en
 	Language string `json:"lang"`
@@ -109,7 +147,7 @@ type ProblemFilter struct {
 	// Currently used for logging statement changes
 	AttachmentID *int `json:"-"`
 
-	// Used for getting problems for MOSS
+	// Used for getting problems for similarity checks
 	ContestID *int `json:"-"`
 
 	SolvedBy    *int `json:"solved_by"`
@@ -148,6 +186,17 @@ type ProblemUpdate struct {
 
 	ScorePrecision  *int32      `json:"score_precision"`
 	ScoringStrategy ScoringType `json:"scoring_strategy"`
+
+	NumProcesses *int `json:"num_processes"`
+
+	BuiltinChecker    *BuiltinCheckerType `json:"builtin_checker"`
+	CheckerAbsEpsilon *float64            `json:"checker_abs_epsilon"`
+	CheckerRelEpsilon *float64            `json:"checker_rel_epsilon"`
+
+	StopOnFailure *bool `json:"stop_on_failure"`
+
+	// LanguageLimits replaces all language limit overrides of the problem, if not nil
+	LanguageLimits map[string]*LanguageLimits `json:"language_limits"`
 }
 
 type Attachment struct {
@@ -197,6 +246,21 @@ type ProblemEvalSettings struct {
 	CheckerName string `json:"has_checker"`
 	// If problem has custom checker that is marked as legacy
 	LegacyChecker bool `json:"legacy_checker"`
+	// If problem has custom checker that follows testlib conventions (exit codes and result file), such as Polygon checkers
+	TestlibChecker bool `json:"testlib_checker"`
+	// Comparator used if the problem has no custom checker, along with its parameters
+	BuiltinChecker    BuiltinCheckerType `json:"builtin_checker"`
+	CheckerAbsEpsilon float64            `json:"checker_abs_epsilon"`
+	CheckerRelEpsilon float64            `json:"checker_rel_epsilon"`
+	// If problem is interactive and has a separate interactor process, this is non-empty
+	InteractorName string `json:"interactor_name"`
+	// If problem is a communication task, with a manager talking to multiple contestant processes, this is non-empty
+	ManagerName string `json:"manager_name"`
+
+	// Test generation pipeline: the generator creates test inputs, the validator checks them and the main solution creates the outputs
+	GeneratorName string `json:"generator_name"`
+	ValidatorName string `json:"validator_name"`
+	SolutionName  string `json:"solution_name"`
 
 	// Stores the list of languages that are allowed to be submitted based on existing attachments
 	LanguageWhitelist []string `json:"lang_whitelist"`
@@ -216,4 +280,9 @@ type ProblemChecklist struct {
 	NumOtherTags  int `json:"num_other_tags" db:"num_other_tags"`
 
 	NumSolutions int `json:"num_sols" db:"num_sols"`
+
+	// Model solution verification, filled from the latest results of the model solutions
+	NumModelSolutions  int `json:"num_model_sols" db:"-"`
+	NumModelMismatches int `json:"num_model_mismatches" db:"-"`
+	NumModelPending    int `json:"num_model_pending" db:"-"`
 }
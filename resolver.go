@@ -0,0 +1,217 @@
+package kilonova
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ResolverStep describes a problem cell revealed by the ICPC resolver
+type ResolverStep struct {
+	UserID    int  `json:"user_id"`
+	ProblemID int  `json:"problem_id"`
+	Solved    bool `json:"solved"`
+
+	OldRank int `json:"old_rank"`
+	NewRank int `json:"new_rank"`
+}
+
+// ResolverState is the ICPC leaderboard after a number of resolver steps
+type ResolverState struct {
+	Leaderboard *ContestLeaderboard `json:"leaderboard"`
+
+	Step     int           `json:"step"`
+	LastStep *ResolverStep `json:"last_step"`
+
+	// Pending holds, for every user, the problems whose results are still hidden
+	Pending map[int][]int `json:"pending"`
+	Done    bool          `json:"done"`
+}
+
+// ComputeICPCTotals recomputes the number of solved problems, penalty and last time of an ICPC entry from its problem results.
+// It mirrors the computation in the contest_icpc_view SQL function
+func (e *LeaderboardEntry) ComputeICPCTotals(startTime time.Time, submissionPenalty int) {
+	e.NumSolved, e.Penalty, e.LastTime = 0, 0, nil
+	for pbID, score := range e.ProblemScores {
+		if !score.Equal(decimal.NewFromInt(100)) {
+			continue
+		}
+		e.NumSolved++
+		e.Penalty += e.ProblemAttempts[pbID]*submissionPenalty + max(int(math.Floor(e.ProblemTimes[pbID])), 0)
+		if t := startTime.Add(time.Duration(e.ProblemTimes[pbID] * float64(time.Minute))); e.LastTime == nil || t.After(*e.LastTime) {
+			e.LastTime = &t
+		}
+	}
+}
+
+func icpcCellHidden(frozen, final *LeaderboardEntry, problemID int) bool {
+	frozenScore, ok1 := frozen.ProblemScores[problemID]
+	finalScore, ok2 := final.ProblemScores[problemID]
+	return ok1 != ok2 || !frozenScore.Equal(finalScore) ||
+		frozen.ProblemAttempts[problemID] != final.ProblemAttempts[problemID] ||
+		frozen.ProblemTimes[problemID] != final.ProblemTimes[problemID]
+}
+
+// ICPCResolver reveals, one problem cell at a time, the results hidden by the leaderboard freeze, like the ICPC resolver.
+// Every step reveals the leftmost hidden problem of the lowest ranked participant that still has hidden results.
+// It only moves forward, so it can be kept around and advanced as more steps are requested
+type ICPCResolver struct {
+	contest      *Contest
+	leaderboard  *ContestLeaderboard
+	finalEntries map[int]*LeaderboardEntry
+
+	step     int
+	lastStep *ResolverStep
+	pending  map[int][]int
+}
+
+// NewICPCResolver creates a resolver starting from the frozen leaderboard, which is updated in place.
+// Both leaderboards must be sorted
+func NewICPCResolver(contest *Contest, frozen, final *ContestLeaderboard) *ICPCResolver {
+	r := &ICPCResolver{
+		contest:      contest,
+		leaderboard:  frozen,
+		finalEntries: make(map[int]*LeaderboardEntry, len(final.Entries)),
+		pending:      make(map[int][]int),
+	}
+	for _, entry := range final.Entries {
+		r.finalEntries[entry.User.ID] = entry
+	}
+	for _, entry := range frozen.Entries {
+		finalEntry, ok := r.finalEntries[entry.User.ID]
+		if !ok {
+			continue
+		}
+		for _, pb := range frozen.ProblemOrder {
+			if icpcCellHidden(entry, finalEntry, pb) {
+				r.pending[entry.User.ID] = append(r.pending[entry.User.ID], pb)
+			}
+		}
+	}
+	return r
+}
+
+// Step returns the number of steps taken so far
+func (r *ICPCResolver) Step() int {
+	return r.step
+}
+
+// Advance takes steps until the given number of steps is reached or all results are revealed
+func (r *ICPCResolver) Advance(steps int) {
+	ld := r.leaderboard
+	for r.step < steps {
+		idx := -1
+		for i := len(ld.Entries) - 1; i >= 0; i-- {
+			if len(r.pending[ld.Entries[i].User.ID]) > 0 {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return
+		}
+
+		entry := ld.Entries[idx]
+		finalEntry := r.finalEntries[entry.User.ID]
+		pb := r.pending[entry.User.ID][0]
+		r.pending[entry.User.ID] = r.pending[entry.User.ID][1:]
+
+		if score, ok := finalEntry.ProblemScores[pb]; ok {
+			entry.ProblemScores[pb] = score
+		} else {
+			delete(entry.ProblemScores, pb)
+		}
+		entry.ProblemAttempts[pb] = finalEntry.ProblemAttempts[pb]
+		if t, ok := finalEntry.ProblemTimes[pb]; ok {
+			entry.ProblemTimes[pb] = t
+		} else {
+			delete(entry.ProblemTimes, pb)
+		}
+
+		if len(r.pending[entry.User.ID]) == 0 {
+			// Fully revealed, so the totals are the final ones
+			delete(r.pending, entry.User.ID)
+			entry.NumSolved, entry.Penalty, entry.LastTime = finalEntry.NumSolved, finalEntry.Penalty, finalEntry.LastTime
+		} else {
+			entry.ComputeICPCTotals(r.contest.StartTime, r.contest.ICPCSubmissionPenalty)
+		}
+
+		oldRank := entry.Rank
+		ld.reposition(idx)
+		ld.Rank()
+
+		r.step++
+		r.lastStep = &ResolverStep{
+			UserID:    entry.User.ID,
+			ProblemID: pb,
+			Solved:    entry.ProblemScores[pb].Equal(decimal.NewFromInt(100)),
+			OldRank:   oldRank,
+			NewRank:   entry.Rank,
+		}
+	}
+}
+
+// State returns a snapshot of the resolver, which isn't changed by further steps
+func (r *ICPCResolver) State() *ResolverState {
+	ld := *r.leaderboard
+	ld.Entries = make([]*LeaderboardEntry, 0, len(r.leaderboard.Entries))
+	for _, entry := range r.leaderboard.Entries {
+		entry2 := *entry
+		entry2.ProblemScores = maps.Clone(entry.ProblemScores)
+		entry2.ProblemAttempts = maps.Clone(entry.ProblemAttempts)
+		entry2.ProblemTimes = maps.Clone(entry.ProblemTimes)
+		ld.Entries = append(ld.Entries, &entry2)
+	}
+	pending := make(map[int][]int, len(r.pending))
+	for userID, pbs := range r.pending {
+		pending[userID] = slices.Clone(pbs)
+	}
+	var lastStep *ResolverStep
+	if r.lastStep != nil {
+		step := *r.lastStep
+		lastStep = &step
+	}
+	return &ResolverState{
+		Leaderboard: &ld,
+
+		Step:     r.step,
+		LastStep: lastStep,
+
+		Pending: pending,
+		Done:    len(pending) == 0,
+	}
+}
+
+// ResolveICPC returns the state of a new resolver after the given number of steps.
+// The frozen leaderboard is updated in place, and both leaderboards must be sorted
+func ResolveICPC(contest *Contest, frozen, final *ContestLeaderboard, steps int) *ResolverState {
+	r := NewICPCResolver(contest, frozen, final)
+	r.Advance(steps)
+	return r.State()
+}
+
+// sortsBefore reports if entry a comes before entry b in the sorted leaderboard. Entries that rank the same are ordered by user ID,
+// like in the contest_icpc_view SQL function
+func (ld *ContestLeaderboard) sortsBefore(a, b *LeaderboardEntry) bool {
+	if ld.ranksBefore(a, b) {
+		return true
+	}
+	return !ld.ranksBefore(b, a) && a.User.ID < b.User.ID
+}
+
+// reposition moves the entry at the given index, whose results changed, to its place in the otherwise sorted leaderboard
+func (ld *ContestLeaderboard) reposition(idx int) {
+	entry := ld.Entries[idx]
+	for idx > 0 && ld.sortsBefore(entry, ld.Entries[idx-1]) {
+		ld.Entries[idx] = ld.Entries[idx-1]
+		idx--
+	}
+	for idx < len(ld.Entries)-1 && ld.sortsBefore(ld.Entries[idx+1], entry) {
+		ld.Entries[idx] = ld.Entries[idx+1]
+		idx++
+	}
+	ld.Entries[idx] = entry
+}
@@ -0,0 +1,92 @@
+package kilonova
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestResolveICPC(t *testing.T) {
+	contest := &Contest{StartTime: time.Now().Add(-5 * time.Hour), ICPCSubmissionPenalty: 20}
+	// Problem results are given as (score, attempts, minutes)
+	entry := func(userID int, results map[int][3]int) *LeaderboardEntry {
+		e := &LeaderboardEntry{
+			User:            &UserBrief{ID: userID},
+			ProblemScores:   make(map[int]decimal.Decimal),
+			ProblemAttempts: make(map[int]int),
+			ProblemTimes:    make(map[int]float64),
+		}
+		for pb, res := range results {
+			e.ProblemScores[pb] = decimal.NewFromInt(int64(res[0]))
+			e.ProblemAttempts[pb] = res[1]
+			if res[0] == 100 {
+				e.ProblemTimes[pb] = float64(res[2])
+			}
+		}
+		e.ComputeICPCTotals(contest.StartTime, contest.ICPCSubmissionPenalty)
+		return e
+	}
+	leaderboard := func(entries ...*LeaderboardEntry) *ContestLeaderboard {
+		ld := &ContestLeaderboard{Type: LeaderboardTypeICPC, ProblemOrder: []int{1, 2}, Entries: entries}
+		slices.SortFunc(ld.Entries, func(a, b *LeaderboardEntry) int {
+			if ld.sortsBefore(a, b) {
+				return -1
+			}
+			return 1
+		})
+		ld.Rank()
+		return ld
+	}
+	resolve := func(steps int) *ResolverState {
+		frozen := leaderboard(
+			entry(1, map[int][3]int{1: {100, 0, 10}, 2: {0, 1, 0}}),
+			entry(2, map[int][3]int{1: {100, 0, 100}, 2: {100, 0, 150}}),
+			entry(3, map[int][3]int{1: {-1, 0, 0}, 2: {-1, 0, 0}}),
+		)
+		final := leaderboard(
+			entry(1, map[int][3]int{1: {100, 0, 10}, 2: {100, 1, 200}}),
+			entry(2, map[int][3]int{1: {100, 0, 100}, 2: {100, 0, 150}}),
+			entry(3, map[int][3]int{1: {100, 0, 250}, 2: {-1, 0, 0}}),
+		)
+		return ResolveICPC(contest, frozen, final, steps)
+	}
+
+	state := resolve(0)
+	if state.Done || len(state.Pending) != 2 || state.LastStep != nil {
+		t.Fatalf("Wanted 2 users with hidden results before resolving, got %v", state.Pending)
+	}
+
+	// The lowest ranked participant is revealed first
+	state = resolve(1)
+	if step := state.LastStep; step == nil || step.UserID != 3 || step.ProblemID != 1 || !step.Solved || step.NewRank != 3 {
+		t.Fatalf("Wrong first step: %+v", state.LastStep)
+	}
+
+	state = resolve(2)
+	if step := state.LastStep; step == nil || step.UserID != 1 || step.ProblemID != 2 || step.OldRank != 2 || step.NewRank != 1 {
+		t.Fatalf("Wrong second step: %+v", state.LastStep)
+	}
+	if !state.Done || state.Leaderboard.Entries[0].Penalty != 230 {
+		t.Fatalf("Resolver should be done with user 1 first, got %+v", state.Leaderboard.Entries[0])
+	}
+
+	// Extra steps do nothing
+	if state = resolve(10); state.Step != 2 || !state.Done {
+		t.Fatalf("Wanted the resolver to stop after 2 steps, got %d", state.Step)
+	}
+
+	// Advancing a resolver step by step gives the same states, and earlier states are not changed
+	frozen, final := resolve(0).Leaderboard, resolve(10).Leaderboard
+	r := NewICPCResolver(contest, frozen, final)
+	first := r.State()
+	r.Advance(1)
+	r.Advance(2)
+	if state := r.State(); state.Step != 2 || !state.Done || state.Leaderboard.Entries[0].User.ID != 1 || state.LastStep.UserID != 1 {
+		t.Fatalf("Wrong state after advancing the resolver: %+v", state)
+	}
+	if first.Step != 0 || first.Done || first.Leaderboard.Entries[0].User.ID != 2 || first.Leaderboard.Entries[2].NumSolved != 0 {
+		t.Fatalf("The first state was changed by advancing the resolver: %+v", first)
+	}
+}
@@ -1,6 +1,7 @@
 package kilonova
 
 import (
+	"syscall"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -56,6 +57,67 @@ type Submission struct {
 	ICPCVerdict    *string  `json:"icpc_verdict"`
 }
 
+// QueuePriority is the class of a submission waiting for evaluation.
+// Submissions in running contests are evaluated first, then practice submissions and finally reevaluations
+type QueuePriority string
+
+const (
+	QueuePriorityContest      QueuePriority = "contest"
+	QueuePriorityPractice     QueuePriority = "practice"
+	QueuePriorityReevaluation QueuePriority = "reevaluation"
+)
+
+// QueuedSubmission is a submission waiting for evaluation, in the order the grader will evaluate it
+type QueuedSubmission struct {
+	SubmissionID int           `json:"submission_id"`
+	UserID       int           `json:"user_id"`
+	ProblemID    int           `json:"problem_id"`
+	ContestID    *int          `json:"contest_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Priority     QueuePriority `json:"priority"`
+
+	// Position starts from 1
+	Position int `json:"position"`
+	// EstimatedWait is a rough estimate, based on the average evaluation time of recent submissions. It's 0 if there's not enough data
+	EstimatedWait time.Duration `json:"estimated_wait"`
+}
+
+// DebugRun is the result of running code compiled with warnings and sanitizers on the example tests of a problem.
+// Debug runs are not saved as submissions
+type DebugRun struct {
+	CompileError bool `json:"compile_error"`
+	// CompileMessage also has the compiler warnings if the compilation succeeded
+	CompileMessage string `json:"compile_message"`
+
+	Tests []*DebugRunTest `json:"tests"`
+}
+
+type DebugRunTest struct {
+	VisibleID int     `json:"visible_id"`
+	Verdict   string  `json:"verdict"`
+	Time      float64 `json:"time"`
+	Memory    int     `json:"memory"`
+
+	Percentage decimal.Decimal `json:"percentage"`
+	// Stderr has the sanitizer reports, along with anything else the program printed to stderr
+	Stderr string `json:"stderr"`
+}
+
+// CustomInvocation is the result of running code on an input given by the user. Like debug runs, custom invocations are not saved
+type CustomInvocation struct {
+	CompileError   bool   `json:"compile_error"`
+	CompileMessage string `json:"compile_message"`
+
+	// Verdict is empty if the program finished successfully
+	Verdict  string  `json:"verdict"`
+	Time     float64 `json:"time"`
+	Memory   int     `json:"memory"`
+	ExitCode int     `json:"exit_code"`
+
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
 type SubmissionUpdate struct {
 	Status Status
 	Score  *decimal.Decimal
@@ -118,6 +180,16 @@ type SubTest struct {
 	VisibleID int `db:"visible_id" json:"visible_id"`
 
 	Score decimal.Decimal `json:"score"`
+
+	// Detailed resource usage, as reported by the sandbox. Time is the CPU time and Memory is the peak memory usage of the control group
+	WallTime     float64 `db:"wall_time" json:"wall_time"`
+	ExitCode     int     `db:"exit_code" json:"exit_code"`
+	ExitSignal   int     `db:"exit_signal" json:"exit_signal"`
+	SignalName   string  `db:"-" json:"signal_name,omitempty"`
+	MaxRSS       int     `db:"max_rss" json:"max_rss"`
+	OOMKilled    bool    `db:"oom_killed" json:"oom_killed"`
+	VoluntaryCSW int     `db:"csw_voluntary" json:"csw_voluntary"`
+	ForcedCSW    int     `db:"csw_forced" json:"csw_forced"`
 }
 
 type SubTestUpdate struct {
@@ -127,6 +199,22 @@ type SubTestUpdate struct {
 	Verdict    *string
 	Done       *bool
 	Skipped    *bool
+
+	WallTime     *float64
+	ExitCode     *int
+	ExitSignal   *int
+	MaxRSS       *int
+	OOMKilled    *bool
+	VoluntaryCSW *int
+	ForcedCSW    *int
+}
+
+// SignalName returns the name of the signal that killed a program, or an empty string if it exited normally
+func SignalName(signal int) string {
+	if signal <= 0 {
+		return ""
+	}
+	return syscall.Signal(signal).String()
 }
 
 type SubmissionSubTask struct {
@@ -148,6 +236,9 @@ type SubmissionSubTask struct {
 
 	ScorePrecision int `json:"score_precision"`
 
+	// Scoring is the subtask's scoring rule at the time of submission
+	Scoring SubtaskScoring `json:"scoring"`
+
 	Subtests []int `json:"subtests"`
 }
 
@@ -0,0 +1,109 @@
+package kilonova
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// SubtaskScoring is the rule by which the percentages of a subtask's tests are combined into the percentage of the subtask
+type SubtaskScoring string
+
+const (
+	// SubtaskScoringMin takes the lowest test percentage. It is the default
+	SubtaskScoringMin SubtaskScoring = "min"
+	// SubtaskScoringSum gives every test an equal share of the subtask score
+	SubtaskScoringSum SubtaskScoring = "sum"
+	// SubtaskScoringProduct multiplies the test percentages, so partial scores compound
+	SubtaskScoringProduct SubtaskScoring = "product"
+	// SubtaskScoringAllOrNothing gives the whole score only if every test got 100%
+	SubtaskScoringAllOrNothing SubtaskScoring = "all_or_nothing"
+	// SubtaskScoringMax takes the best test percentage
+	SubtaskScoringMax SubtaskScoring = "max"
+	// SubtaskScoringDependent takes the lowest test percentage, but only if all subtasks
+	// with a smaller visible ID got 100% (Codeforces-style groups)
+	SubtaskScoringDependent SubtaskScoring = "dependent"
+)
+
+var SubtaskScorings = []SubtaskScoring{
+	SubtaskScoringMin, SubtaskScoringSum, SubtaskScoringProduct,
+	SubtaskScoringAllOrNothing, SubtaskScoringMax, SubtaskScoringDependent,
+}
+
+// subtaskPercentagePrecision is the number of decimals kept for percentages that aren't a test percentage (averages and products)
+const subtaskPercentagePrecision = 6
+
+var hundred = decimal.NewFromInt(100)
+
+func (s SubtaskScoring) Valid() bool {
+	return slices.Contains(SubtaskScorings, s)
+}
+
+// Aggregate combines the test percentages (between 0 and 100) into the subtask percentage.
+// Subtasks without tests are invalidated. Dependencies between subtasks are handled by SubTaskPercentages
+func (s SubtaskScoring) Aggregate(percentages []decimal.Decimal) decimal.Decimal {
+	if len(percentages) == 0 {
+		return decimal.Zero
+	}
+	switch s {
+	case SubtaskScoringSum:
+		return decimal.Sum(percentages[0], percentages[1:]...).Div(decimal.NewFromInt(int64(len(percentages)))).Round(subtaskPercentagePrecision)
+	case SubtaskScoringProduct:
+		rez := hundred
+		for _, p := range percentages {
+			rez = rez.Mul(p.Shift(-2))
+		}
+		return rez.Round(subtaskPercentagePrecision)
+	case SubtaskScoringAllOrNothing:
+		for _, p := range percentages {
+			if p.LessThan(hundred) {
+				return decimal.Zero
+			}
+		}
+		return hundred
+	case SubtaskScoringMax:
+		return decimal.Max(percentages[0], percentages[1:]...)
+	default:
+		return decimal.Min(percentages[0], percentages[1:]...)
+	}
+}
+
+// FailedBy reports if a single test with the given percentage makes the subtask worth 0 points, regardless of its other tests.
+// Dependent subtasks are scored like min within the group, so only a 0% test fails them.
+// That a group can't get 100% anymore (which fails the dependent groups after it) must be tracked separately
+func (s SubtaskScoring) FailedBy(percentage decimal.Decimal) bool {
+	switch s {
+	case SubtaskScoringSum, SubtaskScoringMax:
+		return false
+	case SubtaskScoringAllOrNothing:
+		return percentage.LessThan(hundred)
+	default:
+		return !percentage.IsPositive()
+	}
+}
+
+// SubTaskPercentages computes the final percentages of the given submission subtasks, indexed by their ID.
+// testPercentages holds the percentages of the submission's subtests, indexed by subtest ID
+func SubTaskPercentages(subTasks []*SubmissionSubTask, testPercentages map[int]decimal.Decimal) map[int]decimal.Decimal {
+	stks := slices.Clone(subTasks)
+	slices.SortFunc(stks, func(a, b *SubmissionSubTask) int { return cmp.Compare(a.VisibleID, b.VisibleID) })
+
+	rez := make(map[int]decimal.Decimal, len(stks))
+	previousPassed := true
+	for _, stk := range stks {
+		percentages := make([]decimal.Decimal, 0, len(stk.Subtests))
+		for _, id := range stk.Subtests {
+			if p, ok := testPercentages[id]; ok {
+				percentages = append(percentages, p)
+			}
+		}
+		percentage := stk.Scoring.Aggregate(percentages)
+		if stk.Scoring == SubtaskScoringDependent && !previousPassed {
+			percentage = decimal.Zero
+		}
+		previousPassed = previousPassed && percentage.GreaterThanOrEqual(hundred)
+		rez[stk.ID] = percentage
+	}
+	return rez
+}
@@ -0,0 +1,109 @@
+package kilonova
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSubtaskScoringAggregate(t *testing.T) {
+	pcts := []decimal.Decimal{decimal.NewFromInt(100), decimal.NewFromInt(50), decimal.NewFromInt(20)}
+	var expected = map[SubtaskScoring]string{
+		SubtaskScoringMin:          "20",
+		SubtaskScoringSum:          "56.666667",
+		SubtaskScoringProduct:      "10",
+		SubtaskScoringAllOrNothing: "0",
+		SubtaskScoringMax:          "100",
+		SubtaskScoringDependent:    "20",
+	}
+	for _, scoring := range SubtaskScorings {
+		if got := scoring.Aggregate(pcts); got.String() != expected[scoring] {
+			t.Fatalf("Wanted %s for %q, got %s", expected[scoring], scoring, got)
+		}
+		if got := scoring.Aggregate(nil); !got.IsZero() {
+			t.Fatalf("Empty %q subtask should be invalidated, got %s", scoring, got)
+		}
+	}
+
+	full := []decimal.Decimal{decimal.NewFromInt(100), decimal.NewFromInt(100)}
+	if got := SubtaskScoringAllOrNothing.Aggregate(full); !got.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("Solved all_or_nothing subtask got %s", got)
+	}
+}
+
+func TestSubTaskPercentages(t *testing.T) {
+	tests := map[int]decimal.Decimal{
+		1: decimal.NewFromInt(100),
+		2: decimal.NewFromInt(100),
+		3: decimal.NewFromInt(40),
+		4: decimal.NewFromInt(100),
+	}
+	// Given out of order, to check that dependencies follow the visible IDs
+	stks := []*SubmissionSubTask{
+		{ID: 13, VisibleID: 3, Scoring: SubtaskScoringDependent, Subtests: []int{4}},
+		{ID: 11, VisibleID: 1, Scoring: SubtaskScoringDependent, Subtests: []int{1, 2}},
+		{ID: 12, VisibleID: 2, Scoring: SubtaskScoringSum, Subtests: []int{2, 3}},
+	}
+	pcts := SubTaskPercentages(stks, tests)
+	var expected = map[int]string{11: "100", 12: "70", 13: "0"}
+	for id, want := range expected {
+		if got := pcts[id]; got.String() != want {
+			t.Fatalf("Wanted %s for subtask %d, got %s", want, id, got)
+		}
+	}
+
+	// Once the earlier subtasks are solved, the dependent subtask counts
+	tests[3] = decimal.NewFromInt(100)
+	if got := SubTaskPercentages(stks, tests)[13]; !got.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("Dependent subtask should count after solving the previous ones, got %s", got)
+	}
+}
+
+// TestSubtaskScoringShortCircuit checks that skipping the tests after a failure, as done when evaluation stops on failure, gives the same score as a full run
+func TestSubtaskScoringShortCircuit(t *testing.T) {
+	var runs = map[string][]int{
+		"solved":         {100, 100, 100},
+		"partial":        {100, 50, 100},
+		"partialLast":    {100, 100, 50},
+		"zero":           {100, 0, 100},
+		"partialAndZero": {50, 0, 100},
+	}
+	for name, run := range runs {
+		for _, scoring := range SubtaskScorings {
+			// The second subtask depends on the first one, if its scoring is dependent
+			stks := []*SubmissionSubTask{
+				{ID: 1, VisibleID: 1, Scoring: scoring, Subtests: []int{1, 2, 3}},
+				{ID: 2, VisibleID: 2, Scoring: scoring, Subtests: []int{4}},
+			}
+			full := make(map[int]decimal.Decimal)
+			for i, pct := range run {
+				full[i+1] = decimal.NewFromInt(int64(pct))
+			}
+			full[4] = decimal.NewFromInt(100)
+
+			// Skipped tests count as 0%
+			shortCircuit := make(map[int]decimal.Decimal)
+			failed, imperfect := false, false
+			for i := 1; i <= 3; i++ {
+				if failed {
+					shortCircuit[i] = decimal.Zero
+					continue
+				}
+				shortCircuit[i] = full[i]
+				failed = failed || scoring.FailedBy(full[i])
+				imperfect = imperfect || full[i].LessThan(decimal.NewFromInt(100))
+			}
+			shortCircuit[4] = full[4]
+			if scoring == SubtaskScoringDependent && imperfect {
+				shortCircuit[4] = decimal.Zero
+			}
+
+			want, got := SubTaskPercentages(stks, full), SubTaskPercentages(stks, shortCircuit)
+			for id := range want {
+				if !want[id].Equal(got[id]) {
+					t.Fatalf("%s, %q: subtask %d got %s with a full run, but %s when stopping on failure", name, scoring, id, want[id], got[id])
+				}
+			}
+		}
+	}
+}
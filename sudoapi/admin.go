@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/internal/util"
 	"github.com/davecgh/go-spew/spew"
@@ -56,9 +57,8 @@ func (s *BaseAPI) ResetWaitingSubmissions(ctx context.Context) *StatusError {
 }
 
 func (s *BaseAPI) ResetSubmission(ctx context.Context, id int) *StatusError {
-	if err := s.db.ResetSubmissions(ctx, kilonova.SubmissionFilter{ID: &id}); err != nil {
-		zap.S().Warn("Couldn't reset submission: ", err)
-		return Statusf(500, "Couldn't reset submission")
+	if err := s.PrepareReevaluation(ctx, id); err != nil {
+		return err
 	}
 
 	// Wake grader to start processing immediately
@@ -67,6 +67,16 @@ func (s *BaseAPI) ResetSubmission(ctx context.Context, id int) *StatusError {
 	return nil
 }
 
+// PrepareReevaluation resets a submission picked up by the grader for reevaluation.
+// Unlike ResetSubmission, it doesn't wake the grader, since the grader already knows about it
+func (s *BaseAPI) PrepareReevaluation(ctx context.Context, id int) *StatusError {
+	if err := s.db.ResetSubmissions(ctx, kilonova.SubmissionFilter{ID: &id}); err != nil {
+		zap.S().Warn("Couldn't reset submission: ", err)
+		return Statusf(500, "Couldn't reset submission")
+	}
+	return nil
+}
+
 func (s *BaseAPI) SetAdmin(ctx context.Context, userID int, toSet bool) *StatusError {
 	if userID <= 0 {
 		return Statusf(400, "Invalid ID")
@@ -372,10 +382,41 @@ func (s *BaseAPI) WakeGrader() {
 	}
 }
 
-func (s *BaseAPI) RegisterGrader(gr interface{ Wake() }) {
+// ReloadLanguages reloads the languages file, so language changes don't need a restart
+func (s *BaseAPI) ReloadLanguages(ctx context.Context) *StatusError {
+	if err := eval.LoadLanguages(); err != nil {
+		return WrapError(err, "Couldn't reload languages")
+	}
+	s.LogUserAction(ctx, "Reloaded languages")
+	return nil
+}
+
+// Grader is the interface of the grader, as needed by the API
+type Grader interface {
+	Wake()
+	Queue(ctx context.Context) ([]*kilonova.QueuedSubmission, error)
+	DebugRun(ctx context.Context, problem *kilonova.Problem, tests []*kilonova.Test, code []byte, lang string) (*kilonova.DebugRun, error)
+	CustomInvocation(ctx context.Context, problem *kilonova.Problem, code []byte, lang string, input []byte) (*kilonova.CustomInvocation, error)
+	// GenerateTests saves the input and output of every generated test in dir, as <visible id>.in and <visible id>.out
+	GenerateTests(ctx context.Context, problem *kilonova.Problem, invocations []*GeneratorInvocation, dir string) error
+}
+
+func (s *BaseAPI) RegisterGrader(gr Grader) {
 	s.grader = gr
 }
 
+// SubmissionQueue returns the submissions waiting for evaluation, in the order they will be evaluated
+func (s *BaseAPI) SubmissionQueue(ctx context.Context) ([]*kilonova.QueuedSubmission, *StatusError) {
+	if s.grader == nil {
+		return nil, Statusf(503, "Grader is not running")
+	}
+	queue, err := s.grader.Queue(ctx)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get submission queue")
+	}
+	return queue, nil
+}
+
 func (ll logLevel) IsAuditLogLvl() bool {
 	return ll == logLevelSystem || ll == logLevelImportant || ll == logLevelWarning || ll == logLevelDiscord
 }
@@ -370,6 +370,14 @@ func (s *BaseAPI) ProblemSettings(ctx context.Context, problemID int) (*kilonova
 		return nil, WrapError(err, "Couldn't get problem settings")
 	}
 
+	pb, err := s.Problem(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+	settings.BuiltinChecker = pb.BuiltinChecker
+	settings.CheckerAbsEpsilon = pb.CheckerAbsEpsilon
+	settings.CheckerRelEpsilon = pb.CheckerRelEpsilon
+
 	var whitelistC, whitelistCPP bool
 	var biggestCPP string
 
@@ -382,11 +390,39 @@ func (s *BaseAPI) ProblemSettings(ctx context.Context, problemID int) (*kilonova
 		if filename == "checker_legacy" && eval.GetLangByFilename(att.Name) != "" {
 			settings.CheckerName = att.Name
 			settings.LegacyChecker = true
+			settings.TestlibChecker = false
+			continue
+		}
+		if filename == "checker_testlib" && eval.GetLangByFilename(att.Name) != "" {
+			settings.CheckerName = att.Name
+			settings.LegacyChecker = false
+			settings.TestlibChecker = true
 			continue
 		}
 		if filename == "checker" && eval.GetLangByFilename(att.Name) != "" {
 			settings.CheckerName = att.Name
 			settings.LegacyChecker = false
+			settings.TestlibChecker = false
+			continue
+		}
+		if filename == "interactor" && eval.GetLangByFilename(att.Name) != "" {
+			settings.InteractorName = att.Name
+			continue
+		}
+		if filename == "manager" && eval.GetLangByFilename(att.Name) != "" {
+			settings.ManagerName = att.Name
+			continue
+		}
+		if filename == "generator" && eval.GetLangByFilename(att.Name) != "" {
+			settings.GeneratorName = att.Name
+			continue
+		}
+		if filename == "validator" && eval.GetLangByFilename(att.Name) != "" {
+			settings.ValidatorName = att.Name
+			continue
+		}
+		if filename == "solution" && eval.GetLangByFilename(att.Name) != "" {
+			settings.SolutionName = att.Name
 			continue
 		}
 
@@ -426,7 +462,7 @@ func (s *BaseAPI) ProblemSettings(ctx context.Context, problemID int) (*kilonova
 
 	if whitelistCPP {
 		// limit cpp version to the ones >= the grader has
-		for name := range eval.Langs {
+		for name := range eval.Langs() {
 			if strings.HasPrefix(name, "cpp") && name >= biggestCPP {
 				settings.LanguageWhitelist = append(settings.LanguageWhitelist, name)
 			}
@@ -434,7 +470,7 @@ func (s *BaseAPI) ProblemSettings(ctx context.Context, problemID int) (*kilonova
 	} else if whitelistC {
 		// Allow C and don't limit cpp version
 		settings.LanguageWhitelist = append(settings.LanguageWhitelist, "c")
-		for name := range eval.Langs {
+		for name := range eval.Langs() {
 			if strings.HasPrefix(name, "cpp") {
 				settings.LanguageWhitelist = append(settings.LanguageWhitelist, name)
 			}
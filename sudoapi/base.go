@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -35,7 +36,18 @@ type BaseAPI struct {
 
 	sessionUserCache *theine.LoadingCache[string, *kilonova.UserFull]
 
-	grader interface{ Wake() }
+	grader Grader
+	// runLimiter rate limits debug runs and custom invocations
+	runLimiter *userRunLimiter
+
+	// testLocks serializes the operations that write the tests of a problem
+	testLocks  problemLocks
+	testGensMu sync.Mutex
+	testGens   map[int]*TestGeneration
+
+	// resolvers caches the ICPC resolvers of contests, see ContestResolver
+	resolversMu sync.Mutex
+	resolvers   map[resolverKey]*cachedResolver
 
 	logChan chan *logEntry
 
@@ -43,6 +55,7 @@ type BaseAPI struct {
 	attachmentCacheBucket *datastore.Bucket
 	subtestBucket         *datastore.Bucket
 	avatarBucket          *datastore.Bucket
+	outputBucket          *datastore.Bucket
 }
 
 func (s *BaseAPI) Start(ctx context.Context) {
@@ -67,13 +80,15 @@ func GetBaseAPI(db *db.DB, mailer kilonova.Mailer) (*BaseAPI, *StatusError) {
 
 		sessionUserCache: nil,
 
-		grader:  nil,
-		logChan: make(chan *logEntry, 50),
+		grader:     nil,
+		runLimiter: newUserRunLimiter(),
+		logChan:    make(chan *logEntry, 50),
 
 		testBucket:            datastore.GetBucket(datastore.BucketTypeTests),
 		attachmentCacheBucket: datastore.GetBucket(datastore.BucketTypeAttachments),
 		subtestBucket:         datastore.GetBucket(datastore.BucketTypeSubtests),
 		avatarBucket:          datastore.GetBucket(datastore.BucketTypeAvatars),
+		outputBucket:          datastore.GetBucket(datastore.BucketTypeOutputs),
 	}
 	sUserCache, err := theine.NewBuilder[string, *kilonova.UserFull](500).BuildWithLoader(func(ctx context.Context, sid string) (theine.Loaded[*kilonova.UserFull], error) {
 		user, err := base.sessionUser(ctx, sid)
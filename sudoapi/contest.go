@@ -2,12 +2,10 @@ package sudoapi
 
 import (
 	"context"
-	"fmt"
+	"sync"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
-	"github.com/KiloProjects/kilonova/eval"
-	"github.com/KiloProjects/kilonova/integrations/moss"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"go.uber.org/zap"
 )
@@ -153,22 +151,138 @@ func (s *BaseAPI) ProblemRunningContests(ctx context.Context, problemID int) ([]
 }
 
 func (s *BaseAPI) ContestLeaderboard(ctx context.Context, contest *kilonova.Contest, freezeTime *time.Time, filter kilonova.UserFilter) (*kilonova.ContestLeaderboard, *StatusError) {
+	var leaderboard *kilonova.ContestLeaderboard
+	var err error
 	switch contest.LeaderboardStyle {
 	case kilonova.LeaderboardTypeClassic:
-		leaderboard, err := s.db.ContestClassicLeaderboard(ctx, contest, freezeTime, &filter)
+		leaderboard, err = s.db.ContestClassicLeaderboard(ctx, contest, freezeTime, &filter)
+	case kilonova.LeaderboardTypeICPC:
+		leaderboard, err = s.db.ContestICPCLeaderboard(ctx, contest, freezeTime, &filter)
+	default:
+		return nil, Statusf(400, "Invalid contest leaderboard type")
+	}
+	if err != nil {
+		return nil, WrapError(err, "Couldn't generate leaderboard")
+	}
+
+	categories, err1 := s.ContestCategories(ctx, contest.ID)
+	if err1 != nil {
+		return nil, err1
+	}
+	leaderboard.Categories = categories
+	leaderboard.Rank()
+	return leaderboard, nil
+}
+
+// VirtualContestLeaderboard returns the leaderboard as seen by a virtual participant: the original participants
+// as they stood after the time the participant has spent in the contest, alongside the participant's ghost entry.
+// The original participants of USACO-style contests all started at different times, so their results can't be cut off at a single moment
+func (s *BaseAPI) VirtualContestLeaderboard(ctx context.Context, contest *kilonova.Contest, reg *kilonova.ContestRegistration, freezeTime *time.Time, filter kilonova.UserFilter) (*kilonova.ContestLeaderboard, *StatusError) {
+	if !reg.Virtual || reg.IndividualStartTime == nil || reg.IndividualEndTime == nil {
+		return nil, Statusf(400, "Registration is not a virtual participation")
+	}
+	if contest.PerUserTime > 0 {
+		return nil, Statusf(400, "Virtual leaderboards are not available for contests with individual timers")
+	}
+	now := time.Now()
+	if reg.IndividualEndTime.Before(now) {
+		now = *reg.IndividualEndTime
+	}
+	elapsedTime := contest.StartTime.Add(now.Sub(*reg.IndividualStartTime))
+	if freezeTime == nil || elapsedTime.Before(*freezeTime) {
+		freezeTime = &elapsedTime
+	}
+
+	leaderboard, err := s.ContestLeaderboard(ctx, contest, freezeTime, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ContestCategoryID == nil || (reg.CategoryID != nil && *reg.CategoryID == *filter.ContestCategoryID) {
+		ghost, err := s.db.ContestVirtualEntry(ctx, contest, reg)
 		if err != nil {
-			return nil, WrapError(err, "Couldn't generate leaderboard")
+			return nil, WrapError(err, "Couldn't get virtual participation results")
 		}
-		return leaderboard, nil
-	case kilonova.LeaderboardTypeICPC:
-		leaderboard, err := s.db.ContestICPCLeaderboard(ctx, contest, freezeTime, &filter)
+		leaderboard.AddGhost(ghost)
+		leaderboard.Rank()
+	}
+	return leaderboard, nil
+}
+
+// resolverCacheTTL is how long a resolver is reused before being rebuilt, so later reevaluations are taken into account
+const resolverCacheTTL = 10 * time.Minute
+
+type resolverKey struct {
+	contestID int
+	// generated is the filter on generated accounts: nil, or a pointer to false/true, encoded as 0, 1 or 2
+	generated int
+}
+
+type cachedResolver struct {
+	mu        sync.Mutex
+	resolver  *kilonova.ICPCResolver
+	createdAt time.Time
+}
+
+// cachedResolver returns the cached resolver for the key, creating an empty one if needed. Expired resolvers are evicted
+func (s *BaseAPI) cachedResolver(key resolverKey) *cachedResolver {
+	s.resolversMu.Lock()
+	defer s.resolversMu.Unlock()
+	if s.resolvers == nil {
+		s.resolvers = make(map[resolverKey]*cachedResolver)
+	}
+	for k, r := range s.resolvers {
+		if k != key && time.Since(r.createdAt) > resolverCacheTTL {
+			delete(s.resolvers, k)
+		}
+	}
+	r, ok := s.resolvers[key]
+	if !ok {
+		r = &cachedResolver{}
+		s.resolvers[key] = r
+	}
+	return r
+}
+
+// ContestResolver returns the state of the ICPC resolver after the given number of steps.
+// The resolver starts from the frozen leaderboard and reveals the results hidden by the freeze, one problem at a time.
+// Resolvers are cached, so stepping forward only computes the new steps
+func (s *BaseAPI) ContestResolver(ctx context.Context, contest *kilonova.Contest, steps int, generated *bool) (*kilonova.ResolverState, *StatusError) {
+	if contest.LeaderboardStyle != kilonova.LeaderboardTypeICPC {
+		return nil, Statusf(400, "The resolver is only available for ICPC-style contests")
+	}
+	if contest.LeaderboardFreeze == nil || !contest.Ended() {
+		return nil, Statusf(400, "The resolver is only available for ended contests with a frozen leaderboard")
+	}
+	if steps < 0 {
+		return nil, Statusf(400, "Invalid number of steps")
+	}
+
+	key := resolverKey{contestID: contest.ID}
+	if generated != nil {
+		key.generated = 1
+		if *generated {
+			key.generated = 2
+		}
+	}
+	cached := s.cachedResolver(key)
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if cached.resolver == nil || cached.resolver.Step() > steps || time.Since(cached.createdAt) > resolverCacheTTL {
+		filter := kilonova.UserFilter{Generated: generated}
+		frozen, err := s.ContestLeaderboard(ctx, contest, contest.LeaderboardFreeze, filter)
 		if err != nil {
-			return nil, WrapError(err, "Couldn't generate leaderboard")
+			return nil, err
 		}
-		return leaderboard, nil
-	default:
-		return nil, Statusf(400, "Invalid contest leaderboard type")
+		final, err := s.ContestLeaderboard(ctx, contest, nil, filter)
+		if err != nil {
+			return nil, err
+		}
+		cached.resolver = kilonova.NewICPCResolver(contest, frozen, final)
+		cached.createdAt = time.Now()
 	}
+	cached.resolver.Advance(steps)
+	return cached.resolver.State(), nil
 }
 
 func (s *BaseAPI) CanJoinContest(c *kilonova.Contest) bool {
@@ -181,12 +295,27 @@ func (s *BaseAPI) CanJoinContest(c *kilonova.Contest) bool {
 	return !c.Started()
 }
 
+// CanStartVirtualContest checks if users can start a virtual participation in the contest.
+// Only ended, visible, official contests can be replayed. Contests with individual timers (USACO-style) have no common timeline
+// to compare the virtual participation against, so they can't be replayed either
+func (s *BaseAPI) CanStartVirtualContest(c *kilonova.Contest) bool {
+	return c.Type == kilonova.ContestTypeOfficial && c.Visible && c.Ended() && c.PerUserTime == 0
+}
+
 // CanSubmitInContest checks if the user is either a contestant and the contest is running, or a tester/editor/admin.
-// Ended contests cannot have submissions created by anyone
+// Ended contests cannot have submissions created by anyone, except for virtual participants during their run
 // Also, USACO-style contests are fun to handle...
 func (s *BaseAPI) CanSubmitInContest(user *kilonova.UserBrief, c *kilonova.Contest) bool {
 	if c.Ended() {
-		return false
+		if user == nil {
+			return false
+		}
+		reg, err := s.db.ContestRegistration(context.Background(), c.ID, user.ID)
+		if err != nil {
+			zap.S().Warn(err)
+			return false
+		}
+		return reg.VirtualRunning()
 	}
 	if s.IsContestTester(user, c) {
 		return true
@@ -281,88 +410,3 @@ func (s *BaseAPI) StripContestAccess(ctx context.Context, pbid int, uid int) *St
 	}
 	return nil
 }
-
-func (s *BaseAPI) RunMOSS(ctx context.Context, contest *kilonova.Contest) *StatusError {
-	pbs, err := s.Problems(ctx, kilonova.ProblemFilter{ContestID: &contest.ID})
-	if err != nil {
-		return err
-	}
-
-	for _, pb := range pbs {
-		subs, err := s.RawSubmissions(ctx, kilonova.SubmissionFilter{
-			ProblemID: &pb.ID,
-			ContestID: &contest.ID,
-
-			Ordering:  "score",
-			Ascending: false,
-		})
-		if err != nil {
-			return err
-		}
-		if len(subs) == 0 {
-			continue
-		}
-		mossSubs := make(map[string][]*kilonova.Submission)
-		for _, sub := range subs {
-			name := eval.Langs[sub.Language].MOSSName
-			// TODO: See if this can be simplified?
-			_, ok := mossSubs[name]
-			if !ok {
-				mossSubs[name] = []*kilonova.Submission{sub}
-			} else {
-				mossSubs[name] = append(mossSubs[name], sub)
-			}
-		}
-
-		for mossLang, subs := range mossSubs {
-			var lang eval.Language
-			for _, elang := range eval.Langs {
-				if elang.MOSSName == mossLang && (lang.InternalName == "" || lang.InternalName < elang.InternalName) {
-					lang = elang
-				}
-			}
-
-			zap.S().Debugf("%s - %s - %d", pb.Name, lang.InternalName, len(subs))
-			conn, err1 := moss.New(ctx)
-			if err1 != nil {
-				return WrapError(err1, "Could not initialize MOSS")
-			}
-			users := make(map[int]bool)
-			for _, sub := range subs {
-				if _, ok := users[sub.UserID]; ok {
-					continue
-				}
-				user, err := s.UserBrief(ctx, sub.UserID)
-				if err != nil {
-					return err
-				}
-				users[sub.UserID] = true
-
-				code, err := s.RawSubmissionCode(ctx, sub.ID)
-				if err != nil {
-					return err
-				}
-				conn.AddFile(eval.Langs[sub.Language], user.Name, code)
-			}
-			url, err1 := conn.Process(&moss.Options{
-				Language: lang,
-				Comment:  fmt.Sprintf("%s - %s (%s)", contest.Name, pb.Name, lang.PrintableName),
-			})
-			if err1 != nil {
-				return WrapError(err1, "Could not get MOSS result")
-			}
-			if _, err := s.db.InsertMossSubmission(ctx, contest.ID, pb.ID, lang, url, len(users)); err != nil {
-				return WrapError(err, "Could not commit MOSS result to DB")
-			}
-		}
-	}
-	return nil
-}
-
-func (s *BaseAPI) MOSSSubmissions(ctx context.Context, contestID int) ([]*kilonova.MOSSSubmission, *StatusError) {
-	subs, err := s.db.MossSubmissions(ctx, contestID)
-	if err != nil {
-		return nil, WrapError(err, "Couldn't fetch MOSS submissions")
-	}
-	return subs, nil
-}
@@ -3,6 +3,7 @@ package sudoapi
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -18,7 +19,16 @@ func (s *BaseAPI) RegisterContestUser(ctx context.Context, contest *kilonova.Con
 		return Statusf(400, "Regular joining is disallowed")
 	}
 
-	if err := s.db.InsertContestRegistration(ctx, contest.ID, userID, invitationID); err != nil {
+	var categoryID *int
+	if invitationID != nil {
+		inv, err := s.ContestInvitation(ctx, *invitationID)
+		if err != nil {
+			return err
+		}
+		categoryID = inv.CategoryID
+	}
+
+	if err := s.db.InsertContestRegistration(ctx, contest.ID, userID, invitationID, categoryID); err != nil {
 		return WrapError(err, "Couldn't register user for contest")
 	}
 	return nil
@@ -30,10 +40,14 @@ func (s *BaseAPI) StartContestRegistration(ctx context.Context, contest *kilonov
 	}
 
 	reg, err := s.ContestRegistration(ctx, contest.ID, userID)
-	if err != nil && !errors.Is(err, ErrNotFound) {
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
 		if err := s.RegisterContestUser(ctx, contest, userID, nil, false); err != nil {
 			return err
 		}
+		reg = &kilonova.ContestRegistration{}
 	}
 
 	if reg.IndividualStartTime != nil {
@@ -48,8 +62,33 @@ func (s *BaseAPI) StartContestRegistration(ctx context.Context, contest *kilonov
 	return nil
 }
 
-func (s *BaseAPI) ContestRegistrations(ctx context.Context, contestID int, fuzzyName *string, inviteID *string, limit, offset int) ([]*kilonova.ContestRegistration, *StatusError) {
-	regs, err := s.db.ContestRegistrations(ctx, contestID, fuzzyName, inviteID, limit, offset)
+// StartVirtualContest starts a personal run of an ended contest for the user, lasting as much as an actual participation.
+// Users that took part in the contest (or already started a virtual run) cannot start another one
+func (s *BaseAPI) StartVirtualContest(ctx context.Context, contest *kilonova.Contest, userID int) *StatusError {
+	if !s.CanStartVirtualContest(contest) {
+		return Statusf(400, "Virtual participations are only available for ended official contests without individual timers")
+	}
+
+	reg, err := s.ContestRegistration(ctx, contest.ID, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if reg != nil {
+		if reg.Virtual {
+			return Statusf(400, "User already started a virtual participation")
+		}
+		return Statusf(400, "User already participated in the contest")
+	}
+
+	startTime := time.Now()
+	if err := s.db.InsertVirtualContestRegistration(ctx, contest.ID, userID, startTime, startTime.Add(contest.VirtualDuration())); err != nil {
+		return WrapError(err, "Couldn't start virtual participation")
+	}
+	return nil
+}
+
+func (s *BaseAPI) ContestRegistrations(ctx context.Context, contestID int, fuzzyName *string, inviteID *string, categoryID *int, limit, offset int) ([]*kilonova.ContestRegistration, *StatusError) {
+	regs, err := s.db.ContestRegistrations(ctx, contestID, fuzzyName, inviteID, categoryID, limit, offset)
 	if err != nil {
 		return nil, WrapError(err, "Couldn't get registrations")
 	}
@@ -76,6 +115,24 @@ func (s *BaseAPI) ContestRegistration(ctx context.Context, contestID, userID int
 	return reg, nil
 }
 
+func (s *BaseAPI) UpdateContestRegistration(ctx context.Context, contestID, userID int, upd kilonova.ContestRegistrationUpdate) *StatusError {
+	if upd.CategoryID != nil {
+		if _, err := s.ContestCategory(ctx, contestID, *upd.CategoryID); err != nil {
+			return err
+		}
+	}
+	if upd.School != nil {
+		*upd.School = strings.TrimSpace(*upd.School)
+	}
+	if upd.Region != nil {
+		*upd.Region = strings.TrimSpace(*upd.Region)
+	}
+	if err := s.db.UpdateContestRegistration(ctx, contestID, userID, upd); err != nil {
+		return WrapError(err, "Couldn't update registration")
+	}
+	return nil
+}
+
 func (s *BaseAPI) KickUserFromContest(ctx context.Context, contestID, userID int) *StatusError {
 	if err := s.db.DeleteContestRegistration(ctx, contestID, userID); err != nil {
 		return WrapError(err, "Couldn't kick contestant")
@@ -86,12 +143,17 @@ func (s *BaseAPI) KickUserFromContest(ctx context.Context, contestID, userID int
 	return nil
 }
 
-func (s *BaseAPI) CreateContestInvitation(ctx context.Context, contestID int, author *kilonova.UserBrief, maxUses *int) (string, *StatusError) {
+func (s *BaseAPI) CreateContestInvitation(ctx context.Context, contestID int, author *kilonova.UserBrief, maxUses *int, categoryID *int) (string, *StatusError) {
 	var id *int
 	if author != nil {
 		id = &author.ID
 	}
-	invID, err := s.db.CreateContestInvitation(ctx, contestID, id, maxUses)
+	if categoryID != nil {
+		if _, err := s.ContestCategory(ctx, contestID, *categoryID); err != nil {
+			return "", err
+		}
+	}
+	invID, err := s.db.CreateContestInvitation(ctx, contestID, id, maxUses, categoryID)
 	if err != nil {
 		return "", WrapError(err, "Couldn't create invitation")
 	}
@@ -123,3 +185,65 @@ func (s *BaseAPI) ContestInvitation(ctx context.Context, id string) (*kilonova.C
 	}
 	return inv, nil
 }
+
+func (s *BaseAPI) ContestCategories(ctx context.Context, contestID int) ([]*kilonova.ContestCategory, *StatusError) {
+	categories, err := s.db.ContestCategories(ctx, contestID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get categories")
+	}
+	return categories, nil
+}
+
+// ContestCategory returns the category with the given ID, if it belongs to the contest
+func (s *BaseAPI) ContestCategory(ctx context.Context, contestID int, id int) (*kilonova.ContestCategory, *StatusError) {
+	category, err := s.db.ContestCategory(ctx, id)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get category")
+	}
+	if category == nil || category.ContestID != contestID {
+		return nil, WrapError(ErrNotFound, "Category not found")
+	}
+	return category, nil
+}
+
+func validateCategoryName(name string) (string, *StatusError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", Statusf(400, "Category name must not be empty")
+	}
+	if len(name) > 64 {
+		return "", Statusf(400, "Category name is too long")
+	}
+	return name, nil
+}
+
+func (s *BaseAPI) CreateContestCategory(ctx context.Context, contestID int, name string) (int, *StatusError) {
+	name, err := validateCategoryName(name)
+	if err != nil {
+		return -1, err
+	}
+	id, err1 := s.db.CreateContestCategory(ctx, contestID, name)
+	if err1 != nil {
+		return -1, WrapError(err1, "Couldn't create category")
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) UpdateContestCategory(ctx context.Context, id int, name string) *StatusError {
+	name, err := validateCategoryName(name)
+	if err != nil {
+		return err
+	}
+	if err := s.db.UpdateContestCategory(ctx, id, name); err != nil {
+		return WrapError(err, "Couldn't update category")
+	}
+	return nil
+}
+
+// DeleteContestCategory removes the category. Its participants and invitations are left without a category
+func (s *BaseAPI) DeleteContestCategory(ctx context.Context, id int) *StatusError {
+	if err := s.db.DeleteContestCategory(ctx, id); err != nil {
+		return WrapError(err, "Couldn't delete category")
+	}
+	return nil
+}
@@ -32,6 +32,15 @@ func (s *BaseAPI) SubtestReader(subtest int) (io.ReadCloser, error) {
 	return s.subtestBucket.Reader(strconv.Itoa(subtest))
 }
 
+func (s *BaseAPI) SaveSubtestOutput(subtest int, output io.Reader) error {
+	if err := s.subtestBucket.WriteFile(strconv.Itoa(subtest), output, 0644); err != nil {
+		// Don't keep a partially written output around
+		s.subtestBucket.RemoveFile(strconv.Itoa(subtest))
+		return WrapError(err, "Could not save subtest output")
+	}
+	return nil
+}
+
 func (s *BaseAPI) SaveTestInput(testID int, input io.Reader) error {
 	if err := s.testBucket.WriteFile(strconv.Itoa(testID)+".in", dos2unix.DOS2Unix(input), 0644); err != nil {
 		return WrapError(err, "Could not save test input")
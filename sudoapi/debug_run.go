@@ -0,0 +1,48 @@
+package sudoapi
+
+import (
+	"context"
+	"slices"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/internal/config"
+)
+
+var DebugRunsEnabled = config.GenFlag("feature.grader.debug_runs", true, "Allow users to run their code with sanitizers on the example tests of problems, outside contests")
+
+// DebugRun compiles the code with warnings and sanitizers and runs it on the example tests of the problem.
+// Debug runs are only available in practice mode and are not saved, so they don't count towards the submission limits or the score.
+func (s *BaseAPI) DebugRun(ctx context.Context, user *kilonova.UserBrief, problem *kilonova.Problem, code []byte, lang eval.Language) (*kilonova.DebugRun, *StatusError) {
+	if !DebugRunsEnabled.Value() {
+		return nil, Statusf(403, "Debug runs are disabled")
+	}
+	// Only practice mode, contestants shouldn't get extra feedback during a contest
+	if !s.IsProblemFullyVisible(user, problem) {
+		return nil, Statusf(400, "Debug runs are not available while the problem is in a running contest")
+	}
+	if !lang.SupportsDebugRuns() {
+		return nil, Statusf(400, "Debug runs are not available for %s", lang.PrintableName)
+	}
+
+	tests, err := s.Tests(ctx, problem.ID)
+	if err != nil {
+		return nil, err
+	}
+	tests = slices.DeleteFunc(tests, func(t *kilonova.Test) bool { return !t.Example })
+	if len(tests) == 0 {
+		return nil, Statusf(400, "This problem has no example tests")
+	}
+
+	release, err := s.startRun(ctx, user, problem, code, lang)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	run, err1 := s.grader.DebugRun(ctx, problem, tests, code, lang.InternalName)
+	if err1 != nil {
+		return nil, WrapError(err1, "Couldn't finish debug run")
+	}
+	return run, nil
+}
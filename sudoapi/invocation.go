@@ -0,0 +1,126 @@
+package sudoapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/internal/config"
+)
+
+var (
+	CustomInvocationsEnabled = config.GenFlag("feature.grader.custom_invocations", true, "Allow users to run their code on their own input, without creating a submission")
+
+	UserRunLimit       = config.GenFlag[int]("behavior.runs.user_max_minute", 10, "Maximum number of debug runs and custom invocations per minute (for a single user)")
+	CustomInputMaxSize = config.GenFlag[int]("behavior.runs.max_input_size", 1024*1024, "Maximum size (in bytes) of the input of custom invocations")
+)
+
+// userRunLimiter makes sure every user has at most one debug run or custom invocation in progress
+// and that they don't start more than UserRunLimit per minute
+type userRunLimiter struct {
+	mu      sync.Mutex
+	running map[int]bool
+	recent  map[int][]time.Time
+}
+
+func newUserRunLimiter() *userRunLimiter {
+	return &userRunLimiter{running: make(map[int]bool), recent: make(map[int][]time.Time)}
+}
+
+// acquire marks the start of a run. If it succeeds, release must be called once the run is done
+func (l *userRunLimiter) acquire(userID int) *StatusError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[userID] {
+		return Statusf(http.StatusTooManyRequests, "You already have a run in progress")
+	}
+
+	cutoff := time.Now().Add(-1 * time.Minute)
+	recent := slices.DeleteFunc(l.recent[userID], func(t time.Time) bool { return t.Before(cutoff) })
+	if UserRunLimit.Value() > 0 && len(recent) >= UserRunLimit.Value() {
+		l.recent[userID] = recent
+		return Statusf(http.StatusTooManyRequests, "You cannot start more than %d runs in a minute, please wait a bit", UserRunLimit.Value())
+	}
+	l.recent[userID] = append(recent, time.Now())
+	l.running[userID] = true
+	return nil
+}
+
+func (l *userRunLimiter) release(userID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.running, userID)
+	// Users that didn't run anything in the last minute don't need to be remembered
+	cutoff := time.Now().Add(-1 * time.Minute)
+	if times := l.recent[userID]; len(times) == 0 || times[len(times)-1].Before(cutoff) {
+		delete(l.recent, userID)
+	}
+}
+
+// startRun checks the common constraints of debug runs and custom invocations and marks the start of a run of the user:
+// the language must be allowed for the problem, the code must fit the source size limit, and the problem must not be interactive.
+// If it succeeds, the returned function must be called once the run is done.
+// The global limit of runs in progress is enforced by the grader, which reserves the boxes
+func (s *BaseAPI) startRun(ctx context.Context, user *kilonova.UserBrief, problem *kilonova.Problem, code []byte, lang eval.Language) (func(), *StatusError) {
+	if s.grader == nil {
+		return nil, Statusf(503, "Grader is not running")
+	}
+	if user == nil {
+		return nil, Statusf(401, "You must be logged in to run code")
+	}
+	if !s.IsProblemVisible(user, problem) {
+		return nil, Statusf(400, "You can't see the problem!")
+	}
+	if lang.InternalName == "outputOnly" {
+		return nil, Statusf(400, "Output only submissions can't be run")
+	}
+	if len(code) == 0 {
+		return nil, Statusf(400, "Empty code")
+	}
+	if len(code) > problem.SourceSize {
+		return nil, Statusf(400, "Code exceeds %d characters", problem.SourceSize)
+	}
+
+	settings, err := s.ProblemSettings(ctx, problem.ID)
+	if err != nil {
+		return nil, WrapError(err, "Could not get problem settings")
+	}
+	if len(settings.LanguageWhitelist) > 0 && !slices.Contains(settings.LanguageWhitelist, lang.InternalName) {
+		return nil, Statusf(400, "Language not on whitelist")
+	}
+	if settings.InteractorName != "" || settings.ManagerName != "" {
+		return nil, Statusf(400, "Running code is not available for interactive problems")
+	}
+
+	if err := s.runLimiter.acquire(user.ID); err != nil {
+		return nil, err
+	}
+	return func() { s.runLimiter.release(user.ID) }, nil
+}
+
+// CustomInvocation compiles the code and runs it on the given input, with the limits of the problem.
+// Nothing is saved, so it doesn't count towards the submission limits or the score
+func (s *BaseAPI) CustomInvocation(ctx context.Context, user *kilonova.UserBrief, problem *kilonova.Problem, code []byte, lang eval.Language, input []byte) (*kilonova.CustomInvocation, *StatusError) {
+	if !CustomInvocationsEnabled.Value() {
+		return nil, Statusf(403, "Custom invocations are disabled")
+	}
+	if len(input) > CustomInputMaxSize.Value() {
+		return nil, Statusf(400, "Input exceeds %d bytes", CustomInputMaxSize.Value())
+	}
+
+	release, err1 := s.startRun(ctx, user, problem, code, lang)
+	if err1 != nil {
+		return nil, err1
+	}
+	defer release()
+
+	invocation, err := s.grader.CustomInvocation(ctx, problem, code, lang.InternalName, input)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't run program")
+	}
+	return invocation, nil
+}
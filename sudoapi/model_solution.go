@@ -0,0 +1,119 @@
+package sudoapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+// SetSolutionExpectation marks the submission as a model solution of the problem, with the expected result in text form
+// (see kilonova.ParseSolutionExpectation). An empty expectation unmarks the submission
+func (s *BaseAPI) SetSolutionExpectation(ctx context.Context, problem *kilonova.Problem, subID int, expectation string) *StatusError {
+	sub, err := s.RawSubmission(ctx, subID)
+	if err != nil {
+		return err
+	}
+	if sub.ProblemID != problem.ID {
+		return Statusf(400, "Submission is not for this problem")
+	}
+
+	if strings.TrimSpace(expectation) == "" {
+		if err := s.db.DeleteSolutionExpectation(ctx, subID); err != nil {
+			return WrapError(err, "Couldn't remove expected result")
+		}
+		return nil
+	}
+
+	exp, err1 := kilonova.ParseSolutionExpectation(expectation)
+	if err1 != nil {
+		return Statusf(400, "Invalid expected result: %s", err1)
+	}
+	exp.SubmissionID = subID
+	if err := s.db.SetSolutionExpectation(ctx, exp); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't set expected result")
+	}
+	return nil
+}
+
+func (s *BaseAPI) ProblemSolutionExpectations(ctx context.Context, problemID int) ([]*kilonova.SolutionExpectation, *StatusError) {
+	exps, err := s.db.ProblemSolutionExpectations(ctx, problemID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get model solutions")
+	}
+	return exps, nil
+}
+
+// VerifyProblem reevaluates all model solutions of the problem. The results can be checked using ProblemVerification
+func (s *BaseAPI) VerifyProblem(ctx context.Context, problem *kilonova.Problem) (int, *StatusError) {
+	exps, err := s.ProblemSolutionExpectations(ctx, problem.ID)
+	if err != nil {
+		return -1, err
+	}
+	if len(exps) == 0 {
+		return -1, Statusf(400, "The problem has no model solutions")
+	}
+	ids := make([]int, 0, len(exps))
+	for _, exp := range exps {
+		ids = append(ids, exp.SubmissionID)
+	}
+
+	if err := s.db.BulkUpdateSubmissions(ctx, kilonova.SubmissionFilter{IDs: ids}, kilonova.SubmissionUpdate{
+		Status: kilonova.StatusReevaling,
+	}); err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't mark model solutions for reevaluation")
+	}
+
+	s.LogUserAction(ctx, "Started verification of problem #%d: %s", problem.ID, problem.Name)
+
+	// Wake grader to start processing immediately
+	s.WakeGrader()
+	return len(ids), nil
+}
+
+// ProblemVerification compares the latest results of the model solutions of the problem with their expectations
+func (s *BaseAPI) ProblemVerification(ctx context.Context, problemID int) ([]*kilonova.SolutionVerification, *StatusError) {
+	exps, err := s.ProblemSolutionExpectations(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+	rez := make([]*kilonova.SolutionVerification, 0, len(exps))
+	for _, exp := range exps {
+		verification, err := s.verifySolution(ctx, exp)
+		if err != nil {
+			return nil, err
+		}
+		rez = append(rez, verification)
+	}
+	return rez, nil
+}
+
+func (s *BaseAPI) verifySolution(ctx context.Context, exp *kilonova.SolutionExpectation) (*kilonova.SolutionVerification, *StatusError) {
+	sub, err := s.RawSubmission(ctx, exp.SubmissionID)
+	if err != nil {
+		return nil, err
+	}
+	verification := &kilonova.SolutionVerification{
+		Expectation:     exp,
+		ExpectationText: exp.String(),
+		Submission:      sub,
+	}
+	if sub.Status != kilonova.StatusFinished {
+		verification.Pending = true
+		return verification, nil
+	}
+
+	subTests, err := s.SubTests(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	subTasks, err := s.SubmissionSubTasks(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	verification.Mismatch = exp.Mismatch(sub, subTests, subTasks)
+	return verification, nil
+}
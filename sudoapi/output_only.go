@@ -0,0 +1,222 @@
+package sudoapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	OutputArchiveMaxSize = config.GenFlag[int]("behavior.submissions.output_archive_max_size", 32*1024*1024, "Maximum size (in bytes) of an archive with outputs, submitted to an output-only problem")
+
+	OutputMaxSize             = config.GenFlag[int]("behavior.submissions.output_max_size", 64*1024*1024, "Maximum uncompressed size (in bytes) of a single output in an output archive")
+	OutputArchiveMaxTotalSize = config.GenFlag[int]("behavior.submissions.output_archive_max_total_size", 256*1024*1024, "Maximum uncompressed size (in bytes) of all the outputs in an output archive")
+
+	// ErrMissingOutput is returned by SubmissionOutput when the archive has no output for the requested test
+	ErrMissingOutput = kilonova.Statusf(404, "Missing output for test")
+	// ErrOutputTooLarge is returned when reading an output from an archive that exceeds OutputMaxSize
+	ErrOutputTooLarge = kilonova.Statusf(400, "Output is too large")
+
+	outputNumberRegex = regexp.MustCompile(`\d+`)
+)
+
+// IsOutputArchive reports if the code submitted to an output-only problem is a zip archive with an output for every test (ie. output01.txt),
+// instead of a single output used for all tests
+func IsOutputArchive(code []byte) bool {
+	return bytes.HasPrefix(code, []byte("PK\x03\x04"))
+}
+
+func outputArchiveName(subID int) string {
+	return strconv.Itoa(subID) + ".zip"
+}
+
+// outputTestID returns the visible ID of the test the archive entry corresponds to, based on the last number in its name
+func outputTestID(name string) (int, bool) {
+	base := path.Base(name)
+	if strings.HasPrefix(base, ".") || strings.Contains(name, "__MACOSX") {
+		return -1, false
+	}
+	matches := outputNumberRegex.FindAllString(strings.TrimSuffix(base, path.Ext(base)), -1)
+	if len(matches) == 0 {
+		return -1, false
+	}
+	id, err := strconv.Atoi(matches[len(matches)-1])
+	if err != nil {
+		return -1, false
+	}
+	return id, true
+}
+
+// outputArchiveFiles maps the entries of an output archive to test visible IDs
+func outputArchiveFiles(ar *zip.Reader) (map[int]*zip.File, *StatusError) {
+	files := make(map[int]*zip.File)
+	for _, file := range ar.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		id, ok := outputTestID(file.Name)
+		if !ok {
+			continue
+		}
+		if other, ok := files[id]; ok {
+			return nil, Statusf(400, "Files %q and %q are both outputs for test %d", other.Name, file.Name, id)
+		}
+		files[id] = file
+	}
+	return files, nil
+}
+
+// outputArchiveManifest validates the archive against the problem tests and returns a summary of it.
+// The summary is saved as the submission code, since the archive itself is kept in the outputs bucket
+func (s *BaseAPI) outputArchiveManifest(ctx context.Context, problem *kilonova.Problem, archive []byte) (string, *StatusError) {
+	ar, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", Statusf(400, "Invalid output archive")
+	}
+	files, err1 := outputArchiveFiles(ar)
+	if err1 != nil {
+		return "", err1
+	}
+	if len(files) == 0 {
+		return "", Statusf(400, "Output archive has no outputs. Files should be named after the test number (ie. output01.txt)")
+	}
+
+	tests, err1 := s.Tests(ctx, problem.ID)
+	if err1 != nil {
+		return "", err1
+	}
+	var testIDs []int
+	for _, test := range tests {
+		testIDs = append(testIDs, test.VisibleID)
+	}
+	slices.Sort(testIDs)
+
+	// The sizes in the archive headers are enforced by archive/zip while decompressing, and entries are also read through a limited reader when graded
+	var totalSize uint64
+	for id, file := range files {
+		if file.UncompressedSize64 > uint64(OutputMaxSize.Value()) {
+			return "", Statusf(400, "Output for test %d exceeds %d bytes", id, OutputMaxSize.Value())
+		}
+		totalSize += file.UncompressedSize64
+	}
+	if totalSize > uint64(OutputArchiveMaxTotalSize.Value()) {
+		return "", Statusf(400, "Outputs in archive exceed %d bytes in total", OutputArchiveMaxTotalSize.Value())
+	}
+
+	var manifest strings.Builder
+	var missing []string
+	fmt.Fprintf(&manifest, "Output archive with %d file(s)\n", len(files))
+	for _, id := range testIDs {
+		file, ok := files[id]
+		if !ok {
+			missing = append(missing, strconv.Itoa(id))
+			continue
+		}
+		fmt.Fprintf(&manifest, "Test %d: %s (%d bytes)\n", id, file.Name, file.UncompressedSize64)
+		delete(files, id)
+	}
+	for id, file := range files {
+		return "", Statusf(400, "File %q is the output for test %d, which doesn't exist", file.Name, id)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(&manifest, "Missing outputs for tests: %s\n", strings.Join(missing, ", "))
+	}
+	return manifest.String(), nil
+}
+
+// SubmissionOutput returns the output uploaded for the given test in an output-only submission.
+// If the submission is not an output archive, kilonova.ErrNotExist is returned.
+// Reading more than OutputMaxSize bytes from the output fails with ErrOutputTooLarge
+func (s *BaseAPI) SubmissionOutput(subID int, testVisibleID int) (io.ReadCloser, error) {
+	f, err := s.outputBucket.ReadSeeker(outputArchiveName(subID))
+	if err != nil {
+		return nil, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, Statusf(500, "Output archive can't be read")
+	}
+	ar, err := zip.NewReader(ra, size)
+	if err != nil {
+		f.Close()
+		return nil, WrapError(err, "Invalid output archive")
+	}
+	files, err1 := outputArchiveFiles(ar)
+	if err1 != nil {
+		f.Close()
+		return nil, err1
+	}
+	file, ok := files[testVisibleID]
+	if !ok {
+		f.Close()
+		return nil, ErrMissingOutput
+	}
+	rc, err := file.Open()
+	if err != nil {
+		f.Close()
+		return nil, WrapError(err, "Couldn't open output file")
+	}
+	return &archiveEntryReader{&sizeLimitedReader{r: rc, n: int64(OutputMaxSize.Value())}, rc, f}, nil
+}
+
+func (s *BaseAPI) saveOutputArchive(subID int, archive []byte) error {
+	return s.outputBucket.WriteFile(outputArchiveName(subID), bytes.NewReader(archive), 0644)
+}
+
+func (s *BaseAPI) deleteOutputArchive(subID int) {
+	if err := s.outputBucket.RemoveFile(outputArchiveName(subID)); err != nil {
+		zap.S().Warn("Couldn't remove output archive: ", err)
+	}
+}
+
+type archiveEntryReader struct {
+	io.Reader
+	entry   io.Closer
+	archive io.Closer
+}
+
+func (r *archiveEntryReader) Close() error {
+	err := r.entry.Close()
+	if err1 := r.archive.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return err
+}
+
+// sizeLimitedReader is like io.LimitedReader, except that it fails with ErrOutputTooLarge instead of truncating the data
+type sizeLimitedReader struct {
+	r io.Reader
+	// n is the number of bytes that may still be read
+	n int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	// Read one byte over the limit, to tell if there's more data
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.n = -1
+		return 0, ErrOutputTooLarge
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,33 @@
+package sudoapi
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSizeLimitedReader(t *testing.T) {
+	for _, tc := range []struct {
+		data  string
+		limit int64
+		fails bool
+	}{
+		{"", 0, false},
+		{"abc", 3, false},
+		{"abc", 10, false},
+		{"abcd", 3, true},
+		{strings.Repeat("a", 100000), 99999, true},
+	} {
+		out, err := io.ReadAll(&sizeLimitedReader{r: strings.NewReader(tc.data), n: tc.limit})
+		if tc.fails {
+			if !errors.Is(err, ErrOutputTooLarge) {
+				t.Errorf("Expected %d bytes with limit %d to fail, got %v", len(tc.data), tc.limit, err)
+			}
+			continue
+		}
+		if err != nil || string(out) != tc.data {
+			t.Errorf("Expected %d bytes with limit %d to be read, got %d (%v)", len(tc.data), tc.limit, len(out), err)
+		}
+	}
+}
@@ -3,12 +3,21 @@ package sudoapi
 import (
 	"context"
 	"errors"
+	"slices"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/db"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/internal/config"
 	"go.uber.org/zap"
 )
 
+// MaxCommunicationProcesses is the maximum number of contestant processes in a communication problem
+const MaxCommunicationProcesses = 8
+
+// MaxTimeMultiplier is the maximum per-language time limit multiplier of a problem
+const MaxTimeMultiplier = 10
+
 // Problem stuff
 
 // When editing Problem, please edit ScoredProblem as well
@@ -33,6 +42,29 @@ func (s *BaseAPI) UpdateProblem(ctx context.Context, id int, args kilonova.Probl
 	if args.ScoringStrategy != kilonova.ScoringTypeNone && args.ScoringStrategy != kilonova.ScoringTypeMaxSub && args.ScoringStrategy != kilonova.ScoringTypeSumSubtasks && args.ScoringStrategy != kilonova.ScoringTypeICPC {
 		return Statusf(400, "Invalid scoring strategy!")
 	}
+	if args.NumProcesses != nil && (*args.NumProcesses < 1 || *args.NumProcesses > MaxCommunicationProcesses) {
+		return Statusf(400, "Number of processes must be between 1 and %d", MaxCommunicationProcesses)
+	}
+	if args.BuiltinChecker != nil && !slices.Contains(kilonova.BuiltinCheckerTypes, *args.BuiltinChecker) {
+		return Statusf(400, "Invalid built-in checker!")
+	}
+	if (args.CheckerAbsEpsilon != nil && *args.CheckerAbsEpsilon < 0) || (args.CheckerRelEpsilon != nil && *args.CheckerRelEpsilon < 0) {
+		return Statusf(400, "Checker epsilon can't be negative")
+	}
+	for lang, limits := range args.LanguageLimits {
+		if _, ok := eval.Langs()[lang]; !ok {
+			return Statusf(400, "Unknown language %q", lang)
+		}
+		if limits == nil {
+			continue
+		}
+		if v := limits.TimeMultiplier; v != nil && (*v <= 0 || *v > MaxTimeMultiplier) {
+			return Statusf(400, "Time multiplier must be positive and at most %d", MaxTimeMultiplier)
+		}
+		if v := limits.MemoryOverhead; v != nil && (*v < 0 || *v > config.Common.TestMaxMemKB) {
+			return Statusf(400, "Memory overhead must be between 0 and %dKB", config.Common.TestMaxMemKB)
+		}
+	}
 
 	if err := s.db.UpdateProblem(ctx, id, args); err != nil {
 		zap.S().Warn(err)
@@ -326,6 +358,20 @@ func (s *BaseAPI) ProblemChecklist(ctx context.Context, pbid int) (*kilonova.Pro
 	if err != nil || chk == nil {
 		return nil, WrapError(err, "Couldn't get problem checklist")
 	}
+
+	verifications, err1 := s.ProblemVerification(ctx, pbid)
+	if err1 != nil {
+		return nil, err1
+	}
+	chk.NumModelSolutions = len(verifications)
+	for _, v := range verifications {
+		switch {
+		case v.Pending:
+			chk.NumModelPending++
+		case v.Mismatch != "":
+			chk.NumModelMismatches++
+		}
+	}
 	return chk, nil
 }
 
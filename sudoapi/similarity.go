@@ -0,0 +1,291 @@
+package sudoapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/KiloProjects/kilonova/plagiarism"
+	"go.uber.org/zap"
+)
+
+var MaxSimilaritySubmitters = config.GenFlag[int]("behavior.similarity.max_problem_submitters", 200, "Maximum number of users, the latest ones to submit, checked by a problem-wide similarity check")
+
+// SimilarityReport is a similarity report, along with its pairs of similar submissions
+type SimilarityReport struct {
+	Report *kilonova.MOSSSubmission   `json:"report"`
+	Pairs  []*kilonova.SimilarityPair `json:"pairs"`
+	Users  map[int]*UserBrief         `json:"users"`
+}
+
+// SimilarityPairDetails holds the code of both submissions of a similar pair
+type SimilarityPairDetails struct {
+	*kilonova.SimilarityPair
+
+	LeftUser  *UserBrief `json:"left_user"`
+	RightUser *UserBrief `json:"right_user"`
+
+	LeftLanguage  string `json:"left_language"`
+	RightLanguage string `json:"right_language"`
+	LeftCode      string `json:"left_code"`
+	RightCode     string `json:"right_code"`
+}
+
+// CheckContestSimilarity runs the similarity detector over the contest submissions of every problem in the contest
+func (s *BaseAPI) CheckContestSimilarity(ctx context.Context, contest *kilonova.Contest) *StatusError {
+	pbs, err := s.Problems(ctx, kilonova.ProblemFilter{ContestID: &contest.ID})
+	if err != nil {
+		return err
+	}
+
+	for _, pb := range pbs {
+		subs, err := s.RawSubmissions(ctx, kilonova.SubmissionFilter{
+			ProblemID: &pb.ID,
+			ContestID: &contest.ID,
+
+			Ordering:  "score",
+			Ascending: false,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.checkSimilarity(ctx, &contest.ID, pb, subs, plagiarism.DefaultOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckProblemSimilarity runs the similarity detector over the submissions of the latest users to submit to the problem
+func (s *BaseAPI) CheckProblemSimilarity(ctx context.Context, problem *kilonova.Problem) *StatusError {
+	userIDs, err := s.latestSubmitters(ctx, problem.ID, MaxSimilaritySubmitters.Value())
+	if err != nil {
+		return err
+	}
+	var subs []*kilonova.Submission
+	for _, userID := range userIDs {
+		userSubs, err := s.RawSubmissions(ctx, kilonova.SubmissionFilter{
+			ProblemID: &problem.ID,
+			UserID:    &userID,
+
+			Ordering:  "score",
+			Ascending: false,
+		})
+		if err != nil {
+			return err
+		}
+		subs = append(subs, userSubs...)
+	}
+
+	// Problems can gather many more submissions than a contest, so the common code threshold scales with them
+	opts := plagiarism.DefaultOptions
+	opts.MaxDocFraction = 0.05
+	return s.checkSimilarity(ctx, nil, problem, subs, opts)
+}
+
+// latestSubmitters returns the IDs of the (at most) n latest users to submit to the problem
+func (s *BaseAPI) latestSubmitters(ctx context.Context, problemID int, n int) ([]int, *StatusError) {
+	const pageSize = 500
+	var userIDs []int
+	seen := make(map[int]bool)
+	for offset := 0; len(userIDs) < n; offset += pageSize {
+		subs, err := s.RawSubmissions(ctx, kilonova.SubmissionFilter{
+			ProblemID: &problemID,
+
+			Limit:  pageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subs {
+			if !seen[sub.UserID] && len(userIDs) < n {
+				seen[sub.UserID] = true
+				userIDs = append(userIDs, sub.UserID)
+			}
+		}
+		if len(subs) < pageSize {
+			break
+		}
+	}
+	return userIDs, nil
+}
+
+// checkSimilarity creates a report for every language family of the submissions.
+// Only the first submission of every user in each family is checked, so subs should be ordered by score
+func (s *BaseAPI) checkSimilarity(ctx context.Context, contestID *int, pb *kilonova.Problem, subs []*kilonova.Submission, opts plagiarism.Options) *StatusError {
+	langs := eval.Langs()
+	langSubs := make(map[string][]*kilonova.Submission)
+	for _, sub := range subs {
+		name := langs[sub.Language].MOSSName
+		langSubs[name] = append(langSubs[name], sub)
+	}
+
+	for family, subs := range langSubs {
+		var lang eval.Language
+		for _, elang := range langs {
+			if elang.MOSSName == family && (lang.InternalName == "" || lang.InternalName < elang.InternalName) {
+				lang = elang
+			}
+		}
+
+		detector := plagiarism.New(opts)
+		var docSubs []*kilonova.Submission
+		users := make(map[int]bool)
+		for _, sub := range subs {
+			if _, ok := users[sub.UserID]; ok {
+				continue
+			}
+			users[sub.UserID] = true
+
+			code, err := s.RawSubmissionCode(ctx, sub.ID)
+			if err != nil {
+				return err
+			}
+			detector.AddDocument(code, family)
+			docSubs = append(docSubs, sub)
+		}
+		zap.S().Debugf("Similarity check: %s - %s - %d", pb.Name, lang.InternalName, len(docSubs))
+
+		var pairs []*kilonova.SimilarityPair
+		for _, pair := range detector.Compare() {
+			left, right := docSubs[pair.Left], docSubs[pair.Right]
+			matches := make([]*kilonova.SimilarityMatch, 0, len(pair.Matches))
+			for _, match := range pair.Matches {
+				matches = append(matches, &kilonova.SimilarityMatch{
+					LeftStart:  match.LeftStart,
+					LeftEnd:    match.LeftEnd,
+					RightStart: match.RightStart,
+					RightEnd:   match.RightEnd,
+					Tokens:     match.Tokens,
+				})
+			}
+			pairs = append(pairs, &kilonova.SimilarityPair{
+				LeftSubmissionID:  left.ID,
+				RightSubmissionID: right.ID,
+				LeftUserID:        left.UserID,
+				RightUserID:       right.UserID,
+
+				LeftSimilarity:  pair.LeftSimilarity,
+				RightSimilarity: pair.RightSimilarity,
+				MatchedTokens:   pair.MatchedTokens,
+
+				Matches: matches,
+			})
+		}
+
+		if _, err := s.db.InsertSimilarityReport(ctx, contestID, pb.ID, lang, len(docSubs), pairs); err != nil {
+			return WrapError(err, "Could not save similarity report")
+		}
+	}
+	return nil
+}
+
+func (s *BaseAPI) MOSSSubmissions(ctx context.Context, contestID int) ([]*kilonova.MOSSSubmission, *StatusError) {
+	subs, err := s.db.MossSubmissions(ctx, contestID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch MOSS submissions")
+	}
+	return subs, nil
+}
+
+// ProblemSimilarityReports returns the reports created over all the submissions of the problem
+func (s *BaseAPI) ProblemSimilarityReports(ctx context.Context, problemID int) ([]*kilonova.MOSSSubmission, *StatusError) {
+	subs, err := s.db.ProblemMossSubmissions(ctx, problemID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch similarity reports")
+	}
+	return subs, nil
+}
+
+// SimilarityReport returns the report with the given ID, along with its pairs.
+// If contestID is nil, the report must be a problem report of the given problem, otherwise it must belong to the contest
+func (s *BaseAPI) SimilarityReport(ctx context.Context, id int, contestID *int, problemID *int) (*SimilarityReport, *StatusError) {
+	report, err := s.db.MossSubmission(ctx, id)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch similarity report")
+	}
+	if report == nil || !similarityReportMatches(report, contestID, problemID) {
+		return nil, WrapError(ErrNotFound, "Similarity report not found")
+	}
+
+	pairs, err := s.db.SimilarityPairs(ctx, report.ID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch similar pairs")
+	}
+	if pairs == nil {
+		pairs = []*kilonova.SimilarityPair{}
+	}
+
+	userIDs := make([]int, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		userIDs = append(userIDs, pair.LeftUserID, pair.RightUserID)
+	}
+	usersMap := make(map[int]*UserBrief)
+	if len(userIDs) > 0 {
+		users, err := s.UsersBrief(ctx, kilonova.UserFilter{IDs: userIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			usersMap[user.ID] = user
+		}
+	}
+
+	return &SimilarityReport{Report: report, Pairs: pairs, Users: usersMap}, nil
+}
+
+// SimilarityPair returns a pair of the report, along with the code of both submissions
+func (s *BaseAPI) SimilarityPair(ctx context.Context, reportID int, pairID int, contestID *int, problemID *int) (*SimilarityPairDetails, *StatusError) {
+	report, err := s.db.MossSubmission(ctx, reportID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch similarity report")
+	}
+	if report == nil || !similarityReportMatches(report, contestID, problemID) {
+		return nil, WrapError(ErrNotFound, "Similarity report not found")
+	}
+	pair, err := s.db.SimilarityPair(ctx, reportID, pairID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch similar pair")
+	}
+	if pair == nil {
+		return nil, WrapError(ErrNotFound, "Similar pair not found")
+	}
+
+	details := &SimilarityPairDetails{SimilarityPair: pair}
+	var err1 *StatusError
+	details.LeftUser, details.LeftLanguage, details.LeftCode, err1 = s.similarityPairSide(ctx, pair.LeftSubmissionID)
+	if err1 != nil {
+		return nil, err1
+	}
+	details.RightUser, details.RightLanguage, details.RightCode, err1 = s.similarityPairSide(ctx, pair.RightSubmissionID)
+	if err1 != nil {
+		return nil, err1
+	}
+	return details, nil
+}
+
+func (s *BaseAPI) similarityPairSide(ctx context.Context, subID int) (*UserBrief, string, string, *StatusError) {
+	sub, err := s.RawSubmission(ctx, subID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	user, err := s.UserBrief(ctx, sub.UserID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, "", "", err
+	}
+	code, err := s.RawSubmissionCode(ctx, sub.ID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return user, sub.Language, string(code), nil
+}
+
+func similarityReportMatches(report *kilonova.MOSSSubmission, contestID *int, problemID *int) bool {
+	if contestID != nil {
+		return report.ContestID != nil && *report.ContestID == *contestID
+	}
+	return report.ContestID == nil && problemID != nil && report.ProblemID == *problemID
+}
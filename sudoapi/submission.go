@@ -344,7 +344,12 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 	if problem == nil {
 		return -1, Statusf(400, "Invalid submission problem")
 	}
-	if len(code) > problem.SourceSize { // Maximum admitted by problem
+	// Output archives are only checked against their own limit, since they are not stored as code
+	archived := lang.InternalName == "outputOnly" && IsOutputArchive(code)
+	if archived && len(code) > OutputArchiveMaxSize.Value() {
+		return -1, Statusf(400, "Output archive exceeds %d bytes", OutputArchiveMaxSize.Value())
+	}
+	if !archived && len(code) > problem.SourceSize { // Maximum admitted by problem
 		return -1, Statusf(400, "Code exceeds %d characters", problem.SourceSize)
 	}
 	if !s.IsProblemVisible(author.Brief(), problem) {
@@ -438,6 +443,15 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 		return -1, Statusf(400, "Language not on whitelist")
 	}
 
+	var archive []byte
+	if archived {
+		manifest, err := s.outputArchiveManifest(ctx, problem, code)
+		if err != nil {
+			return -1, err
+		}
+		archive, code = code, []byte(manifest)
+	}
+
 	// Add submission
 	id, err := s.db.CreateSubmission(ctx, author.ID, problem, lang, string(code), contestID)
 	if err != nil {
@@ -445,6 +459,16 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 		return -1, Statusf(500, "Couldn't create submission")
 	}
 
+	if archive != nil {
+		if err := s.saveOutputArchive(id, archive); err != nil {
+			zap.S().Warn("Couldn't save output archive:", err)
+			if err := s.db.DeleteSubmission(ctx, id); err != nil {
+				zap.S().Warn("Couldn't delete submission:", err)
+			}
+			return -1, Statusf(500, "Couldn't save output archive")
+		}
+	}
+
 	if err := s.db.InitSubmission(ctx, id); err != nil {
 		zap.S().Warn("Couldn't initialize submission:", err)
 		return -1, Statusf(500, "Couldn't initialize submission")
@@ -461,6 +485,10 @@ func (s *BaseAPI) DeleteSubmission(ctx context.Context, subID int) *StatusError
 		zap.S().Warn("Couldn't delete submission:", err)
 		return Statusf(500, "Failed to delete submission")
 	}
+	s.deleteOutputArchive(subID)
+
+	// The submission might have been queued
+	s.WakeGrader()
 	return nil
 }
 
@@ -34,6 +34,12 @@ func (s *BaseAPI) SubTask(ctx context.Context, problemID int, subtaskVID int) (*
 }
 
 func (s *BaseAPI) CreateSubTask(ctx context.Context, subtask *kilonova.SubTask) *StatusError {
+	if subtask.Scoring == "" {
+		subtask.Scoring = kilonova.SubtaskScoringMin
+	}
+	if !subtask.Scoring.Valid() {
+		return Statusf(400, "Invalid subtask scoring")
+	}
 	if err := s.db.CreateSubTask(ctx, subtask); err != nil {
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't create subtask")
@@ -42,6 +48,9 @@ func (s *BaseAPI) CreateSubTask(ctx context.Context, subtask *kilonova.SubTask)
 }
 
 func (s *BaseAPI) UpdateSubTask(ctx context.Context, id int, upd kilonova.SubTaskUpdate) *StatusError {
+	if upd.Scoring != nil && !upd.Scoring.Valid() {
+		return Statusf(400, "Invalid subtask scoring")
+	}
 	if err := s.db.UpdateSubTask(ctx, id, upd); err != nil {
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't update subtask metadata")
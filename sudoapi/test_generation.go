@@ -0,0 +1,300 @@
+package sudoapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// GeneratorScriptName is the attachment with the generator invocations of a problem
+const GeneratorScriptName = "generator_script.txt"
+
+const maxGeneratedTests = 1000
+
+// GeneratorInvocation is a line of the generator script
+type GeneratorInvocation struct {
+	// Line is the line in the script, used in error messages
+	Line      int
+	Args      []string
+	VisibleID int
+}
+
+// ParseGeneratorScript parses a script of generator invocations. Every line has the arguments given to the generator,
+// optionally followed by `> N` to set the visible ID of the test. Otherwise, tests are numbered consecutively, starting from 1.
+// Empty lines and lines starting with # are ignored
+func ParseGeneratorScript(script string) ([]*GeneratorInvocation, error) {
+	var invocations []*GeneratorInvocation
+	usedIDs := make(map[int]int)
+	nextID := 1
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		inv := &GeneratorInvocation{Line: line, Args: fields, VisibleID: nextID}
+		if idx := len(fields) - 2; idx >= 0 && fields[idx] == ">" {
+			id, err := strconv.Atoi(fields[idx+1])
+			if err != nil || id < 0 {
+				return nil, fmt.Errorf("line %d: invalid test ID %q", line, fields[idx+1])
+			}
+			inv.Args, inv.VisibleID = fields[:idx], id
+		}
+		if slices.Contains(inv.Args, ">") {
+			return nil, fmt.Errorf("line %d: the test ID must be at the end of the line", line)
+		}
+		if prev, ok := usedIDs[inv.VisibleID]; ok {
+			return nil, fmt.Errorf("line %d: test %d was already generated on line %d", line, inv.VisibleID, prev)
+		}
+		usedIDs[inv.VisibleID] = line
+		nextID = inv.VisibleID + 1
+
+		invocations = append(invocations, inv)
+		if len(invocations) > maxGeneratedTests {
+			return nil, fmt.Errorf("at most %d tests can be generated", maxGeneratedTests)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(invocations) == 0 {
+		return nil, errors.New("the script has no generator invocations")
+	}
+	return invocations, nil
+}
+
+// TestGeneration is the status of the last test generation of a problem
+type TestGeneration struct {
+	Running    bool       `json:"running"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Count is the number of generated tests, once the generation finished successfully
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// problemLocks is a set of per-problem mutexes, so long operations on the tests of a problem don't block other problems
+type problemLocks struct {
+	mu    sync.Mutex
+	locks map[int]*problemLock
+}
+
+type problemLock struct {
+	mu sync.Mutex
+	// refs is the number of holders and waiters, the lock is forgotten once it reaches 0
+	refs int
+}
+
+func (l *problemLocks) get(problemID int) *problemLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.locks == nil {
+		l.locks = make(map[int]*problemLock)
+	}
+	lock, ok := l.locks[problemID]
+	if !ok {
+		lock = &problemLock{}
+		l.locks[problemID] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+func (l *problemLocks) put(problemID int, lock *problemLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, problemID)
+	}
+}
+
+// lock locks the problem and returns the function that unlocks it
+func (l *problemLocks) lock(problemID int) func() {
+	lock := l.get(problemID)
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		l.put(problemID, lock)
+	}
+}
+
+// tryLock is like lock, but it fails instead of waiting if the problem is already locked
+func (l *problemLocks) tryLock(problemID int) (func(), bool) {
+	lock := l.get(problemID)
+	if !lock.mu.TryLock() {
+		l.put(problemID, lock)
+		return nil, false
+	}
+	return func() {
+		lock.mu.Unlock()
+		l.put(problemID, lock)
+	}, true
+}
+
+// LockProblemTests waits until no other operation writes the tests of the problem (such as archive processing or test generation).
+// The returned function must be called once the tests were written
+func (s *BaseAPI) LockProblemTests(problemID int) func() {
+	return s.testLocks.lock(problemID)
+}
+
+// TestGenerationStatus returns the status of the last test generation of the problem, or nil if tests weren't generated since the server started
+func (s *BaseAPI) TestGenerationStatus(problemID int) *TestGeneration {
+	s.testGensMu.Lock()
+	defer s.testGensMu.Unlock()
+	gen, ok := s.testGens[problemID]
+	if !ok {
+		return nil
+	}
+	genCopy := *gen
+	return &genCopy
+}
+
+func (s *BaseAPI) setTestGenerationStatus(problemID int, gen *TestGeneration) {
+	s.testGensMu.Lock()
+	defer s.testGensMu.Unlock()
+	if s.testGens == nil {
+		s.testGens = make(map[int]*TestGeneration)
+	}
+	s.testGens[problemID] = gen
+}
+
+// StartTestGeneration starts the test generation pipeline of the problem, based on its attachments:
+// the generator is run for every line of the generator script, the validator checks every generated input and the main solution creates the outputs.
+// Tests are saved only if the whole pipeline succeeds. Existing tests with the same visible ID get their data replaced, keeping their score,
+// while new tests are created with score 0. Other tests are not changed.
+//
+// The problem is checked before returning, but the tests are generated in the background. Progress is reported by TestGenerationStatus
+func (s *BaseAPI) StartTestGeneration(ctx context.Context, problem *kilonova.Problem) *StatusError {
+	invocations, err := s.testGenerationInvocations(ctx, problem)
+	if err != nil {
+		return err
+	}
+
+	unlock, ok := s.testLocks.tryLock(problem.ID)
+	if !ok {
+		return Statusf(409, "The tests of the problem are already being changed, please wait")
+	}
+	s.setTestGenerationStatus(problem.ID, &TestGeneration{Running: true, StartedAt: time.Now()})
+
+	// The request context is kept only for its values (such as the user, for the audit log)
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer unlock()
+		status := &TestGeneration{StartedAt: s.TestGenerationStatus(problem.ID).StartedAt}
+		if err := s.generateTests(ctx, problem, invocations); err != nil {
+			zap.S().Debugf("Couldn't generate tests for problem #%d: %v", problem.ID, err)
+			status.Error = err.Text
+		} else {
+			status.Count = len(invocations)
+		}
+		finishedAt := time.Now()
+		status.FinishedAt = &finishedAt
+		s.setTestGenerationStatus(problem.ID, status)
+	}()
+	return nil
+}
+
+// testGenerationInvocations checks that the problem has everything needed to generate tests and parses its generator script
+func (s *BaseAPI) testGenerationInvocations(ctx context.Context, problem *kilonova.Problem) ([]*GeneratorInvocation, *StatusError) {
+	if s.grader == nil {
+		return nil, Statusf(503, "Grader is not running")
+	}
+	settings, err := s.ProblemSettings(ctx, problem.ID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case settings.GeneratorName == "":
+		return nil, Statusf(400, "The problem has no generator (an executable `generator` attachment)")
+	case settings.ValidatorName == "":
+		return nil, Statusf(400, "The problem has no validator (an executable `validator` attachment)")
+	case settings.SolutionName == "":
+		return nil, Statusf(400, "The problem has no main solution (an executable `solution` attachment)")
+	case settings.InteractorName != "" || settings.ManagerName != "":
+		return nil, Statusf(400, "Tests of interactive problems can't be generated")
+	}
+
+	script, err := s.ProblemAttDataByName(ctx, problem.ID, GeneratorScriptName)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get generator script")
+	}
+	invocations, err1 := ParseGeneratorScript(string(script))
+	if err1 != nil {
+		return nil, Statusf(400, "Invalid generator script: %s", err1)
+	}
+	return invocations, nil
+}
+
+// generateTests runs the generation pipeline and saves the tests. The tests of the problem must be locked
+func (s *BaseAPI) generateTests(ctx context.Context, problem *kilonova.Problem, invocations []*GeneratorInvocation) *StatusError {
+	dir, err1 := os.MkdirTemp("", "kn-testgen-*")
+	if err1 != nil {
+		return WrapError(err1, "Couldn't create temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	if err := s.grader.GenerateTests(ctx, problem, invocations, dir); err != nil {
+		return WrapError(err, "Couldn't generate tests")
+	}
+
+	tests, err := s.Tests(ctx, problem.ID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int]*kilonova.Test)
+	for _, test := range tests {
+		existing[test.VisibleID] = test
+	}
+
+	for _, inv := range invocations {
+		test, ok := existing[inv.VisibleID]
+		if !ok {
+			test = &kilonova.Test{ProblemID: problem.ID, VisibleID: inv.VisibleID, Score: decimal.Zero}
+			if err := s.CreateTest(ctx, test); err != nil {
+				return err
+			}
+		}
+		if err := s.saveGeneratedTest(test.ID, path.Join(dir, strconv.Itoa(inv.VisibleID))); err != nil {
+			zap.S().Warn(err)
+			return WrapError(err, "Couldn't save generated test")
+		}
+	}
+
+	s.LogUserAction(ctx, "Generated %d tests for problem #%d", len(invocations), problem.ID)
+	return nil
+}
+
+// saveGeneratedTest saves the test data from the <prefix>.in and <prefix>.out files
+func (s *BaseAPI) saveGeneratedTest(testID int, prefix string) error {
+	in, err := os.Open(prefix + ".in")
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := s.SaveTestInput(testID, in); err != nil {
+		return err
+	}
+
+	out, err := os.Open(prefix + ".out")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return s.SaveTestOutput(testID, out)
+}
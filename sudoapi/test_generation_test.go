@@ -0,0 +1,52 @@
+package sudoapi_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/KiloProjects/kilonova/sudoapi"
+)
+
+type generatorScriptTest struct {
+	Script string
+	IDs    []int
+	Error  bool
+}
+
+var generatorScriptExamples = map[string]generatorScriptTest{
+	"simple":   {Script: "gen 1 10\ngen 2 100\ngen 3 1000", IDs: []int{1, 2, 3}},
+	"comments": {Script: "# small tests\ngen 1\n\n   \n# big tests\ngen 2", IDs: []int{1, 2}},
+	"ids":      {Script: "gen 1 > 5\ngen 2\ngen 3 > 0\ngen 4", IDs: []int{5, 6, 0, 1}},
+	"noArgs":   {Script: "> 3\n", IDs: []int{3}},
+	"empty":    {Script: "# nothing\n", Error: true},
+	"dupe":     {Script: "gen 1 > 2\ngen 2 > 2", Error: true},
+	"dupe2":    {Script: "gen 1\ngen 2 > 1", Error: true},
+	"badID":    {Script: "gen 1 > a", Error: true},
+	"negative": {Script: "gen 1 > -1", Error: true},
+	"middle":   {Script: "gen > 1 2", Error: true},
+}
+
+func TestParseGeneratorScript(t *testing.T) {
+	for k, v := range generatorScriptExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			invocations, err := sudoapi.ParseGeneratorScript(v.Script)
+			if err != nil && !v.Error {
+				t.Fatalf("Error parsing generator script: %#v", err)
+			}
+			if err == nil && v.Error {
+				t.Fatalf("Test should not succeed")
+			}
+			if v.Error {
+				return
+			}
+			ids := make([]int, 0, len(invocations))
+			for _, inv := range invocations {
+				ids = append(ids, inv.VisibleID)
+			}
+			if !slices.Equal(ids, v.IDs) {
+				t.Fatalf("Invalid test IDs, expected %v, got %v", v.IDs, ids)
+			}
+		})
+	}
+}
@@ -12,11 +12,15 @@ type Test struct {
 	Score     decimal.Decimal `json:"score"`
 	ProblemID int             `db:"problem_id" json:"problem_id"`
 	VisibleID int             `db:"visible_id" json:"visible_id"`
+
+	// Example tests are public (ie. shown in the statement) and used for debug runs
+	Example bool `json:"example"`
 }
 
 type TestUpdate struct {
 	Score     *decimal.Decimal `json:"score"`
 	VisibleID *int             `json:"visible_id"`
+	Example   *bool            `json:"example"`
 }
 
 type SubTask struct {
@@ -26,9 +30,12 @@ type SubTask struct {
 	VisibleID int             `json:"visible_id"`
 	Score     decimal.Decimal `json:"score"`
 	Tests     []int           `json:"tests"`
+
+	Scoring SubtaskScoring `json:"scoring"`
 }
 
 type SubTaskUpdate struct {
 	VisibleID *int             `json:"visible_id"`
 	Score     *decimal.Decimal `json:"score"`
+	Scoring   *SubtaskScoring  `json:"scoring"`
 }
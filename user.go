@@ -86,7 +86,8 @@ type UserFilter struct {
 	ContestID *int `json:"contest_id"`
 
 	// For filtering in leaderboards
-	Generated *bool `json:"generated"`
+	Generated         *bool `json:"generated"`
+	ContestCategoryID *int  `json:"contest_category_id"`
 
 	// For session recognition
 	SessionID *string `json:"session_id"`
@@ -25,6 +25,19 @@ export async function startContestRegistration(contestID: number) {
 	}
 }
 
+export async function startVirtualContest(contestID: number) {
+	const res = await postCall(`/contest/${contestID}/startVirtual`, {});
+	if (res.status === "error") {
+		apiToast(res);
+		return;
+	}
+	if (window.location.pathname.startsWith(`/contests/${contestID}`)) {
+		window.location.reload();
+	} else {
+		window.location.assign(`/contests/${contestID}`);
+	}
+}
+
 export async function answerQuestion(q: Question, text: string) {
 	let res = await postCall(`/contest/${q.contest_id}/answerQuestion`, { questionID: q.id, text });
 	apiToast(res);
@@ -89,6 +89,41 @@ declare global {
 
 		visible_id: number;
 		score: number;
+
+		wall_time: number;
+		exit_code: number;
+		exit_signal: number;
+		signal_name?: string;
+		max_rss: number;
+		oom_killed: boolean;
+		csw_voluntary: number;
+		csw_forced: number;
+	};
+
+	type DebugRunTest = {
+		visible_id: number;
+		verdict: string;
+		time: number;
+		memory: number;
+		percentage: number;
+		stderr: string;
+	};
+
+	type DebugRun = {
+		compile_error: boolean;
+		compile_message: string;
+		tests: DebugRunTest[];
+	};
+
+	type CustomInvocation = {
+		compile_error: boolean;
+		compile_message: string;
+		verdict: string;
+		time: number;
+		memory: number;
+		exit_code: number;
+		stdout: string;
+		stderr: string;
 	};
 
 	type SubmissionSubTask = {
@@ -103,6 +138,7 @@ declare global {
 		visible_id: number;
 		score: number;
 		final_percentage?: number;
+		scoring: "min" | "sum" | "product" | "all_or_nothing" | "max" | "dependent";
 
 		subtests: number[];
 	};
@@ -0,0 +1,136 @@
+import { h, Fragment, render } from "preact";
+import { useEffect, useState } from "preact/hooks";
+import getText from "../translation";
+import { apiToast } from "../toast";
+import { BigSpinner } from "./common";
+import { bodyCall } from "../api/client";
+import { testVerdictString } from "./sub_mgr";
+import { KNModal } from "./modal";
+import { getGradient, sizeFormatter } from "../util";
+
+function DebugRunResult({ run }: { run: DebugRun }) {
+	return (
+		<>
+			{run.compile_message.trim().length > 0 && (
+				<>
+					<h2>{run.compile_error ? getText("compileErr") : getText("compilerWarnings")}</h2>
+					<pre class="mb-2">{run.compile_message}</pre>
+				</>
+			)}
+			{run.tests.map((test) => (
+				<details class="mb-2" key={"debug_test" + test.visible_id} open={test.stderr.length > 0}>
+					<summary>
+						<span class="badge-lite font-bold text-black mr-2" style={{ backgroundColor: getGradient(test.percentage, 100) }}>
+							{getText("nthTest", test.visible_id)}
+						</span>
+						{testVerdictString(test.verdict)} ({Math.floor(test.time * 1000)} ms, {sizeFormatter(test.memory * 1024, 1, true)})
+					</summary>
+					{test.stderr.length > 0 ? <pre>{test.stderr}</pre> : <p class="text-muted">{getText("debugRunNoErrors")}</p>}
+				</details>
+			))}
+		</>
+	);
+}
+
+function DebugRunModal({ problemID, language, code }: { problemID: number; language: string; code: string }) {
+	let [open, setOpen] = useState(true);
+	let [run, setRun] = useState<DebugRun | null>(null);
+
+	useEffect(() => {
+		bodyCall<DebugRun>(`/problem/${problemID}/debugRun`, { language, code })
+			.then((res) => {
+				if (res.status === "error") {
+					apiToast(res);
+					setOpen(false);
+					return;
+				}
+				setRun(res.data);
+			})
+			.catch(console.error);
+	}, [problemID, language, code]);
+
+	return (
+		<KNModal open={open} title={getText("debugRun")} closeCallback={() => setOpen(false)}>
+			<p class="text-muted mb-2">{getText("debugRunExplainer")}</p>
+			{run == null ? <BigSpinner /> : <DebugRunResult run={run} />}
+		</KNModal>
+	);
+}
+
+// runModalContainer returns the (emptied) element the debug run and custom invocation modals are rendered in
+function runModalContainer(): HTMLElement {
+	let container = document.getElementById("run_modal");
+	if (container == null) {
+		container = document.createElement("div");
+		container.id = "run_modal";
+		document.getElementById("modals")!.appendChild(container);
+	}
+	render(null, container);
+	return container;
+}
+
+// startDebugRun runs the code with sanitizers on the example tests of the problem and shows the results in a modal
+export function startDebugRun(problemID: number, language: string, code: string) {
+	const container = runModalContainer();
+	render(<DebugRunModal problemID={problemID} language={language} code={code} />, container);
+}
+
+function CustomInvocationModal({ problemID, language, code, input }: { problemID: number; language: string; code: string; input: string }) {
+	let [open, setOpen] = useState(true);
+	let [invocation, setInvocation] = useState<CustomInvocation | null>(null);
+
+	useEffect(() => {
+		bodyCall<CustomInvocation>(`/problem/${problemID}/customInvocation`, { language, code, input })
+			.then((res) => {
+				if (res.status === "error") {
+					apiToast(res);
+					setOpen(false);
+					return;
+				}
+				setInvocation(res.data);
+			})
+			.catch(console.error);
+	}, [problemID, language, code, input]);
+
+	let content = <BigSpinner />;
+	if (invocation != null) {
+		content = (
+			<>
+				{invocation.compile_message.trim().length > 0 && (
+					<>
+						<h2>{invocation.compile_error ? getText("compileErr") : getText("compilerWarnings")}</h2>
+						<pre class="mb-2">{invocation.compile_message}</pre>
+					</>
+				)}
+				{!invocation.compile_error && (
+					<>
+						<p class="mb-2">
+							{invocation.verdict.length > 0 ? testVerdictString(invocation.verdict) : getText("customInvocationFinished")} (
+							{Math.floor(invocation.time * 1000)} ms, {sizeFormatter(invocation.memory * 1024, 1, true)})
+						</p>
+						<h2>{getText("output")}</h2>
+						<pre class="mb-2">{invocation.stdout}</pre>
+						{invocation.stderr.length > 0 && (
+							<>
+								<h2>stderr</h2>
+								<pre>{invocation.stderr}</pre>
+							</>
+						)}
+					</>
+				)}
+			</>
+		);
+	}
+
+	return (
+		<KNModal open={open} title={getText("customInvocation")} closeCallback={() => setOpen(false)}>
+			{content}
+		</KNModal>
+	);
+}
+
+// startCustomInvocation runs the code on the given input and shows the output in a modal
+export function startCustomInvocation(problemID: number, language: string, code: string, input: string) {
+	const container = runModalContainer();
+	render(<CustomInvocationModal problemID={problemID} language={language} code={code} input={input} />, container);
+}
@@ -71,9 +71,17 @@ type LeaderboardResponse = {
 		freeze_time: string | null;
 		last_times: Record<number, number>;
 		attempts: Record<number, number>; // TODO: check if will still be null once finished
+
+		category_id: number | null;
+		school: string;
+		region: string;
+		rank: number;
+		category_rank: number;
+		virtual: boolean;
 	}[];
 
 	advanced_filter: boolean;
+	categories: ContestCategory[];
 
 	freeze_time?: string;
 	type: "classic" | "acm-icpc";
@@ -86,6 +94,25 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 	let [lastUpdated, setLastUpdated] = useState<string | null>(null);
 
 	let [generated, setGenerated] = useState<boolean | null>(null);
+	let [category, setCategory] = useState<number | null>(null);
+	let [cutoffs, setCutoffs] = useState({ gold: 0, silver: 0, bronze: 0 });
+
+	const exportParams = new URLSearchParams({
+		...(generated != null && { generated_acc: generated.toString() }),
+		...(category != null && { category_id: category.toString() }),
+		gold: cutoffs.gold.toString(),
+		silver: cutoffs.silver.toString(),
+		bronze: cutoffs.bronze.toString(),
+	}).toString();
+
+	const categoryNames = useMemo(() => {
+		let names: Record<number, string> = {};
+		for (let cat of leaderboard?.categories ?? []) {
+			names[cat.id] = cat.name;
+		}
+		return names;
+	}, [leaderboard]);
+	const showCategories = category == null && (leaderboard?.categories.length ?? 0) > 0;
 
 	const firstSolves = useMemo(() => {
 		let firstSolves: Record<number, { minTime: number; userID: number }> = {};
@@ -112,6 +139,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 		setLoading(true);
 		const res = await getCall<LeaderboardResponse>(`/contest/${contestID}/leaderboard`, {
 			generated_acc: generated == null ? undefined : generated,
+			category_id: category == null ? undefined : category,
 		});
 		if (res.status === "error") {
 			apiToast(res);
@@ -136,7 +164,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 
 	useEffect(() => {
 		loadLeaderboard().catch(console.error);
-	}, [contestID, generated]);
+	}, [contestID, generated, category]);
 
 	if (loading || leaderboard == null) {
 		return (
@@ -170,6 +198,25 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 					</select>
 				</label>
 			)}
+			{leaderboard.categories.length > 0 && (
+				<label class="block mb-2">
+					<span class="form-label">{getText("contest_category")}:</span>
+					<select
+						class="form-select"
+						value={category == null ? "" : category.toString()}
+						onChange={(e) => {
+							setCategory(e.currentTarget.value == "" ? null : parseInt(e.currentTarget.value));
+						}}
+					>
+						<option value="">{getText("all_categories")}</option>
+						{leaderboard.categories.map((cat) => (
+							<option value={cat.id.toString()} key={cat.id}>
+								{cat.name}
+							</option>
+						))}
+					</select>
+				</label>
+			)}
 			<div class="mb-2">
 				<p>
 					{getText("last_updated_at")}: {lastUpdated ? dayjs(lastUpdated).format("DD/MM/YYYY HH:mm") : "-"}
@@ -189,6 +236,11 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 						<th class="kn-table-cell w-1/5" style={{ wordBreak: "break-all" }} scope="col">
 							{getText("name")}
 						</th>
+						{showCategories && (
+							<th class="kn-table-cell w-1/12" style={{ wordBreak: "break-all" }} scope="col">
+								{getText("contest_category")}
+							</th>
+						)}
 						{leaderboard.type == "acm-icpc" && (
 							<>
 								<th class="kn-table-cell w-1/12" style={{ wordBreak: "break-all" }} scope="col">
@@ -212,14 +264,23 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 					</tr>
 				</thead>
 				<tbody>
-					{leaderboard.entries.map((entry, idx) => (
+					{leaderboard.entries.map((entry) => (
 						<tr class="kn-table-row" key={entry.user.id}>
-							<td class="kn-table-cell">{idx + 1}.</td>
+							<td class="kn-table-cell">{entry.rank}.</td>
 							<td class="kn-table-cell">
 								<a href={`/profile/${entry.user.name}`}>
 									{entry.user.display_name.length > 0 ? `${entry.user.display_name} (${entry.user.name})` : entry.user.name}
 								</a>
+								{entry.virtual && <span class="badge-lite text-sm ml-1">{getText("virtual_participant")}</span>}
+								{(entry.school.length > 0 || entry.region.length > 0) && (
+									<span class="block text-sm">{[entry.school, entry.region].filter((val) => val.length > 0).join(", ")}</span>
+								)}
 							</td>
+							{showCategories && (
+								<td class="kn-table-cell">
+									{entry.category_id != null ? `${categoryNames[entry.category_id] ?? "?"} (${entry.category_rank}.)` : "-"}
+								</td>
+							)}
 							{leaderboard?.type == "acm-icpc" && (
 								<>
 									<td class="kn-table-cell">{entry.num_solved}</td>
@@ -272,14 +333,50 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 					{leaderboard.entries.length == 0 && (
 						<tr class="kn-table-row">
 							{/* TODO: Update here if header changes */}
-							<td class="kn-table-cell" colSpan={2 + (leaderboard.type === "acm-icpc" ? 2 : 1) + problems.length}>
+							<td class="kn-table-cell" colSpan={2 + (showCategories ? 1 : 0) + (leaderboard.type === "acm-icpc" ? 2 : 1) + problems.length}>
 								<h1>{getText("no_users")}</h1>
 							</td>
 						</tr>
 					)}
 				</tbody>
 			</table>
-			<a href={`/assets/contest/${contestID}/leaderboard.csv${generated != null ? "?generated_acc=" + generated : ""}`}>Download CSV</a>
+			<a
+				href={`/assets/contest/${contestID}/leaderboard.csv?${new URLSearchParams({
+					...(generated != null && { generated_acc: generated.toString() }),
+					...(category != null && { category_id: category.toString() }),
+				}).toString()}`}
+			>
+				Download CSV
+			</a>
+			<details class="mt-2">
+				<summary>{getText("export_results")}</summary>
+				<p class="text-sm">{getText("medal_cutoffs_explainer")}</p>
+				<div class="flex flex-wrap gap-2 my-2">
+					{(["gold", "silver", "bronze"] as const).map((medal) => (
+						<label class="block" key={medal}>
+							<span class="form-label">{getText(`medal.${medal}`)}: </span>
+							<input
+								type="number"
+								class="form-input"
+								min={0}
+								value={cutoffs[medal]}
+								onChange={(e) => setCutoffs({ ...cutoffs, [medal]: parseInt(e.currentTarget.value) || 0 })}
+							/>
+						</label>
+					))}
+				</div>
+				<a class="mr-2" href={`/assets/contest/${contestID}/results.csv?${exportParams}`}>
+					{getText("download_results")}
+				</a>
+				{leaderboard.type == "acm-icpc" && (
+					<>
+						<a class="mr-2" href={`/assets/contest/${contestID}/scoreboard.json?${exportParams}`}>
+							CLICS scoreboard.json
+						</a>
+						<a href={`/assets/contest/${contestID}/awards.json?${exportParams}`}>CLICS awards.json</a>
+					</>
+				)}
+			</details>
 		</>
 	);
 }
@@ -624,6 +721,19 @@ type ContestRegistration = {
 	user_id: number;
 	individual_start?: string;
 	individual_end?: string;
+
+	category_id: number | null;
+	school: string;
+	region: string;
+
+	virtual: boolean;
+};
+
+type ContestCategory = {
+	id: number;
+	created_at: string;
+	contest_id: number;
+	name: string;
 };
 
 type ContestRegRez = {
@@ -643,6 +753,28 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 	let [numPages, setNumPages] = useState<number>(1);
 	let [cnt, setCnt] = useState<number>(-1);
 	let [name, setName] = useState<string>("");
+	let [categories, setCategories] = useState<ContestCategory[]>([]);
+
+	async function loadCategories() {
+		let res = await getCall<ContestCategory[]>(`/contest/${contestID}/categories`, {});
+		if (res.status !== "success") {
+			apiToast(res);
+			return;
+		}
+		setCategories(res.data);
+	}
+
+	async function updateRegistration(user: UserBrief, upd: { category_id?: number; remove_category?: boolean; school?: string; region?: string }) {
+		let res = await postCall(`/contest/${contestID}/updateRegistration`, { name: user.name, ...upd });
+		apiToast(res);
+		if (res.status === "success") {
+			await poll();
+		}
+	}
+
+	useEffect(() => {
+		loadCategories().catch(console.error);
+	}, [contestID]);
 
 	async function poll() {
 		let res = await getCall(`/contest/${contestID}/registrations`, { offset: 50 * (page - 1), limit: 50, name_fuzzy: name.length > 0 ? name : undefined });
@@ -704,6 +836,17 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 									{getText("started_at")}
 								</th>
 							)}
+							{categories.length > 0 && (
+								<th class="kn-table-cell" scope="col">
+									{getText("contest_category")}
+								</th>
+							)}
+							<th class="kn-table-cell" scope="col">
+								{getText("school")}
+							</th>
+							<th class="kn-table-cell" scope="col">
+								{getText("region")}
+							</th>
 							<th class="kn-table-cell" scope="col">
 								{getText("action")}
 							</th>
@@ -722,6 +865,7 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 										/>{" "}
 										<span class="align-middle">{user.user.name}</span>
 									</a>
+									{user.registration.virtual && <span class="badge-lite text-sm ml-1">{getText("virtual_participant")}</span>}
 								</td>
 								{usacoMode && (
 									<td class="kn-table-cell">
@@ -732,6 +876,43 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 										)}
 									</td>
 								)}
+								{categories.length > 0 && (
+									<td class="kn-table-cell">
+										<select
+											class="form-select"
+											value={user.registration.category_id == null ? "" : user.registration.category_id.toString()}
+											onChange={(e) =>
+												updateRegistration(
+													user.user,
+													e.currentTarget.value == "" ? { remove_category: true } : { category_id: parseInt(e.currentTarget.value) }
+												)
+											}
+										>
+											<option value="">{getText("no_category")}</option>
+											{categories.map((cat) => (
+												<option value={cat.id.toString()} key={cat.id}>
+													{cat.name}
+												</option>
+											))}
+										</select>
+									</td>
+								)}
+								<td class="kn-table-cell">
+									<input
+										class="form-input"
+										type="text"
+										value={user.registration.school}
+										onChange={(e) => updateRegistration(user.user, { school: e.currentTarget.value })}
+									/>
+								</td>
+								<td class="kn-table-cell">
+									<input
+										class="form-input"
+										type="text"
+										value={user.registration.region}
+										onChange={(e) => updateRegistration(user.user, { region: e.currentTarget.value })}
+									/>
+								</td>
 								<td class="kn-table-cell">
 									<button
 										class="btn btn-red"
@@ -793,6 +974,115 @@ function CommunicationAnnouncerDOM({ contestid, contesteditor }: { contestid: st
 	return <CommunicationAnnouncer contestID={contestID} contestEditor={contesteditor == "true"} />;
 }
 
+type ResolverState = {
+	leaderboard: LeaderboardResponse;
+	step: number;
+	last_step: { user_id: number; problem_id: number; solved: boolean; old_rank: number; new_rank: number } | null;
+	pending: Record<number, number[]>;
+	done: boolean;
+};
+
+function ContestResolver({ contestid }: { contestid: string }) {
+	const contestID = parseInt(contestid);
+	if (isNaN(contestID)) {
+		throw new Error("Invalid contest ID");
+	}
+	let [step, setStep] = useState(0);
+	let [state, setState] = useState<ResolverState | null>(null);
+
+	async function load() {
+		const res = await getCall<ResolverState>(`/contest/${contestID}/resolver`, { step });
+		if (res.status === "error") {
+			apiToast(res);
+			return;
+		}
+		setState(res.data);
+	}
+
+	useEffect(() => {
+		load().catch(console.error);
+	}, [contestID, step]);
+
+	if (state == null) {
+		return <BigSpinner />;
+	}
+	const ld = state.leaderboard;
+
+	return (
+		<>
+			<div class="flex flex-wrap gap-2 mb-2">
+				<button class="btn btn-blue" disabled={state.done} onClick={() => setStep(state!.step + 1)}>
+					{getText("resolver_next")}
+				</button>
+				<button class="btn" disabled={state.step == 0} onClick={() => setStep(Math.max(state!.step - 1, 0))}>
+					{getText("resolver_previous")}
+				</button>
+				<button class="btn" onClick={() => setStep(0)}>
+					{getText("resolver_reset")}
+				</button>
+			</div>
+			<p class="mb-2">
+				{state.done ? getText("resolver_done") : sprintf(getText("resolver_pending"), Object.keys(state.pending).length)}
+			</p>
+			<table class="kn-table table-fixed">
+				<thead>
+					<tr>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("position")}
+						</th>
+						<th class="kn-table-cell w-1/5" scope="col">
+							{getText("name")}
+						</th>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("icpc_num_solved")}
+						</th>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("penalty")}
+						</th>
+						{ld.problem_ordering.map((pb) => (
+							<th class="kn-table-cell" scope="col" key={pb}>
+								{ld.problem_names[pb]}
+							</th>
+						))}
+					</tr>
+				</thead>
+				<tbody>
+					{ld.entries.map((entry) => (
+						<tr
+							class={"kn-table-row" + (state!.last_step?.user_id == entry.user.id ? " font-bold" : "")}
+							key={entry.user.id}
+						>
+							<td class="kn-table-cell">{entry.rank}.</td>
+							<td class="kn-table-cell">{entry.user.display_name.length > 0 ? entry.user.display_name : entry.user.name}</td>
+							<td class="kn-table-cell">{entry.num_solved}</td>
+							<td class="kn-table-cell">{entry.penalty}</td>
+							{ld.problem_ordering.map((pb) =>
+								state!.pending[entry.user.id]?.includes(pb) ? (
+									<td class="kn-table-cell" style={{ backgroundColor: "#facc15", color: "black" }} key={pb}>
+										? {entry.attempts[pb] > 0 && entry.attempts[pb]}
+									</td>
+								) : entry.scores[pb] >= 0 ? (
+									<td
+										class="kn-table-cell"
+										style={{ color: entry.scores[pb] >= 100 ? "black" : undefined, backgroundColor: getGradient(entry.scores[pb] >= 100 ? 1 : 0, 1) }}
+										key={pb}
+									>
+										{entry.scores[pb] >= 100 ? "+" : "-"} {entry.attempts[pb] > 0 && entry.attempts[pb]}
+									</td>
+								) : (
+									<td class="kn-table-cell" key={pb}>
+										-
+									</td>
+								)
+							)}
+						</tr>
+					))}
+				</tbody>
+			</table>
+		</>
+	);
+}
+
 function ContestLeaderboardDOM({ contestid, editor }: { contestid: string; editor: string }) {
 	const contestID = parseInt(contestid);
 	if (isNaN(contestID)) {
@@ -808,3 +1098,4 @@ register(ContestCountdown, "kn-contest-countdown", ["target_time", "type"]);
 register(CommunicationAnnouncerDOM, "kn-comm-announcer", ["contestid", "contesteditor"]);
 register(ContestLeaderboardDOM, "kn-leaderboard", ["contestid", "editor"]);
 register(ContestRegistrations, "kn-contest-registrations", ["contestid", "usacomode"]);
+register(ContestResolver, "kn-contest-resolver", ["contestid"]);
@@ -4,6 +4,7 @@ export { render as preactRender } from "preact";
 
 export * from "./common";
 export * from "./sub_mgr";
+export * from "./code_runs";
 export * from "./users";
 export * from "./subs_view";
 export * from "./sublist";
@@ -15,3 +16,4 @@ export * from "./problem_search";
 export * from "./tags";
 export * from "./modal";
 export * from "./glossary";
+export * from "./similarity";
@@ -0,0 +1,237 @@
+import { h, Fragment } from "preact";
+import { useEffect, useState } from "preact/hooks";
+import register from "preact-custom-element";
+import getText from "../translation";
+import { apiToast } from "../toast";
+import { BigSpinner } from "./common";
+import { getCall } from "../api/client";
+
+type SimilarityMatch = {
+	left_start: number;
+	left_end: number;
+	right_start: number;
+	right_end: number;
+	tokens: number;
+};
+
+type SimilarityPair = {
+	id: number;
+	report_id: number;
+	left_sub_id: number;
+	right_sub_id: number;
+	left_user_id: number;
+	right_user_id: number;
+	left_similarity: number;
+	right_similarity: number;
+	matched_tokens: number;
+	matches: SimilarityMatch[];
+};
+
+type SimilarityReport = {
+	report: {
+		id: number;
+		problem_id: number;
+		language: string;
+		subcount: number;
+	};
+	pairs: SimilarityPair[];
+	users: Record<number, UserBrief>;
+};
+
+type SimilarityPairDetails = SimilarityPair & {
+	left_user?: UserBrief;
+	right_user?: UserBrief;
+	left_language: string;
+	right_language: string;
+	left_code: string;
+	right_code: string;
+};
+
+const matchColors = ["#f8717166", "#60a5fa66", "#4ade8066", "#facc1566", "#c084fc66", "#fb923c66", "#2dd4bf66", "#f472b666"];
+
+function formatPercentage(val: number): string {
+	return `${Math.round(val * 100)}%`;
+}
+
+function UserLink({ user, id }: { user?: UserBrief; id: number }) {
+	if (typeof user === "undefined") {
+		return <span>#{id}</span>;
+	}
+	return <a href={`/profile/${user.name}`}>{user.name}</a>;
+}
+
+function MatchedCode({ code, matches, side }: { code: string; matches: SimilarityMatch[]; side: "left" | "right" }) {
+	const lines = code.split("\n");
+	return (
+		<pre class="overflow-auto text-sm" style={{ maxHeight: "75vh" }}>
+			{lines.map((line, idx) => {
+				const lineNum = idx + 1;
+				const matchIdx = matches.findIndex((m) =>
+					side === "left" ? m.left_start <= lineNum && lineNum <= m.left_end : m.right_start <= lineNum && lineNum <= m.right_end
+				);
+				const isStart = matchIdx >= 0 && (side === "left" ? matches[matchIdx].left_start : matches[matchIdx].right_start) == lineNum;
+				return (
+					<div
+						key={idx}
+						id={isStart ? `${side}_match_${matchIdx}` : undefined}
+						style={matchIdx >= 0 ? { backgroundColor: matchColors[matchIdx % matchColors.length] } : {}}
+					>
+						<span class="inline-block w-10 pr-2 text-right text-gray-500 select-none">{lineNum}</span>
+						{line}
+					</div>
+				);
+			})}
+		</pre>
+	);
+}
+
+function SimilarityPairView({ apiprefix, reportID, pairID }: { apiprefix: string; reportID: number; pairID: number }) {
+	let [pair, setPair] = useState<SimilarityPairDetails | null>(null);
+
+	async function load() {
+		setPair(null);
+		const res = await getCall<SimilarityPairDetails>(`${apiprefix}/similarityPair`, { report_id: reportID, pair_id: pairID });
+		if (res.status === "error") {
+			apiToast(res);
+			return;
+		}
+		setPair(res.data);
+	}
+
+	useEffect(() => {
+		load().catch(console.error);
+	}, [apiprefix, reportID, pairID]);
+
+	if (pair == null) {
+		return <BigSpinner />;
+	}
+
+	function scrollTo(idx: number) {
+		document.getElementById(`left_match_${idx}`)?.scrollIntoView({ block: "nearest" });
+		document.getElementById(`right_match_${idx}`)?.scrollIntoView({ block: "nearest" });
+	}
+
+	return (
+		<>
+			<table class="kn-table mb-2">
+				<thead>
+					<tr>
+						<th class="kn-table-cell" scope="col">
+							<UserLink user={pair.left_user} id={pair.left_user_id} /> (<a href={`/submissions/${pair.left_sub_id}`}>#{pair.left_sub_id}</a>)
+						</th>
+						<th class="kn-table-cell" scope="col">
+							<UserLink user={pair.right_user} id={pair.right_user_id} /> (
+							<a href={`/submissions/${pair.right_sub_id}`}>#{pair.right_sub_id}</a>)
+						</th>
+						<th class="kn-table-cell" scope="col">
+							{getText("similarity_matched_tokens")}
+						</th>
+					</tr>
+				</thead>
+				<tbody>
+					{pair.matches.map((match, idx) => (
+						<tr class="kn-table-row cursor-pointer" key={idx} onClick={() => scrollTo(idx)}>
+							<td class="kn-table-cell" style={{ backgroundColor: matchColors[idx % matchColors.length] }}>
+								{match.left_start}-{match.left_end}
+							</td>
+							<td class="kn-table-cell" style={{ backgroundColor: matchColors[idx % matchColors.length] }}>
+								{match.right_start}-{match.right_end}
+							</td>
+							<td class="kn-table-cell">{match.tokens}</td>
+						</tr>
+					))}
+				</tbody>
+			</table>
+			<div class="grid grid-cols-1 lg:grid-cols-2 gap-2">
+				<div>
+					<h3>
+						<UserLink user={pair.left_user} id={pair.left_user_id} /> - {pair.left_language} ({formatPercentage(pair.left_similarity)})
+					</h3>
+					<MatchedCode code={pair.left_code} matches={pair.matches} side="left" />
+				</div>
+				<div>
+					<h3>
+						<UserLink user={pair.right_user} id={pair.right_user_id} /> - {pair.right_language} ({formatPercentage(pair.right_similarity)})
+					</h3>
+					<MatchedCode code={pair.right_code} matches={pair.matches} side="right" />
+				</div>
+			</div>
+		</>
+	);
+}
+
+function SimilarityReportView({ apiprefix, reportid }: { apiprefix: string; reportid: string }) {
+	const reportID = parseInt(reportid);
+	if (isNaN(reportID)) {
+		throw new Error("Invalid report ID");
+	}
+	let [report, setReport] = useState<SimilarityReport | null>(null);
+	let [pairID, setPairID] = useState<number | null>(null);
+
+	async function load() {
+		const res = await getCall<SimilarityReport>(`${apiprefix}/similarityReport`, { id: reportID });
+		if (res.status === "error") {
+			apiToast(res);
+			return;
+		}
+		setReport(res.data);
+	}
+
+	useEffect(() => {
+		load().catch(console.error);
+	}, [apiprefix, reportID]);
+
+	if (report == null) {
+		return <BigSpinner />;
+	}
+	if (report.pairs.length == 0) {
+		return <p>{getText("similarity_no_pairs")}</p>;
+	}
+
+	return (
+		<>
+			<div class="overflow-auto mb-2" style={{ maxHeight: "40vh" }}>
+				<table class="kn-table">
+					<thead>
+						<tr>
+							<th class="kn-table-cell" scope="col">
+								{getText("id")}
+							</th>
+							<th class="kn-table-cell" scope="col" colSpan={2}>
+								{getText("users")}
+							</th>
+							<th class="kn-table-cell" scope="col">
+								{getText("similarity_matched_tokens")}
+							</th>
+						</tr>
+					</thead>
+					<tbody>
+						{report.pairs.map((pair) => (
+							<tr
+								class={"kn-table-row cursor-pointer" + (pair.id == pairID ? " font-bold" : "")}
+								key={pair.id}
+								onClick={() => setPairID(pair.id)}
+							>
+								<td class="kn-table-cell">{pair.id}</td>
+								<td class="kn-table-cell">
+									<UserLink user={report!.users[pair.left_user_id]} id={pair.left_user_id} /> ({formatPercentage(pair.left_similarity)})
+								</td>
+								<td class="kn-table-cell">
+									<UserLink user={report!.users[pair.right_user_id]} id={pair.right_user_id} /> ({formatPercentage(pair.right_similarity)})
+								</td>
+								<td class="kn-table-cell">{pair.matched_tokens}</td>
+							</tr>
+						))}
+					</tbody>
+				</table>
+			</div>
+			{pairID == null ? (
+				<p>{getText("similarity_select_pair")}</p>
+			) : (
+				<SimilarityPairView apiprefix={apiprefix} reportID={reportID} pairID={pairID} />
+			)}
+		</>
+	);
+}
+
+register(SimilarityReportView, "kn-similarity-report", ["apiprefix", "reportid"]);
@@ -227,7 +227,7 @@ function SubCode({ sub, codeHTML, isPaste }: { sub: FullSubmission; codeHTML: st
 	);
 }
 
-function testVerdictString(verdict: string): string | h.JSX.Element {
+export function testVerdictString(verdict: string): string | h.JSX.Element {
 	let txt = verdict
 		.replace(/translate:([a-z_]+)/g, (substr, p1) => {
 			return maybeGetText("test_verdict." + p1);
@@ -297,6 +297,7 @@ export function TestTable({
 						</>
 					)}
 					{problem_editor && <th scope="col">{getText("output")}</th>}
+					{problem_editor && <th scope="col">{getText("resourceUsage")}</th>}
 				</tr>
 			</thead>
 			<tbody>
@@ -356,6 +357,7 @@ export function TestTable({
 										<a href={"/assets/subtest/" + subtest.id}>{getText("output")}</a>
 									</td>
 								)}
+								{problem_editor && <td>{subtest.done && !subtest.skipped && <ResourceUsage subtest={subtest} />}</td>}
 							</tr>
 						);
 					})}
@@ -364,6 +366,41 @@ export function TestTable({
 	);
 }
 
+// ResourceUsage shows the detailed stats of a test run, to help problem setters tell apart wall/CPU time limits and memory limits
+function ResourceUsage({ subtest }: { subtest: SubTest }) {
+	return (
+		<details>
+			<summary>{getText("details")}</summary>
+			<ul class="text-left text-sm">
+				<li>
+					{getText("cpuTime")}: {Math.floor(subtest.time * 1000)} ms
+				</li>
+				<li>
+					{getText("wallTime")}: {Math.floor(subtest.wall_time * 1000)} ms
+				</li>
+				<li>
+					{getText("exitCode")}: {subtest.exit_code}
+				</li>
+				{subtest.exit_signal > 0 && (
+					<li>
+						{getText("exitSignal")}: {subtest.exit_signal} ({subtest.signal_name})
+					</li>
+				)}
+				<li>
+					{getText("cgroupMemory")}: {sizeFormatter(subtest.memory * 1024, 1, true)}
+				</li>
+				<li>
+					{getText("maxRSS")}: {sizeFormatter(subtest.max_rss * 1024, 1, true)}
+				</li>
+				{subtest.oom_killed && <li>{getText("oomKilled")}</li>}
+				<li>
+					{getText("contextSwitches")}: {subtest.csw_voluntary} / {subtest.csw_forced}
+				</li>
+			</ul>
+		</details>
+	);
+}
+
 export function SubTask({
 	subtests,
 	subtask,
@@ -8,6 +8,8 @@ export const languages = {
 	golang: "text/x-go",
 	haskell: "text/x-haskell",
 	java: "text/x-java",
+	kotlin: "text/x-kotlin",
+	rust: "text/x-rustsrc",
 	python3: "text/x-python",
 	pascal: "text/x-pascal",
 	outputOnly: "text/plain",
@@ -23,6 +25,8 @@ export const prettyLanguages = {
 	golang: "Go",
 	haskell: "Haskell",
 	java: "Java",
+	kotlin: "Kotlin",
+	rust: "Rust",
 	python3: "Python 3",
 	pascal: "Pascal",
 	outputOnly: "Output Only",
@@ -60,6 +64,16 @@ export const cm_modes: { [name: string]: CMMode } = {
 		prettyName: "Java",
 		extensions: ["java"],
 	},
+	kotlin: {
+		mimeType: "text/x-kotlin",
+		prettyName: "Kotlin",
+		extensions: ["kt"],
+	},
+	rust: {
+		mimeType: "text/x-rustsrc",
+		prettyName: "Rust",
+		extensions: ["rs"],
+	},
 	python: {
 		mimeType: "text/x-python",
 		prettyName: "Python",
@@ -507,15 +507,23 @@ func (rt *Web) debugPage() http.HandlerFunc {
 			datastore.BucketTypeTests, datastore.BucketTypeSubtests,
 			datastore.BucketTypeAvatars, datastore.BucketTypeAttachments,
 			datastore.BucketTypeCheckers, datastore.BucketTypeCompiles,
+			datastore.BucketTypeOutputs, datastore.BucketTypeCompileCache,
 		} {
 			stats = append(stats, datastore.GetBucket(bucket).Statistics())
 		}
 
+		queue, err := rt.base.SubmissionQueue(r.Context())
+		if err != nil {
+			zap.S().Warn(err)
+		}
+
 		rt.runTempl(w, r, templ, &struct {
 			Metrics []*Metric
 
 			BucketStats []*datastore.BucketStats
-		}{finalMetrics, stats})
+
+			Queue []*kilonova.QueuedSubmission
+		}{finalMetrics, stats, queue})
 	}
 }
 
@@ -657,7 +665,7 @@ func (rt *Web) problem() http.HandlerFunc {
 			atts = newAtts
 		}
 
-		langs := eval.Langs
+		langs := eval.Langs()
 		if evalSettings, err := rt.base.ProblemSettings(r.Context(), util.Problem(r).ID); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				zap.S().Warn("Error getting problem settings:", err, util.Problem(r).ID)
@@ -720,6 +728,8 @@ func (rt *Web) problem() http.HandlerFunc {
 			Languages: langs,
 			Variants:  variants,
 
+			LanguageLimits: languageLimits(util.Problem(r), langs),
+
 			SelectedVariant: &kilonova.StatementVariant{
 				Language: foundLang,
 				Format:   foundFmt,
@@ -743,7 +753,7 @@ func (rt *Web) problemSubmissions() http.HandlerFunc {
 func (rt *Web) problemSubmit() http.HandlerFunc {
 	templ := rt.parse(nil, "problem/pb_submit.html", "problem/topbar.html", "modals/contest_sidebar.html", "modals/pb_submit_form.html")
 	return func(w http.ResponseWriter, r *http.Request) {
-		langs := eval.Langs
+		langs := eval.Langs()
 		if evalSettings, err := rt.base.ProblemSettings(r.Context(), util.Problem(r).ID); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				zap.S().Warn("Error getting problem settings:", err, util.Problem(r).ID)
@@ -1133,6 +1143,12 @@ func (rt *Web) contestEdit() http.HandlerFunc {
 			invitations = []*kilonova.ContestInvitation{}
 		}
 
+		categories, err := rt.base.ContestCategories(r.Context(), util.Contest(r).ID)
+		if err != nil {
+			zap.S().Warn(err)
+			categories = []*kilonova.ContestCategory{}
+		}
+
 		mossSubs, err := rt.base.MOSSSubmissions(r.Context(), util.Contest(r).ID)
 		if err != nil {
 			zap.S().Warn(err)
@@ -1145,6 +1161,7 @@ func (rt *Web) contestEdit() http.HandlerFunc {
 			Contest: util.Contest(r),
 
 			ContestInvitations: invitations,
+			ContestCategories:  categories,
 			MOSSResults:        mossSubs,
 		})
 	}
@@ -1214,6 +1231,28 @@ func (rt *Web) contestRegistrations() http.HandlerFunc {
 	}
 }
 
+func (rt *Web) contestSimilarityReport() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/similarity.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+		if err != nil {
+			rt.statusPage(w, r, 400, "Invalid report ID")
+			return
+		}
+		report, err1 := rt.base.SimilarityReport(r.Context(), reportID, &util.Contest(r).ID, nil)
+		if err1 != nil {
+			rt.statusPage(w, r, err1.Code, err1.Error())
+			return
+		}
+		rt.runTempl(w, r, templ, &ContestParams{
+			Topbar: rt.problemTopbar(r, "contest_edit", -1),
+
+			Contest:          util.Contest(r),
+			SimilarityReport: report.Report,
+		})
+	}
+}
+
 func (rt *Web) contestLeaderboard() http.HandlerFunc {
 	templ := rt.parse(nil, "contest/leaderboard.html", "problem/topbar.html")
 	return func(w http.ResponseWriter, r *http.Request) {
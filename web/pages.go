@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"io/fs"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	tparse "text/template/parse"
 
@@ -118,7 +120,10 @@ type ContestParams struct {
 	Contest *kilonova.Contest
 
 	ContestInvitations []*kilonova.ContestInvitation
+	ContestCategories  []*kilonova.ContestCategory
 	MOSSResults        []*kilonova.MOSSSubmission
+
+	SimilarityReport *kilonova.MOSSSubmission
 }
 
 type ContestInviteParams struct {
@@ -143,10 +148,36 @@ type ProblemParams struct {
 	Statement template.HTML
 	Languages map[string]eval.Language
 	Variants  []*kilonova.StatementVariant
+	// LanguageLimits are the limits of the languages which don't use the problem limits as they are
+	LanguageLimits []*LanguageLimit
 
 	SelectedVariant *kilonova.StatementVariant
 }
 
+// LanguageLimit is the effective time (in seconds) and memory (in kilobytes) limit of a language for a problem
+type LanguageLimit struct {
+	Name        string
+	TimeLimit   float64
+	MemoryLimit int
+}
+
+// languageLimits returns the effective limits of the enabled languages whose limits differ from the problem limits, sorted by name
+func languageLimits(problem *kilonova.Problem, langs map[string]eval.Language) []*LanguageLimit {
+	var limits []*LanguageLimit
+	for name, lang := range langs {
+		if lang.Disabled {
+			continue
+		}
+		timeLimit, memoryLimit := lang.Limits(problem.TimeLimit, problem.MemoryLimit, problem.LanguageLimits[name])
+		if timeLimit == problem.TimeLimit && memoryLimit == problem.MemoryLimit {
+			continue
+		}
+		limits = append(limits, &LanguageLimit{Name: lang.PrintableName, TimeLimit: timeLimit, MemoryLimit: memoryLimit})
+	}
+	slices.SortFunc(limits, func(a, b *LanguageLimit) int { return cmp.Compare(a.Name, b.Name) })
+	return limits
+}
+
 type ProblemTopbarParams struct {
 	Topbar *ProblemTopbar
 
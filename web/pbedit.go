@@ -34,7 +34,11 @@ type ProblemEditParams struct {
 	Problem *kilonova.Problem
 	Topbar  *ProblemTopbar
 
-	Checklist *kilonova.ProblemChecklist
+	Checklist    *kilonova.ProblemChecklist
+	Verification []*kilonova.SolutionVerification
+
+	SimilarityReports []*kilonova.MOSSSubmission
+	SimilarityReport  *kilonova.MOSSSubmission
 
 	AttachmentEditor *AttachmentEditorParams
 	StatementEditor  *StatementEditorParams
@@ -47,11 +51,23 @@ func (rt *Web) editIndex() func(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			chk = nil
 		}
+		verification, err := rt.base.ProblemVerification(r.Context(), util.Problem(r).ID)
+		if err != nil {
+			verification = nil
+		}
+		reports, err := rt.base.ProblemSimilarityReports(r.Context(), util.Problem(r).ID)
+		if err != nil {
+			zap.S().Warn(err)
+			reports = nil
+		}
 		rt.runTempl(w, r, tmpl, &ProblemEditParams{
 			Problem: util.Problem(r),
 			Topbar:  rt.problemTopbar(r, "general", -1),
 
-			Checklist: chk,
+			Checklist:    chk,
+			Verification: verification,
+
+			SimilarityReports: reports,
 		})
 	}
 }
@@ -132,6 +148,28 @@ func (rt *Web) editAccessControl() func(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (rt *Web) editSimilarityReport() func(w http.ResponseWriter, r *http.Request) {
+	tmpl := rt.parse(nil, "problem/edit/similarity.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+		if err != nil {
+			rt.statusPage(w, r, 400, "Invalid report ID")
+			return
+		}
+		report, err1 := rt.base.SimilarityReport(r.Context(), reportID, nil, &util.Problem(r).ID)
+		if err1 != nil {
+			rt.statusPage(w, r, err1.Code, err1.Error())
+			return
+		}
+		rt.runTempl(w, r, tmpl, &ProblemEditParams{
+			Problem: util.Problem(r),
+			Topbar:  rt.problemTopbar(r, "general", -1),
+
+			SimilarityReport: report.Report,
+		})
+	}
+}
+
 func (rt *Web) testIndex() func(w http.ResponseWriter, r *http.Request) {
 	tmpl := rt.parse(nil, "problem/edit/testScores.html", "problem/topbar.html", "problem/edit/testSidebar.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -181,6 +219,7 @@ func (rt *Web) ProblemEditRouter(r chi.Router) {
 	r.Get("/desc", rt.editDesc())
 	r.Get("/attachments", rt.editAttachments())
 	r.Get("/access", rt.editAccessControl())
+	r.Get("/similarity/{reportID}", rt.editSimilarityReport())
 
 	r.Get("/test", rt.testIndex())
 	r.Get("/test/add", rt.testAdd())
@@ -196,6 +196,7 @@ func (rt *Web) Handler() http.Handler {
 					r.Use(rt.mustBeContestEditor)
 					r.Get("/edit", rt.contestEdit())
 					r.Get("/registrations", rt.contestRegistrations())
+					r.Get("/similarity/{reportID}", rt.contestSimilarityReport())
 				})
 				r.Route("/problems/{pbid}", rt.problemRouter)
 			})
@@ -251,9 +252,7 @@ func (rt *Web) parse(optFuncs template.FuncMap, files ...string) *template.Templ
 // NewWeb returns a new web instance
 func NewWeb(base *sudoapi.BaseAPI) *Web {
 	funcs := template.FuncMap{
-		"pLanguages": func() map[string]*WebLanguage {
-			return webLanguages
-		},
+		"pLanguages": webLanguages,
 		"problemSettings": func(problemID int) *kilonova.ProblemEvalSettings {
 			settings, err := base.ProblemSettings(context.Background(), problemID)
 			if err != nil {
@@ -293,6 +292,17 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			}
 			return nil
 		},
+		"categoryFromList": func(categories []*kilonova.ContestCategory, id *int) *kilonova.ContestCategory {
+			if id == nil {
+				return nil
+			}
+			for _, category := range categories {
+				if category.ID == *id {
+					return category
+				}
+			}
+			return nil
+		},
 		"problemContests": func(user *kilonova.UserBrief, pb *kilonova.Problem) []*kilonova.Contest {
 			// TODO: Once there will be more contests, this will need to be optimized out to exclude ended ones
 			// At the moment, however, this is not a priority
@@ -385,6 +395,9 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			return reg.IndividualStartTime != nil && reg.IndividualEndTime.Before(time.Now())
 		},
 		"remainingContestTime": func(c *kilonova.Contest, reg *kilonova.ContestRegistration) time.Time {
+			if reg.VirtualRunning() {
+				return *reg.IndividualEndTime
+			}
 			if c.PerUserTime == 0 || reg == nil || reg.IndividualStartTime == nil {
 				return c.EndTime
 			}
@@ -738,10 +751,11 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			return code
 		},
 
-		"httpstatus":         http.StatusText,
-		"dump":               spew.Sdump,
-		"canJoinContest":     base.CanJoinContest,
-		"canSubmitInContest": base.CanSubmitInContest,
+		"httpstatus":             http.StatusText,
+		"dump":                   spew.Sdump,
+		"canJoinContest":         base.CanJoinContest,
+		"canSubmitInContest":     base.CanSubmitInContest,
+		"canStartVirtualContest": base.CanStartVirtualContest,
 		"contestDuration": func(c *kilonova.Contest) string {
 			d := c.EndTime.Sub(c.StartTime).Round(time.Minute)
 			return d.String()
@@ -834,23 +848,29 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 	return &Web{funcs, base}
 }
 
-var webLanguages map[string]*WebLanguage
-
 type WebLanguage struct {
 	Disabled bool   `json:"disabled"`
 	Name     string `json:"name"`
 	// Extensions []string `json:"extensions"`
+
+	TimeMultiplier float64 `json:"time_multiplier"`
+	MemoryOverhead int     `json:"memory_overhead"`
 }
 
-func init() {
-	webLanguages = make(map[string]*WebLanguage)
-	for name, lang := range eval.Langs {
-		webLanguages[name] = &WebLanguage{
+// webLanguages is computed every time, since languages may be reloaded
+func webLanguages() map[string]*WebLanguage {
+	langs := make(map[string]*WebLanguage)
+	for name, lang := range eval.Langs() {
+		langs[name] = &WebLanguage{
 			Disabled: lang.Disabled,
 			Name:     lang.PrintableName,
 			// Extensions: lang.Extensions,
+
+			TimeMultiplier: lang.TimeMultiplier,
+			MemoryOverhead: lang.MemoryOverhead,
 		}
 	}
+	return langs
 }
 
 // staticFileServer is a modification of the original hashfs